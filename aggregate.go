@@ -0,0 +1,167 @@
+package gofacto
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// BuildWithChildren builds one parent by the parent factory and n children by the child factory,
+// and inserts them with the foreign key of each child pointing at the parent,
+// e.g. a user with 10 orders.
+//
+// The parent is inserted by the parent factory, so its storage name, database, and traits apply,
+// and it's cleaned up by the parent factory.
+// The child type must have the foreignKey tag referencing the parent type,
+// and the foreign keys are set like WithOne, but the parent isn't added to the associations of the child factory.
+//
+// If both factories use the same database supporting transactions, the parent and the children are inserted within a transaction,
+// otherwise, the parent is deleted when inserting the children fails.
+//
+// Example:
+//
+//	type Order struct {
+//		ID     int
+//		UserID int `gofacto:"foreignKey,struct:User"`
+//	}
+//
+//	user, orders, err := gofacto.BuildWithChildren(ctx, userFactory, orderFactory, 10)
+func BuildWithChildren[P, C any](ctx context.Context, parent *Factory[P], child *Factory[C], n int) (P, []C, error) {
+	var zero P
+
+	if parent.configErr != nil {
+		return zero, nil, parent.configErr
+	}
+
+	if child.configErr != nil {
+		return zero, nil, child.configErr
+	}
+
+	if n < 1 {
+		return zero, nil, errBuildListNGreaterThanZero
+	}
+
+	refs, err := parentRefs(child.dataType, parent.dataType.Name())
+	if err != nil {
+		return zero, nil, err
+	}
+
+	if _, ok := parent.db.(txBeginner); !ok || !isSameDB(parent.db, child.db) {
+		return insertWithChildren(ctx, parent, child, n, refs)
+	}
+
+	var p P
+	var children []C
+	err = WithinTx(ctx, parent.db, func(scope *TxScope) error {
+		scope.Bind(parent, child)
+
+		var err error
+		p, children, err = insertWithChildren(ctx, parent, child, n, refs)
+		return err
+	})
+	if err != nil {
+		return zero, nil, err
+	}
+
+	return p, children, nil
+}
+
+// insertWithChildren inserts the parent and the children referencing it by the refs,
+// the parent is deleted when inserting the children fails
+func insertWithChildren[P, C any](ctx context.Context, parent *Factory[P], child *Factory[C], n int, refs []fkRef) (P, []C, error) {
+	var zero P
+
+	// the children are built first, so the parent isn't inserted if building them fails
+	b := child.BuildList(ctx, n)
+	if b.err != nil {
+		return zero, nil, b.err
+	}
+
+	numInserted := len(parent.inserted)
+	p, err := parent.Build(ctx).Insert()
+	if err != nil {
+		return zero, nil, err
+	}
+
+	for i := range refs {
+		refs[i].vals = []interface{}{&p}
+		if refs[i].fkName == "" {
+			refs[i].fkName = parent.idField
+		}
+	}
+
+	for _, v := range b.list {
+		if err := child.setParentKeys(v, refs); err != nil {
+			return zero, nil, parent.rollbackInserted(ctx, numInserted, err)
+		}
+	}
+
+	children, err := b.Insert()
+	if err != nil {
+		return zero, nil, parent.rollbackInserted(ctx, numInserted, err)
+	}
+
+	return p, children, nil
+}
+
+// isSameDB checks if both databases are the same connection
+func isSameDB(a, b database) bool {
+	if a == nil || reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+
+	return a == b
+}
+
+// parentRefs returns the foreign keys of the child type referencing the parent struct,
+// it returns an error if there's none
+func parentRefs(childType reflect.Type, parentName string) ([]fkRef, error) {
+	var refs []fkRef
+	err := processStructFields(childType, func(t tag, hasTag bool) error {
+		if hasTag && t.isForeignKey() && t.structName == parentName {
+			refs = append(refs, fkRef{
+				tableName:    t.tableName,
+				fieldName:    t.fieldName,
+				foreignField: t.foreignField,
+				fkName:       t.fkName,
+				isMany:       t.isMany,
+			})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("%s of %s: %w", parentName, childType.Name(), errParentNotReferenced)
+	}
+
+	return refs, nil
+}
+
+// setParentKeys sets the foreign keys of the child value(v) to the parent, which is the only value of each reference
+func (f *Factory[T]) setParentKeys(v interface{}, refs []fkRef) error {
+	for _, ref := range refs {
+		if ref.isMany {
+			if err := f.setForeignKeys(v, ref); err != nil {
+				return err
+			}
+			continue
+		}
+
+		p := ref.vals[0]
+		if err := setForeignKey(v, ref.fieldName, p, f.refFieldName(ref, p)); err != nil {
+			return err
+		}
+
+		if ref.foreignField != "" {
+			if err := setField(v, ref.foreignField, p); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package gofacto
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
+// defaultPIIDenyList is the list of patterns resembling real PII
+var defaultPIIDenyList = []*regexp.Regexp{
+	// email
+	regexp.MustCompile(`[^@\s]+@[^@\s]+\.[a-zA-Z]{2,}`),
+	// SSN
+	regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	// credit card number
+	regexp.MustCompile(`\b(?:\d[ -]?){12,15}\d\b`),
+	// phone number
+	regexp.MustCompile(`\+?\(?\d{2,4}\)?[\s.-]\d{3,4}[\s.-]\d{3,4}\b`),
+}
+
+// AnonymizeProfile is the profile to make sure the values don't resemble real PII
+type AnonymizeProfile struct {
+	// DenyList is the list of patterns that string values must not match.
+	// The default patterns(email, SSN, credit card number, phone number) are used if it's empty
+	DenyList []*regexp.Regexp
+
+	// MarkerField is the bool field set to true to mark the value as test data, e.g. IsTestData.
+	// It's ignored if the struct doesn't have the field
+	MarkerField string
+}
+
+// WithAnonymizeProfile sets the anonymize profile.
+//
+// When building the values, it returns an error if any string field matches the deny list,
+// and it sets the marker field to true if it's present.
+func (f *Factory[T]) WithAnonymizeProfile(p AnonymizeProfile) *Factory[T] {
+	if len(p.DenyList) == 0 {
+		p.DenyList = defaultPIIDenyList
+	}
+
+	f.anonymizeProfile = &p
+	return f
+}
+
+// anonymize checks the value against the deny list, and sets the marker field.
+// Parameter v must be a pointer to a struct
+func (f *Factory[T]) anonymize(v interface{}) error {
+	if f.anonymizeProfile == nil {
+		return nil
+	}
+
+	val := reflect.ValueOf(v).Elem()
+	if name, ok := findPII(val, val.Type().Name(), f.anonymizeProfile.DenyList); ok {
+		return fmt.Errorf("%w: %s", errValueResemblesPII, name)
+	}
+
+	if f.anonymizeProfile.MarkerField == "" {
+		return nil
+	}
+
+	marker := val.FieldByName(f.anonymizeProfile.MarkerField)
+	if !marker.IsValid() || !marker.CanSet() {
+		return nil
+	}
+
+	switch {
+	case marker.Kind() == reflect.Bool:
+		marker.SetBool(true)
+	case marker.Kind() == reflect.Ptr && marker.Type().Elem().Kind() == reflect.Bool:
+		t := true
+		marker.Set(reflect.ValueOf(&t))
+	}
+
+	return nil
+}
+
+// findPII returns the name of the first string matching the deny list
+func findPII(val reflect.Value, name string, denyList []*regexp.Regexp) (string, bool) {
+	switch val.Kind() {
+	case reflect.String:
+		for _, re := range denyList {
+			if re.MatchString(val.String()) {
+				return name, true
+			}
+		}
+	case reflect.Ptr, reflect.Interface:
+		if !val.IsNil() {
+			return findPII(val.Elem(), name, denyList)
+		}
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			if val.Type().Field(i).PkgPath != "" {
+				continue
+			}
+
+			if n, ok := findPII(val.Field(i), name+"."+val.Type().Field(i).Name, denyList); ok {
+				return n, true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			if n, ok := findPII(val.Index(i), fmt.Sprintf("%s[%d]", name, i), denyList); ok {
+				return n, true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -8,14 +8,21 @@ import (
 	"github.com/eyo-chen/gofacto/internal/db"
 )
 
+// SourceField is the field of the association value referenced by the foreign key, used by WithOneFrom
+type SourceField string
+
 // assocNode is the association node.
 // Each node contains it's metadata and the list of foreign key references
 type assocNode struct {
 	name         string
 	vals         []interface{}
 	tableName    string
+	idField      string
 	ignoreFields []string
 	dependencies []fkRef
+
+	// skipInsert is whether the values are not inserted, while the foreign keys are still set
+	skipInsert bool
 }
 
 // fkRef is the foreign key reference
@@ -25,6 +32,35 @@ type fkRef struct {
 	fieldName    string
 	foreignField string
 	fkName       string
+	isMany       bool
+}
+
+// NodeInsertedFunc is a client-defined function called after each node is inserted,
+// vals are the pointers to the inserted values of the storage(storageName), with the ID field populated
+type NodeInsertedFunc func(storageName string, vals []interface{})
+
+// OnNodeInserted adds the function called after each node is inserted.
+// With associations, the nodes are inserted in the dependency order, e.g. users before orders,
+// so the IDs of the parent values can be captured before the whole Insert completes.
+// Without associations, the factory value is the only node.
+//
+// Example:
+//
+//	factory := gofacto.New(Order{}).
+//		WithDB(db).
+//		OnNodeInserted(func(storageName string, vals []interface{}) {
+//			log.Printf("inserted %d values into %s", len(vals), storageName)
+//		})
+func (f *Factory[T]) OnNodeInserted(fn NodeInsertedFunc) *Factory[T] {
+	f.nodeInsertedFuncs = append(f.nodeInsertedFuncs, fn)
+	return f
+}
+
+// nodeInserted calls the functions added by OnNodeInserted in order
+func (f *Factory[T]) nodeInserted(storageName string, vals []interface{}) {
+	for _, fn := range f.nodeInsertedFuncs {
+		fn(storageName, vals)
+	}
 }
 
 // nodeInfo is used to store the information of a node for later reference.
@@ -36,25 +72,25 @@ type nodeInfo struct {
 }
 
 // insertWithAssoc inserts both factory value and its associations into the database
-func (b *builder[T]) insertWithAssoc(ctx context.Context) (T, error) {
+func (b *builder[T]) insertWithAssoc(ctx context.Context) (*T, error) {
 	// add factory value into association
 	b.f.associations = append(b.f.associations, []interface{}{b.v})
 
-	res, err := b.f.prepareAndInsertAssoc(ctx)
+	res, err := b.f.prepareAndInsertAssoc(ctx, b.skipInsert)
 	if err != nil {
-		return b.f.empty, err
+		return nil, err
 	}
 
 	v, ok := res[0].(*T)
 	if !ok {
-		return b.f.empty, errCantCvtToPtr
+		return nil, errCantCvtToPtr
 	}
 
-	return *v, nil
+	return v, nil
 }
 
 // insertWithAssoc inserts both factory value and its associations into the database
-func (b *builderList[T]) insertWithAssoc(ctx context.Context) ([]T, error) {
+func (b *builderList[T]) insertWithAssoc(ctx context.Context) ([]*T, error) {
 	// add factory value into association
 	vals := make([]interface{}, len(b.list))
 	for i, v := range b.list {
@@ -62,26 +98,42 @@ func (b *builderList[T]) insertWithAssoc(ctx context.Context) ([]T, error) {
 	}
 	b.f.associations = append(b.f.associations, vals)
 
-	res, err := b.f.prepareAndInsertAssoc(ctx)
+	res, err := b.f.prepareAndInsertAssoc(ctx, b.skipInsert)
 	if err != nil {
 		return nil, err
 	}
 
-	ts := make([]T, len(res))
+	ts := make([]*T, len(res))
 	for i, val := range res {
 		v, ok := val.(*T)
 		if !ok {
 			return nil, errCantCvtToPtr
 		}
 
-		ts[i] = *v
+		ts[i] = v
 	}
 
 	return ts, nil
 }
 
-// prepareAndInsertAssoc handles the preparation and insertion of associations
-func (f *Factory[T]) prepareAndInsertAssoc(ctx context.Context) ([]interface{}, error) {
+// prepareAndInsertAssoc handles the preparation and insertion of associations,
+// the factory values are not inserted if skipInsert is true
+func (f *Factory[T]) prepareAndInsertAssoc(ctx context.Context, skipInsert bool) ([]interface{}, error) {
+	nodes, err := f.prepareAssocNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if skipInsert {
+		f.skipFactoryNode(nodes)
+	}
+
+	// insert the deep association nodes into the database
+	return f.insertAssocNode(ctx, nodes, 0, maxNodeLen(nodes))
+}
+
+// prepareAssocNodes generates the association nodes in topological order
+func (f *Factory[T]) prepareAssocNodes() ([]assocNode, error) {
 	// create node info map
 	nodeInfoMap, err := f.genNodeInfoMap()
 	if err != nil {
@@ -89,24 +141,44 @@ func (f *Factory[T]) prepareAndInsertAssoc(ctx context.Context) ([]interface{},
 	}
 
 	// generate deep association nodes
-	deepAssoc, err := f.genAssocNodes(nodeInfoMap)
-	if err != nil {
-		return nil, err
+	return f.genAssocNodes(nodeInfoMap)
+}
+
+// skipFactoryNode sets the node of the factory values not to be inserted
+func (f *Factory[T]) skipFactoryNode(nodes []assocNode) {
+	name := reflect.TypeOf(f.empty).Name()
+	for i := range nodes {
+		if nodes[i].name == name {
+			nodes[i].skipInsert = true
+		}
 	}
+}
 
-	// insert the deep association nodes into the database
-	return f.insertAssocNode(ctx, deepAssoc)
+// maxNodeLen returns the maximum number of values of the nodes
+func maxNodeLen(nodes []assocNode) int {
+	n := 0
+	for _, node := range nodes {
+		n = max(n, len(node.vals))
+	}
+
+	return n
 }
 
-// insertAssocNode inserts the association nodes into the database.
+// insertAssocNode inserts the values of the association nodes whose indexes are in the range [start, end) into the database.
 // It first sets the foreign key fields for each node, then insert the node into the database.
-func (f *Factory[T]) insertAssocNode(ctx context.Context, nodes []assocNode) ([]interface{}, error) {
+// It returns the inserted factory values in the range
+func (f *Factory[T]) insertAssocNode(ctx context.Context, nodes []assocNode, start, end int) ([]interface{}, error) {
 	var fVal []interface{}
 
+	// numInserted is the number of inserted records before inserting the nodes.
+	// When any node fails, the nodes inserted by this call are deleted
+	numInserted := len(f.inserted)
+
 	// each node might have multiple values and dependencies
 	// e.g. SubCategory have User and MainCategory
 	// for each subCategory, has to set the foreign key fields for User and MainCategory
-	// cache is used to handle the case when the dependency is less than the number of values
+	// the value of the index is used when the dependency is less than the number of values,
+	// otherwise, the last value of the dependency is used
 	// e.g. SubCategory*3, User*2, MainCategory*1
 	// for the 1st SubCategory, set the foreign key fields for User1 and MainCategory1
 	// for the 2nd SubCategory, set the foreign key fields for User2 and MainCategory1
@@ -116,43 +188,87 @@ func (f *Factory[T]) insertAssocNode(ctx context.Context, nodes []assocNode) ([]
 	// 2. mainCategory is populated with random values, and insert into db
 	// 3. subCategory is populated with random values, and insert into db
 	for _, node := range nodes {
-		cache := map[string]interface{}{}
-		for i, v := range node.vals {
+		// the values out of the range are inserted by other calls
+		if start >= len(node.vals) {
+			continue
+		}
+		vals := node.vals[start:min(end, len(node.vals))]
+
+		for j, v := range vals {
+			i := start + j
 			for _, dep := range node.dependencies {
-				var d interface{}
-				if i >= len(dep.vals) {
-					d = cache[dep.fieldName]
-				} else {
-					d = dep.vals[i]
-					cache[dep.fieldName] = d
+				// the slice of foreign keys references all the values of the dependency
+				if dep.isMany {
+					if err := f.setForeignKeys(v, dep); err != nil {
+						return nil, f.rollbackInserted(ctx, numInserted, err)
+					}
+					continue
 				}
 
-				if d == nil {
+				if len(dep.vals) == 0 {
 					continue
 				}
+				d := dep.vals[min(i, len(dep.vals)-1)]
 
 				// set the foreign key field
-				if err := setForeignKey(v, dep.fieldName, d, dep.fkName); err != nil {
-					return nil, err
+				if err := setForeignKey(v, dep.fieldName, d, f.refFieldName(dep, d)); err != nil {
+					return nil, f.rollbackInserted(ctx, numInserted, err)
 				}
 				if dep.foreignField != "" {
 					if err := setField(v, dep.foreignField, d); err != nil {
-						return nil, err
+						return nil, f.rollbackInserted(ctx, numInserted, err)
 					}
 				}
 			}
 
-			f.setNonZeroValues(v, node.ignoreFields)
+			if err := f.reserveIndex(ctx); err != nil {
+				return nil, f.rollbackInserted(ctx, numInserted, err)
+			}
+
+			f.setTimestamps(v)
+			f.setNonZeroValues(v, f.fillIgnoreFields(node.ignoreFields))
 			f.index++
+
+			if err := f.anonymize(v); err != nil {
+				return nil, f.rollbackInserted(ctx, numInserted, err)
+			}
 		}
 
-		res, err := f.db.InsertList(ctx, db.InsertListParams{StorageName: node.tableName, Values: node.vals})
-		if err != nil {
-			return nil, err
+		// the insert transforms only apply to the factory values
+		isFactoryNode := node.name == reflect.TypeOf(f.empty).Name()
+
+		// the skipped values are only wired with the foreign keys
+		res := vals
+		if !node.skipInsert {
+			stored := vals
+			var err error
+			if isFactoryNode {
+				if stored, err = f.transformValues(vals); err != nil {
+					return nil, f.rollbackInserted(ctx, numInserted, err)
+				}
+			}
+
+			// the column selection and the ID generation only apply to the factory values
+			params := db.InsertListParams{StorageName: node.tableName, IDField: node.idField, Values: stored, OmitFields: f.omitFields(stored)}
+			if isFactoryNode {
+				params.OmitFields, params.NoGeneratedID = f.factoryOmitFields(stored), f.noGeneratedID
+			}
+
+			res, err = f.db.InsertList(ctx, params)
+			if err != nil {
+				return nil, f.rollbackInserted(ctx, numInserted, f.insertError(node.tableName, node.idField, stored, err))
+			}
+			if isFactoryNode {
+				res = f.untransformValues(vals, res)
+			}
+			if !isFactoryNode || f.isCleanable(params.OmitFields) {
+				f.recordInserted(node.tableName, node.idField, res)
+			}
+			f.nodeInserted(node.tableName, res)
 		}
 
 		// if the node is the factory value, set the fVal, and return later
-		if node.name == reflect.TypeOf(f.empty).Name() {
+		if isFactoryNode {
 			fVal = res
 		}
 	}
@@ -160,6 +276,77 @@ func (f *Factory[T]) insertAssocNode(ctx context.Context, nodes []assocNode) ([]
 	return fVal, nil
 }
 
+// refFieldName returns the name of the field of the dependency value(d) referenced by the foreign key
+func (f *Factory[T]) refFieldName(dep fkRef, d interface{}) string {
+	if name, ok := f.sourceFields[d]; ok {
+		return name
+	}
+
+	if dep.fkName != "" {
+		return dep.fkName
+	}
+
+	if name := f.idFieldName(reflect.TypeOf(d).Elem()); name != "" {
+		return name
+	}
+
+	return defaultIDFieldNames[0]
+}
+
+// setForeignKeys sets the slice of foreign keys of the value(v) to the IDs of all the dependency values.
+// If the foreignField is set, it's set to the slice of all the dependency values.
+// It's skipped when there's no dependency value, so the generated slice is kept
+func (f *Factory[T]) setForeignKeys(v interface{}, dep fkRef) error {
+	if len(dep.vals) == 0 {
+		return nil
+	}
+
+	targetField := reflect.ValueOf(v).Elem().FieldByName(dep.fieldName)
+	if !targetField.IsValid() {
+		return fmt.Errorf("%s: %w", dep.fieldName, errFieldNotFound)
+	}
+
+	fks := reflect.MakeSlice(targetField.Type(), len(dep.vals), len(dep.vals))
+	for i, d := range dep.vals {
+		if err := setKey(fks.Index(i), dep.fieldName, d, f.refFieldName(dep, d)); err != nil {
+			return err
+		}
+	}
+	targetField.Set(fks)
+
+	if dep.foreignField == "" {
+		return nil
+	}
+
+	foreignField := reflect.ValueOf(v).Elem().FieldByName(dep.foreignField)
+	if !foreignField.IsValid() {
+		return fmt.Errorf("%s: %w", dep.foreignField, errFieldNotFound)
+	}
+
+	if foreignField.Kind() != reflect.Slice {
+		return fmt.Errorf("%s: %w", dep.foreignField, errTypeDiff)
+	}
+
+	elemType := foreignField.Type().Elem()
+	foreignVals := reflect.MakeSlice(foreignField.Type(), len(dep.vals), len(dep.vals))
+	for i, d := range dep.vals {
+		source := reflect.ValueOf(d).Elem()
+		switch {
+		case elemType == source.Type():
+			foreignVals.Index(i).Set(source)
+		case elemType.Kind() == reflect.Ptr && elemType.Elem() == source.Type():
+			e := reflect.New(source.Type())
+			e.Elem().Set(source)
+			foreignVals.Index(i).Set(e)
+		default:
+			return fmt.Errorf("type mismatch: field %s is %v, source is %v", dep.foreignField, foreignField.Type(), source.Type())
+		}
+	}
+	foreignField.Set(foreignVals)
+
+	return nil
+}
+
 // genNodeInfoMap generates the node info map
 func (f *Factory[T]) genNodeInfoMap() (map[string]nodeInfo, error) {
 	nodeInfoMap := make(map[string]nodeInfo)
@@ -177,7 +364,7 @@ func (f *Factory[T]) genNodeInfoMap() (map[string]nodeInfo, error) {
 		name := typ.Name()
 		updateNodeInfoMap(nodeInfoMap, vals, name, "") // update the vals field
 		err := processStructFields(typ, func(t tag, hasTag bool) error {
-			if t.omit || !hasTag {
+			if t.omit || !hasTag || !t.isForeignKey() {
 				return nil
 			}
 
@@ -242,6 +429,7 @@ func (f *Factory[T]) genAssocNodes(nodeInfoMap map[string]nodeInfo) ([]assocNode
 			name:      name,
 			vals:      vals,
 			tableName: nodeInfoMap[name].tableName,
+			idField:   f.idFieldName(typ),
 		}
 
 		// process the fields to find out the dependencies
@@ -255,12 +443,17 @@ func (f *Factory[T]) genAssocNodes(nodeInfoMap map[string]nodeInfo) ([]assocNode
 				return nil
 			}
 
+			if !t.isForeignKey() {
+				return nil
+			}
+
 			deepAssoc.dependencies = append(deepAssoc.dependencies, fkRef{
 				vals:         nodeInfoMap[t.structName].vals,
 				tableName:    t.tableName,
 				fieldName:    t.fieldName,
 				foreignField: t.foreignField,
 				fkName:       t.fkName,
+				isMany:       t.isMany,
 			})
 
 			// e.g. User(fk) -> SubCategory
@@ -293,11 +486,35 @@ func setForeignKey(target interface{}, name string, source interface{}, fkName s
 		return fmt.Errorf("%s: %w", name, errFieldCantSet)
 	}
 
+	return setKey(targetField, name, source, fkName)
+}
+
+// setKey sets the value of the source's ID field(fkName) to the target key, e.g. the foreign key field or the element of it.
+// Parameter name is the name of the foreign key field for the error message
+func setKey(targetField reflect.Value, name string, source interface{}, fkName string) error {
 	sourceIDField := reflect.ValueOf(source).Elem().FieldByName(fkName)
 	if !sourceIDField.IsValid() {
 		return fmt.Errorf("%s: %w", fkName, errFieldNotFound)
 	}
 
+	if targetField.Kind() == reflect.Ptr && targetField.IsNil() {
+		targetField.Set(reflect.New(targetField.Type().Elem()))
+	}
+
+	// string ID, e.g. UUID
+	if sourceIDField.Kind() == reflect.String {
+		if targetField.Kind() == reflect.Ptr {
+			targetField = targetField.Elem()
+		}
+
+		if targetField.Kind() != reflect.String {
+			return fmt.Errorf("%s: %w", name, errTypeDiff)
+		}
+
+		targetField.SetString(sourceIDField.String())
+		return nil
+	}
+
 	sourceIDKind := sourceIDField.Kind()
 	if !isIntType(sourceIDKind) && !isUintType(sourceIDKind) {
 		return errNotInt
@@ -408,6 +625,24 @@ func checkAssoc(v interface{}) error {
 	return nil
 }
 
+// setSourceField records the source field referenced by the foreign key for the association value(v)
+func (f *Factory[T]) setSourceField(v interface{}, field SourceField) error {
+	if err := checkAssoc(v); err != nil {
+		return err
+	}
+
+	if _, ok := reflect.TypeOf(v).Elem().FieldByName(string(field)); !ok {
+		return fmt.Errorf("%s: %w", field, errFieldNotFound)
+	}
+
+	if f.sourceFields == nil {
+		f.sourceFields = map[interface{}]string{}
+	}
+	f.sourceFields[v] = string(field)
+
+	return nil
+}
+
 // checkAssocs checks if the input association values are valid
 func checkAssocs(vals []interface{}) error {
 	var name string
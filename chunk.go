@@ -0,0 +1,146 @@
+package gofacto
+
+import (
+	"context"
+	"fmt"
+)
+
+// InsertChunked inserts the list of values and their associations into the database in chunks of size values,
+// and calls fn with the inserted values of each chunk.
+// The association graph is processed chunk by chunk, so the database round trips and the intermediate
+// values are bounded by the chunk size, e.g. when inserting hundreds of thousands of values.
+//
+// The association values are aligned with the values by index as in Insert,
+// and each association value is inserted once in the chunk of its index,
+// e.g. the single value of WithOne is inserted in the first chunk and referenced by the following chunks.
+// The associations referenced by the foreignKeys tag can't be chunked, and an error is returned.
+//
+// When a chunk fails, the values of the chunk are deleted, while the values of the previous chunks are kept,
+// which are deleted by Cleanup
+//
+// Example:
+//
+//	err := orderFactory.BuildList(ctx, 100000).
+//		WithMany(users).
+//		InsertChunked(1000, func(orders []Order) error {
+//			// process the inserted orders
+//			return nil
+//		})
+func (b *builderList[T]) InsertChunked(size int, fn func(vals []T) error) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	if size <= 0 {
+		return fmt.Errorf("%w: %d", errInvalidChunkSize, size)
+	}
+
+	restore := b.f.useDB(b.dbName)
+	defer restore()
+
+	if b.f.db == nil {
+		return errDBIsNotProvided
+	}
+
+	if err := b.f.checkWritable(b.ctx); err != nil {
+		return err
+	}
+
+	input := make([]interface{}, len(b.list))
+	for i, v := range b.list {
+		input[i] = v
+	}
+
+	// the association nodes are generated once, and inserted chunk by chunk
+	var nodes []assocNode
+	total := len(input)
+	if len(b.f.associations) > 0 {
+		b.f.associations = append(b.f.associations, input)
+
+		var err error
+		nodes, err = b.f.prepareAssocNodes()
+		if err != nil {
+			return err
+		}
+
+		if err := checkChunkable(nodes); err != nil {
+			return err
+		}
+
+		if b.skipInsert {
+			b.f.skipFactoryNode(nodes)
+		}
+
+		total = maxNodeLen(nodes)
+	}
+
+	for start := 0; start < total; start += size {
+		end := min(start+size, total)
+		chunk := input[min(start, len(input)):min(end, len(input))]
+
+		var res []interface{}
+		err := b.f.runStep(b.ctx, StepInsert, chunk, func(ctx context.Context, _ Step) error {
+			var err error
+			res, err = b.insertChunk(ctx, nodes, chunk, start, end)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		// the chunks after the values only insert the remaining association values
+		if len(res) == 0 {
+			continue
+		}
+
+		output := make([]T, len(res))
+		for i, val := range res {
+			v, ok := val.(*T)
+			if !ok {
+				return errCantCvtToPtr
+			}
+
+			output[i] = *v
+		}
+
+		if err := fn(output); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertChunk inserts the chunk of values whose indexes are in the range [start, end) into the database,
+// the association nodes in the range are inserted with them if any
+func (b *builderList[T]) insertChunk(ctx context.Context, nodes []assocNode, chunk []interface{}, start, end int) ([]interface{}, error) {
+	if len(nodes) > 0 {
+		return b.f.insertAssocNode(ctx, nodes, start, end)
+	}
+
+	res, err := b.insert(ctx, chunk)
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]interface{}, len(res))
+	for i, v := range res {
+		output[i] = v
+	}
+
+	return output, nil
+}
+
+// checkChunkable checks if the association nodes can be inserted in chunks,
+// the slice of foreign keys references all the values of the dependency, which are not inserted in the same chunk
+func checkChunkable(nodes []assocNode) error {
+	for _, node := range nodes {
+		for _, dep := range node.dependencies {
+			if dep.isMany {
+				return fmt.Errorf("%w: %s", errChunkForeignKeys, dep.fieldName)
+			}
+		}
+	}
+
+	return nil
+}
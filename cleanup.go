@@ -0,0 +1,107 @@
+package gofacto
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eyo-chen/gofacto/internal/db"
+)
+
+// insertedRecord is the record of the inserted values, it's used to clean up the inserted data
+type insertedRecord struct {
+	storageName string
+	idField     string
+	vals        []interface{}
+
+	// dbName is the name of the database connection inserted into, empty means the default one
+	dbName string
+}
+
+// Cleanup deletes all the data inserted by the factory from the database.
+//
+// The data is deleted in the reverse order of insertion,
+// so the associations are deleted after the values referencing them.
+// If the factory is configured with WithIsSoftDelete(true), the data is marked as deleted instead of being removed.
+// An error is returned if the database doesn't implement DeleteList.
+func (f *Factory[T]) Cleanup(ctx context.Context) error {
+	if f.db == nil && len(f.dbs) == 0 {
+		return errDBIsNotProvided
+	}
+
+	for i := len(f.inserted) - 1; i >= 0; i-- {
+		r := f.inserted[i]
+		if len(r.vals) == 0 {
+			continue
+		}
+
+		d := f.dbByName(r.dbName)
+		if d == nil {
+			f.inserted = f.inserted[:i+1]
+			return errDBIsNotProvided
+		}
+
+		del, ok := d.(deleter)
+		if !ok {
+			f.inserted = f.inserted[:i+1]
+			return errDeleteNotSupported
+		}
+
+		if err := del.DeleteList(ctx, db.DeleteListParams{
+			StorageName:  r.storageName,
+			IDField:      r.idField,
+			Values:       r.vals,
+			IsSoftDelete: f.isSoftDelete,
+		}); err != nil {
+			// keep the records which are not deleted yet
+			f.inserted = f.inserted[:i+1]
+			return err
+		}
+	}
+
+	f.inserted = nil
+	return nil
+}
+
+// rollbackInserted hard deletes the records inserted after the first n records in reverse order,
+// and returns the given error.
+// It's used to leave the database clean when inserting fails midway
+func (f *Factory[T]) rollbackInserted(ctx context.Context, n int, err error) error {
+	for i := len(f.inserted) - 1; i >= n; i-- {
+		r := f.inserted[i]
+		del, ok := f.dbByName(r.dbName).(deleter)
+		if !ok {
+			f.inserted = f.inserted[:i+1]
+			return fmt.Errorf("%w; rollback failed: %v", err, errDeleteNotSupported)
+		}
+
+		if delErr := del.DeleteList(ctx, db.DeleteListParams{
+			StorageName: r.storageName,
+			IDField:     r.idField,
+			Values:      r.vals,
+		}); delErr != nil {
+			f.inserted = f.inserted[:i+1]
+			return fmt.Errorf("%w; rollback failed: %v", err, delErr)
+		}
+	}
+
+	f.inserted = f.inserted[:n]
+	return err
+}
+
+// forgetInserted removes the value from the inserted records
+func (f *Factory[T]) forgetInserted(v interface{}) {
+	for i, r := range f.inserted {
+		for k, val := range r.vals {
+			if val == v {
+				r.vals = append(r.vals[:k:k], r.vals[k+1:]...)
+				f.inserted[i] = r
+				return
+			}
+		}
+	}
+}
+
+// recordInserted records the inserted values for later cleanup
+func (f *Factory[T]) recordInserted(storageName, idField string, vals []interface{}) {
+	f.inserted = append(f.inserted, insertedRecord{storageName: storageName, idField: idField, vals: vals, dbName: f.dbName})
+}
@@ -0,0 +1,81 @@
+package gofacto
+
+import (
+	"reflect"
+	"slices"
+)
+
+// WithColumns sets the fields inserted into the database, the other fields are omitted,
+// so the database sets them by the column defaults.
+// It's useful for inserting into the targets requiring specific column subsets, e.g. the writable views.
+// The values are still built as usual, and the omitted fields are kept in the returned values.
+// It's supported by mysqlf, postgresf, and gormf
+//
+// Example:
+//
+//	factory := gofacto.New(User{}).
+//		WithStorageName("active_users").
+//		WithColumns("Name", "Email").
+//		WithNoGeneratedID(true)
+func (f *Factory[T]) WithColumns(fields ...string) *Factory[T] {
+	if f.configErr != nil {
+		return f
+	}
+
+	if err := checkFieldsExist(f.dataType, fields); err != nil {
+		f.configErr = err
+		return f
+	}
+
+	f.columns = fields
+	return f
+}
+
+// WithNoGeneratedID sets whether the database doesn't generate the ID when inserting, e.g. the writable views without the ID column.
+// The inserted IDs are neither returned by the database nor set to the ID field.
+// The ID field is inserted as the other fields if it's selected by WithColumns or it's set, e.g. by the blueprint,
+// otherwise, it's omitted.
+//
+// The values inserted without the ID can't be deleted by the ID, so they're not cleaned up by Cleanup
+func (f *Factory[T]) WithNoGeneratedID(noGeneratedID bool) *Factory[T] {
+	f.noGeneratedID = noGeneratedID
+	return f
+}
+
+// factoryOmitFields returns the fields omitted when inserting the factory values,
+// which are the fields omitted by WithTimestamps and the fields not selected by WithColumns.
+// When WithNoGeneratedID is set, the ID field left zero in all the values is omitted as well.
+// Parameter vals must be the pointers to T
+func (f *Factory[T]) factoryOmitFields(vals []interface{}) []string {
+	fields := f.omitFields(vals)
+
+	if len(f.columns) > 0 {
+		for i := 0; i < f.dataType.NumField(); i++ {
+			name := f.dataType.Field(i).Name
+			if !slices.Contains(f.columns, name) && !slices.Contains(fields, name) {
+				fields = append(fields, name)
+			}
+		}
+	}
+
+	if !f.noGeneratedID || f.idField == "" || slices.Contains(f.columns, f.idField) || slices.Contains(fields, f.idField) {
+		return fields
+	}
+
+	if _, ok := f.dataType.FieldByName(f.idField); !ok {
+		return fields
+	}
+
+	for _, v := range vals {
+		if !reflect.ValueOf(v).Elem().FieldByName(f.idField).IsZero() {
+			return fields
+		}
+	}
+
+	return append(fields, f.idField)
+}
+
+// isCleanable checks if the factory values inserted with the omitted fields can be deleted by the ID
+func (f *Factory[T]) isCleanable(omitFields []string) bool {
+	return !f.noGeneratedID || !slices.Contains(omitFields, f.idField)
+}
@@ -0,0 +1,215 @@
+package gofacto
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Variation is the set of the choices varied across the values built by Combinations and Pairwise
+type Variation struct {
+	// field is the name of the field set to the values, empty means the traits are applied
+	field  string
+	vals   []interface{}
+	traits []string
+}
+
+// Vary returns the variation setting the field to each of the values,
+// the values are converted to the type of the field
+func Vary(field string, vals ...interface{}) Variation {
+	return Variation{field: field, vals: vals}
+}
+
+// VaryTraits returns the variation applying each of the traits set by WithTrait or WithTraitE
+func VaryTraits(names ...string) Variation {
+	return Variation{traits: names}
+}
+
+// numChoices returns the number of the choices of the variation
+func (v Variation) numChoices() int {
+	if v.field == "" {
+		return len(v.traits)
+	}
+
+	return len(v.vals)
+}
+
+// Combinations builds a list covering all the combinations of the variations.
+//
+// Example:
+//
+//	// 6 orders: (new, free), (new, pro), (paid, free), (paid, pro), (refunded, free), (refunded, pro)
+//	orders, err := factory.Combinations(ctx,
+//		gofacto.Vary("Status", "new", "paid", "refunded"),
+//		gofacto.Vary("Plan", "free", "pro"),
+//	).Insert()
+func (f *Factory[T]) Combinations(ctx context.Context, vars ...Variation) *builderList[T] {
+	return f.buildVariations(ctx, vars, allCombinations)
+}
+
+// Pairwise builds a list covering all the pairs of the choices of any two variations,
+// which is much smaller than all the combinations when there are many variations.
+// The list is generated greedily and deterministically, so it's not guaranteed to be the smallest
+func (f *Factory[T]) Pairwise(ctx context.Context, vars ...Variation) *builderList[T] {
+	return f.buildVariations(ctx, vars, pairwiseCombinations)
+}
+
+// buildVariations builds a list of values, each value applies a combination of the choices generated by genCombos
+func (f *Factory[T]) buildVariations(ctx context.Context, vars []Variation, genCombos func(sizes []int) [][]int) *builderList[T] {
+	sizes := make([]int, len(vars))
+	for i, v := range vars {
+		if err := f.checkVariation(v); err != nil {
+			return &builderList[T]{ctx: ctx, err: err, f: f}
+		}
+		sizes[i] = v.numChoices()
+	}
+
+	if len(vars) == 0 {
+		return &builderList[T]{ctx: ctx, err: fmt.Errorf("%w: no variation", errInvalidVariation), f: f}
+	}
+
+	combos := genCombos(sizes)
+	b := f.BuildList(ctx, len(combos))
+	if b.err != nil {
+		return b
+	}
+
+	for i, combo := range combos {
+		for j, choice := range combo {
+			if err := b.applyVariation(b.list[i], vars[j], choice); err != nil {
+				b.err = err
+				return b
+			}
+		}
+	}
+
+	return b
+}
+
+// checkVariation checks if the variation has choices, and the field or traits exist
+func (f *Factory[T]) checkVariation(v Variation) error {
+	if v.numChoices() == 0 {
+		return fmt.Errorf("%w: %s has no choice", errInvalidVariation, v.field)
+	}
+
+	if v.field != "" {
+		return checkFieldsExist(f.dataType, []string{v.field})
+	}
+
+	for _, name := range v.traits {
+		if _, ok := f.trait(name); !ok {
+			return fmt.Errorf("%w: %s", errWithTraitNameNotFound, name)
+		}
+	}
+
+	return nil
+}
+
+// applyVariation applies the choice of the variation to the value
+func (b *builderList[T]) applyVariation(v *T, vr Variation, choice int) error {
+	if vr.field != "" {
+		return setConvertedField(reflect.ValueOf(v).Elem(), vr.field, vr.vals[choice])
+	}
+
+	name := vr.traits[choice]
+	tr, _ := b.f.trait(name)
+	return applyTrait(v, name, tr, b.frozen)
+}
+
+// allCombinations returns all the combinations of the choices, the last variation varies fastest
+func allCombinations(sizes []int) [][]int {
+	combos := [][]int{{}}
+	for _, size := range sizes {
+		next := make([][]int, 0, len(combos)*size)
+		for _, combo := range combos {
+			for choice := 0; choice < size; choice++ {
+				next = append(next, append(combo[:len(combo):len(combo)], choice))
+			}
+		}
+		combos = next
+	}
+
+	return combos
+}
+
+// pair is the pair of the choices of two variations, i < j
+type pair struct {
+	i, a, j, b int
+}
+
+// pairwiseCombinations returns the combinations covering all the pairs of the choices.
+//
+// Each combination starts from the first uncovered pair,
+// and the choices of the other variations are picked to cover the most uncovered pairs with the picked choices
+func pairwiseCombinations(sizes []int) [][]int {
+	if len(sizes) <= 2 {
+		return allCombinations(sizes)
+	}
+
+	var order []pair
+	uncovered := map[pair]bool{}
+	for i := range sizes {
+		for j := i + 1; j < len(sizes); j++ {
+			for a := 0; a < sizes[i]; a++ {
+				for b := 0; b < sizes[j]; b++ {
+					p := pair{i: i, a: a, j: j, b: b}
+					order = append(order, p)
+					uncovered[p] = true
+				}
+			}
+		}
+	}
+
+	var combos [][]int
+	for _, first := range order {
+		if !uncovered[first] {
+			continue
+		}
+
+		combo := make([]int, len(sizes))
+		picked := make([]bool, len(sizes))
+		combo[first.i], combo[first.j] = first.a, first.b
+		picked[first.i], picked[first.j] = true, true
+
+		for k := range sizes {
+			if picked[k] {
+				continue
+			}
+
+			best, bestCount := 0, -1
+			for c := 0; c < sizes[k]; c++ {
+				count := 0
+				for m := range sizes {
+					if picked[m] && uncovered[newPair(m, combo[m], k, c)] {
+						count++
+					}
+				}
+
+				if count > bestCount {
+					best, bestCount = c, count
+				}
+			}
+
+			combo[k], picked[k] = best, true
+		}
+
+		for i := range sizes {
+			for j := i + 1; j < len(sizes); j++ {
+				delete(uncovered, pair{i: i, a: combo[i], j: j, b: combo[j]})
+			}
+		}
+
+		combos = append(combos, combo)
+	}
+
+	return combos
+}
+
+// newPair returns the pair of the choice a of the variation i and the choice b of the variation j in order
+func newPair(i, a, j, b int) pair {
+	if i > j {
+		return pair{i: j, a: b, j: i, b: a}
+	}
+
+	return pair{i: i, a: a, j: j, b: b}
+}
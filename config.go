@@ -0,0 +1,68 @@
+package gofacto
+
+import "slices"
+
+// Config is the options struct of the factory, used by SetConfig.
+// It's the configuration path of the older versions, and the zero fields keep the current settings
+type Config[T any] struct {
+	// DB is the database connection, see WithDB
+	DB database
+
+	// Blueprint is the blueprint function, see WithBlueprint
+	Blueprint blueprintFunc[T]
+
+	// StorageName is the storage name, see WithStorageName
+	StorageName string
+
+	// Traits is the map from name to trait function, see WithTrait
+	Traits map[string]func(v *T)
+
+	// IgnoreFields is the list of fields which are not set with the non-zero values, like the omit tag
+	IgnoreFields []string
+}
+
+// SetConfig sets the configurations of the factory by the options struct,
+// it maps onto the With methods, e.g. DB onto WithDB.
+// It's kept for migrating from the older versions.
+//
+// Example:
+//
+//	factory := gofacto.New(Order{}).SetConfig(gofacto.Config[Order]{
+//		DB:          mysqlf.NewConfig(db),
+//		Blueprint:   blueprint,
+//		StorageName: "orders",
+//	})
+func (f *Factory[T]) SetConfig(c Config[T]) *Factory[T] {
+	if f.configErr != nil {
+		return f
+	}
+
+	if err := checkFieldsExist(f.dataType, c.IgnoreFields); err != nil {
+		f.configErr = err
+		return f
+	}
+
+	if c.DB != nil {
+		f.WithDB(c.DB)
+	}
+
+	if c.Blueprint != nil {
+		f.WithBlueprint(c.Blueprint)
+	}
+
+	if c.StorageName != "" {
+		f.WithStorageName(c.StorageName)
+	}
+
+	for name, tr := range c.Traits {
+		f.WithTrait(name, tr)
+	}
+
+	for _, name := range c.IgnoreFields {
+		if !slices.Contains(f.ignoreFields, name) {
+			f.ignoreFields = append(f.ignoreFields, name)
+		}
+	}
+
+	return f
+}
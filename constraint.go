@@ -0,0 +1,151 @@
+package gofacto
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// maxConstraintAttempts is the maximum number of attempts to generate a value satisfying the constraints
+const maxConstraintAttempts = 10
+
+// constraintFunc is a client-defined function to check the invariant of the value,
+// it returns an error if the value violates the invariant
+type constraintFunc[T any] func(v *T) error
+
+// WithConstraint adds the constraint function checked after generating each value.
+// If the value violates the constraint, the value is re-generated with the same index,
+// and the error is returned after the maximum number of attempts.
+//
+// Re-generation only helps when the generation is random, e.g. the time tag or the blueprint using math/rand.
+// Traits and overwrites are applied after the constraints are checked
+func (f *Factory[T]) WithConstraint(c constraintFunc[T]) *Factory[T] {
+	f.constraints = append(f.constraints, c)
+	return f
+}
+
+// checkConstraints checks the value against all the constraints, and returns the first violation
+func (f *Factory[T]) checkConstraints(v *T) error {
+	for _, c := range f.constraints {
+		if err := c(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// After returns the constraint that the time field after is later than the time field before.
+// Both fields must be time.Time or *time.Time, and the nil pointer violates the constraint
+func After[T any](before, after string) constraintFunc[T] {
+	return func(v *T) error {
+		val := reflect.ValueOf(v).Elem()
+
+		b, err := timeField(val, before)
+		if err != nil {
+			return err
+		}
+
+		a, err := timeField(val, after)
+		if err != nil {
+			return err
+		}
+
+		if b == nil || a == nil || !a.After(*b) {
+			return fmt.Errorf("%w: %s must be after %s", errConstraintViolated, after, before)
+		}
+
+		return nil
+	}
+}
+
+// Sum returns the constraint that the numeric field total equals the sum of the numeric slice field parts
+func Sum[T any](parts, total string) constraintFunc[T] {
+	return func(v *T) error {
+		val := reflect.ValueOf(v).Elem()
+
+		partsVal := val.FieldByName(parts)
+		if !partsVal.IsValid() {
+			return fmt.Errorf("%w: %w: %s", errInvalidConstraint, errFieldNotFound, parts)
+		}
+		if partsVal.Kind() != reflect.Slice {
+			return fmt.Errorf("%w: %s is not a slice", errInvalidConstraint, parts)
+		}
+
+		var sum float64
+		for i := 0; i < partsVal.Len(); i++ {
+			n, ok := toFloat(partsVal.Index(i))
+			if !ok {
+				return fmt.Errorf("%w: %s is not a slice of numbers", errInvalidConstraint, parts)
+			}
+			sum += n
+		}
+
+		totalVal := val.FieldByName(total)
+		if !totalVal.IsValid() {
+			return fmt.Errorf("%w: %w: %s", errInvalidConstraint, errFieldNotFound, total)
+		}
+
+		t, ok := toFloat(totalVal)
+		if !ok {
+			return fmt.Errorf("%w: %s is not a number", errInvalidConstraint, total)
+		}
+
+		if t != sum {
+			return fmt.Errorf("%w: %s must be the sum of %s", errConstraintViolated, total, parts)
+		}
+
+		return nil
+	}
+}
+
+// timeField returns the time of the field, nil if the field is a nil pointer
+func timeField(val reflect.Value, name string) (*time.Time, error) {
+	field := val.FieldByName(name)
+	if !field.IsValid() {
+		return nil, fmt.Errorf("%w: %w: %s", errInvalidConstraint, errFieldNotFound, name)
+	}
+
+	switch field.Type() {
+	case timeType:
+		t := field.Interface().(time.Time)
+		return &t, nil
+	case reflect.PointerTo(timeType):
+		return field.Interface().(*time.Time), nil
+	default:
+		return nil, fmt.Errorf("%w: %s is not a time", errInvalidConstraint, name)
+	}
+}
+
+// toFloat converts the numeric value to float64, the nil pointer is zero
+func toFloat(v reflect.Value) (float64, bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return 0, isNumberKind(v.Type().Elem().Kind())
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// isNumberKind checks if the kind is an integer or a float
+func isNumberKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
@@ -1,8 +1,15 @@
 package gofacto
 
+import (
+	"reflect"
+)
+
 type dag struct {
 	nodes map[string]assocNode
 	edges map[string][]string
+
+	// order is the node names in the order of being added, it keeps the sort result deterministic
+	order []string
 }
 
 func newDAG() *dag {
@@ -13,6 +20,10 @@ func newDAG() *dag {
 }
 
 func (d *dag) addNode(node assocNode) {
+	if _, ok := d.nodes[node.name]; !ok {
+		d.order = append(d.order, node.name)
+	}
+
 	d.nodes[node.name] = node
 }
 
@@ -41,8 +52,8 @@ func (d *dag) topologicalSort() []assocNode {
 		i--
 	}
 
-	for node := range d.nodes {
-		dfs(node)
+	for i := len(d.order) - 1; i >= 0; i-- {
+		dfs(d.order[i])
 	}
 
 	return result
@@ -75,7 +86,7 @@ func (d *dag) hasCycle() bool {
 		return false
 	}
 
-	for node := range d.nodes {
+	for _, node := range d.order {
 		if dfs(node) {
 			return true
 		}
@@ -83,3 +94,61 @@ func (d *dag) hasCycle() bool {
 
 	return false
 }
+
+// PlanInsertOrder orders the given values by the dependencies declared in the foreignKey tags,
+// so that the values being referenced come before the values referencing them.
+//
+// Each value must be a pointer to a struct, and the values of the same type keep their relative order.
+// It's useful when orchestrating custom multi-factory setups.
+//
+// Example:
+//
+//	vals, err := gofacto.PlanInsertOrder([]interface{}{&order, &customer})
+//	// vals == []interface{}{&customer, &order}
+//
+// It returns an error if there's a cycle dependency.
+func PlanInsertOrder(vals []interface{}) ([]interface{}, error) {
+	d := newDAG()
+
+	// group the values by the struct name
+	groups := map[string][]interface{}{}
+	for _, v := range vals {
+		if err := checkAssoc(v); err != nil {
+			return nil, err
+		}
+
+		name := reflect.TypeOf(v).Elem().Name()
+		groups[name] = append(groups[name], v)
+		d.addNode(assocNode{name: name})
+	}
+
+	for _, name := range d.order {
+		typ := reflect.TypeOf(groups[name][0]).Elem()
+		err := processStructFields(typ, func(t tag, hasTag bool) error {
+			if !hasTag || t.omit || !t.isForeignKey() {
+				return nil
+			}
+
+			// only the values given by the clients are ordered
+			if _, ok := groups[t.structName]; ok {
+				d.addEdge(t.structName, name)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if d.hasCycle() {
+		return nil, errCycleDependency
+	}
+
+	res := make([]interface{}, 0, len(vals))
+	for _, node := range d.topologicalSort() {
+		res = append(res, groups[node.name]...)
+	}
+
+	return res, nil
+}
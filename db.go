@@ -14,7 +14,30 @@ type database interface {
 
 	// insertList inserts a list of data into the database
 	InsertList(context.Context, db.InsertListParams) ([]interface{}, error)
+}
+
+// updater is the database which is able to update the data, e.g. for Fixture.Update
+type updater interface {
+	// Update updates a single data in the database by the ID field
+	Update(context.Context, db.UpdateParams) error
+}
 
+// deleter is the database which is able to delete the data, e.g. for Cleanup
+type deleter interface {
+	// DeleteList deletes a list of data from the database.
+	// If IsSoftDelete is true, the data is marked as deleted instead of being removed
+	DeleteList(context.Context, db.DeleteListParams) error
+}
+
+// customTypeGenerator is the database which is able to generate the values of its custom types, e.g. the spatial types
+type customTypeGenerator interface {
 	// GenCustomType generates a non-zero value for custom types
 	GenCustomType(reflect.Type) (interface{}, bool)
 }
+
+// valueConverter is the database which converts the field values before inserting, e.g. the maps to JSON
+type valueConverter interface {
+	// ConvertValue converts the field value to the form accepted by the database driver before inserting.
+	// It returns false if the value doesn't need to be converted
+	ConvertValue(reflect.StructField, interface{}) (interface{}, bool)
+}
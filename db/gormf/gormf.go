@@ -2,12 +2,32 @@ package gormf
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/eyo-chen/gofacto/internal/db"
+	"github.com/eyo-chen/gofacto/internal/utils"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// errInvalidStorageName is the error representing that the storage name is not a valid identifier
+	errInvalidStorageName = errors.New("invalid storage name")
+
+	// errTableNotFound is the error representing that the table doesn't exist
+	errTableNotFound = errors.New("table not found")
+
+	// errColumnNotFound is the error representing that the columns of the fields don't exist in the table
+	errColumnNotFound = errors.New("column not found")
+
+	// errSoftDeleteNotSupported is the error representing that the model can't be soft deleted, e.g. without gorm.DeletedAt
+	errSoftDeleteNotSupported = errors.New("soft delete is not supported")
 )
 
 // config is for Gorm configuration
@@ -16,6 +36,11 @@ type config struct {
 	db *gorm.DB
 }
 
+// txConfig is the config bound to a shared transaction
+type txConfig struct {
+	*config
+}
+
 // NewConfig creates a new gorm configuration
 func NewConfig(db *gorm.DB) *config {
 	return &config{
@@ -24,7 +49,11 @@ func NewConfig(db *gorm.DB) *config {
 }
 
 func (c *config) Insert(ctx context.Context, params db.InsertParams) (interface{}, error) {
-	if err := c.db.WithContext(ctx).Table(params.StorageName).Create(params.Value).Error; err != nil {
+	if err := checkStorageName(params.StorageName); err != nil {
+		return nil, err
+	}
+
+	if err := create(c.db.WithContext(ctx), params.StorageName, params.Value, params.OmitFields, params.NoGeneratedID); err != nil {
 		return nil, err
 	}
 
@@ -32,16 +61,189 @@ func (c *config) Insert(ctx context.Context, params db.InsertParams) (interface{
 }
 
 func (c *config) InsertList(ctx context.Context, params db.InsertListParams) ([]interface{}, error) {
+	if err := checkStorageName(params.StorageName); err != nil {
+		return nil, err
+	}
+
 	// NOTE: Using for-loop to insert is a workaround for GORM
-	for _, v := range params.Values {
-		if err := c.db.WithContext(ctx).Table(params.StorageName).Create(v).Error; err != nil {
-			return nil, err
+	// insert in a transaction, so the inserted rows are rolled back when any of them fails
+	err := c.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, v := range params.Values {
+			if err := create(tx, params.StorageName, v, params.OmitFields, params.NoGeneratedID); err != nil {
+				return &db.InsertError{Index: i, Err: err}
+			}
 		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return params.Values, nil
 }
 
+func (c *config) Update(ctx context.Context, params db.UpdateParams) error {
+	if err := checkStorageName(params.StorageName); err != nil {
+		return err
+	}
+
+	return c.db.WithContext(ctx).Table(params.StorageName).Omit(params.OmitFields...).Save(params.Value).Error
+}
+
+func (c *config) DeleteList(ctx context.Context, params db.DeleteListParams) error {
+	if err := checkStorageName(params.StorageName); err != nil {
+		return err
+	}
+
+	if len(params.Values) == 0 {
+		return nil
+	}
+
+	tx := c.db.WithContext(ctx).Table(params.StorageName)
+	if !params.IsSoftDelete {
+		// bypass the deleted_at semantics of gorm.DeletedAt
+		tx = tx.Unscoped()
+	} else if err := c.checkSoftDelete(params.Values[0]); err != nil {
+		return err
+	}
+
+	for _, v := range params.Values {
+		if err := tx.Delete(v).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkSoftDelete checks if the model(v) can be soft deleted, e.g. it has the gorm.DeletedAt field.
+// Otherwise, gorm deletes it permanently
+func (c *config) checkSoftDelete(v interface{}) error {
+	stmt := &gorm.Statement{DB: c.db}
+	if err := stmt.Parse(v); err != nil {
+		return err
+	}
+
+	if len(stmt.Schema.DeleteClauses) == 0 {
+		return fmt.Errorf("%w: %s", errSoftDeleteNotSupported, stmt.Schema.Name)
+	}
+
+	return nil
+}
+
+// Identify returns the name of the current database
+func (c *config) Identify(ctx context.Context) ([]string, error) {
+	name := c.db.WithContext(ctx).Migrator().CurrentDatabase()
+	if name == "" {
+		return nil, nil
+	}
+
+	return []string{name}, nil
+}
+
+// CheckSchema checks if the table exists, and the fields of the value map to its columns.
+// The fields are mapped to the columns by the naming strategy of gorm, and the fields ignored by gorm and the omitted fields are skipped
+func (c *config) CheckSchema(ctx context.Context, params db.CheckSchemaParams) error {
+	if err := checkStorageName(params.StorageName); err != nil {
+		return err
+	}
+
+	migrator := c.db.WithContext(ctx).Migrator()
+	if !migrator.HasTable(params.StorageName) {
+		return fmt.Errorf("%w: %s", errTableNotFound, params.StorageName)
+	}
+
+	columnTypes, err := migrator.ColumnTypes(params.StorageName)
+	if err != nil {
+		return err
+	}
+
+	columns := make([]string, len(columnTypes))
+	for i, ct := range columnTypes {
+		columns[i] = ct.Name()
+	}
+
+	stmt := &gorm.Statement{DB: c.db}
+	if err := stmt.Parse(params.Value); err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, field := range stmt.Schema.Fields {
+		if slices.Contains(params.OmitFields, field.Name) {
+			continue
+		}
+
+		if field.DBName != "" && !slices.Contains(columns, field.DBName) {
+			missing = append(missing, field.DBName)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s in %s", errColumnNotFound, strings.Join(missing, ", "), params.StorageName)
+	}
+
+	return nil
+}
+
+// Find returns the rows of the table matching all the conditions, the rows are scanned into the values of the struct type.
+// The fields are mapped to the columns by the naming strategy of gorm
+func (c *config) Find(ctx context.Context, params db.FindParams) ([]interface{}, error) {
+	if err := checkStorageName(params.StorageName); err != nil {
+		return nil, err
+	}
+
+	stmt := &gorm.Statement{DB: c.db}
+	if err := stmt.Parse(params.Value); err != nil {
+		return nil, err
+	}
+
+	tx := c.db.WithContext(ctx).Table(params.StorageName)
+	for _, cond := range params.Conditions {
+		field := stmt.Schema.LookUpField(cond.Field)
+		if field == nil || field.DBName == "" {
+			return nil, fmt.Errorf("%w: %s", errColumnNotFound, cond.Field)
+		}
+
+		tx = tx.Where(clause.Eq{Column: clause.Column{Name: field.DBName}, Value: cond.Value})
+	}
+
+	if params.Limit > 0 {
+		tx = tx.Limit(params.Limit)
+	}
+
+	rows := reflect.New(reflect.SliceOf(reflect.TypeOf(params.Value).Elem()))
+	if err := tx.Find(rows.Interface()).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, rows.Elem().Len())
+	for i := range result {
+		result[i] = rows.Elem().Index(i).Addr().Interface()
+	}
+
+	return result, nil
+}
+
+// BeginTx starts a shared transaction, all the operations of the returned config are executed within it
+func (c *config) BeginTx(ctx context.Context) (db.Tx, error) {
+	tx := c.db.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &txConfig{config: &config{db: tx}}, nil
+}
+
+func (c *txConfig) Commit(ctx context.Context) error {
+	return c.db.Commit().Error
+}
+
+func (c *txConfig) Rollback(ctx context.Context) error {
+	return c.db.Rollback().Error
+}
+
 func (c *config) GenCustomType(t reflect.Type) (interface{}, bool) {
 	// Check if the type is a pointer
 	if t.Kind() == reflect.Ptr {
@@ -56,7 +258,7 @@ func (c *config) GenCustomType(t reflect.Type) (interface{}, bool) {
 	}
 
 	// Handle specific types
-	switch t.String() {
+	switch t {
 	case jsonType:
 		return datatypes.JSON([]byte(`{"test": "test"}`)), true
 	case dateType:
@@ -67,3 +269,44 @@ func (c *config) GenCustomType(t reflect.Type) (interface{}, bool) {
 		return nil, false
 	}
 }
+
+func (c *config) ConvertValue(field reflect.StructField, v interface{}) (interface{}, bool) {
+	return nil, false
+}
+
+// create inserts the value(pointer to the struct) into the table without the omitted fields.
+// If noGeneratedID is true, the value is inserted as the map of the columns,
+// so gorm neither returns the generated ID nor sets it to the primary key,
+// and the primary key is inserted as the other fields unless it's omitted
+func create(tx *gorm.DB, table string, v interface{}, omitFields []string, noGeneratedID bool) error {
+	if !noGeneratedID {
+		return tx.Table(table).Omit(omitFields...).Create(v).Error
+	}
+
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(v); err != nil {
+		return err
+	}
+
+	val := reflect.ValueOf(v).Elem()
+	row := map[string]interface{}{}
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" || !field.Creatable || slices.Contains(omitFields, field.Name) {
+			continue
+		}
+
+		row[field.DBName], _ = field.ValueOf(tx.Statement.Context, val)
+	}
+
+	return tx.Table(table).Create(row).Error
+}
+
+// checkStorageName checks the storage name is a plain identifier.
+// Gorm treats the table name containing spaces as raw SQL, so the suspicious names are rejected
+func checkStorageName(name string) error {
+	if !utils.IsValidIdentifier(name) {
+		return fmt.Errorf("%w: %q", errInvalidStorageName, name)
+	}
+
+	return nil
+}
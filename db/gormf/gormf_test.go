@@ -2,6 +2,7 @@ package gormf
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -14,6 +15,7 @@ import (
 	"gorm.io/gorm/logger"
 
 	"github.com/eyo-chen/gofacto"
+	"github.com/eyo-chen/gofacto/internal/db"
 	"github.com/eyo-chen/gofacto/internal/docker"
 	"github.com/eyo-chen/gofacto/internal/testutils"
 )
@@ -172,6 +174,7 @@ func (s *testingSuite) Run(t *testing.T) {
 		{"TestInsertList", s.TestInsertList},
 		{"TestWithOne", s.TestWithOne},
 		{"TestWithMany", s.TestWithMany},
+		{"TestExpectRow", s.TestExpectRow},
 		// {"TestListWithOne", s.TestListWithOne},
 	}
 
@@ -744,3 +747,57 @@ func withMany_MultiLevelDiffAmount(s *testingSuite, t *testing.T) {
 		}
 	}
 }
+
+func (s *testingSuite) TestExpectRow(t *testing.T) {
+	// prepare mock data
+	mockAuthors, err := s.authorF.BuildList(mockCTX, 2).Insert()
+	if err != nil {
+		t.Fatalf("Failed to insert authors: %s", err)
+	}
+
+	// assertion
+	author := s.authorF.ExpectRow(mockCTX, t,
+		gofacto.WithField("Email", mockAuthors[1].Email),
+		gofacto.WithField("FirstName", mockAuthors[1].FirstName))
+	if author.ID != mockAuthors[1].ID {
+		t.Fatalf("ID should be %d, got %d", mockAuthors[1].ID, author.ID)
+	}
+}
+
+type softDeleteAuthor struct {
+	ID        int64
+	Name      string
+	DeletedAt gorm.DeletedAt
+}
+
+func TestDeleteList_SoftDelete(t *testing.T) {
+	// the statements are not executed in the dry run mode, so the database doesn't need to be running
+	gdb, err := gorm.Open(mysql.New(mysql.Config{DSN: "root:password@tcp(localhost:3306)/test", SkipInitializeWithVersion: true}), &gorm.Config{DryRun: true, SkipDefaultTransaction: true, DisableAutomaticPing: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	c := NewConfig(gdb)
+
+	tests := []struct {
+		desc    string
+		value   interface{}
+		wantErr error
+	}{
+		{desc: "model with gorm.DeletedAt", value: &softDeleteAuthor{ID: 1}, wantErr: nil},
+		{desc: "model without gorm.DeletedAt", value: &Author{ID: 1}, wantErr: errSoftDeleteNotSupported},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			err := c.DeleteList(mockCTX, db.DeleteListParams{
+				StorageName:  "authors",
+				IDField:      "ID",
+				Values:       []interface{}{test.value},
+				IsSoftDelete: true,
+			})
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("error should be %v, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
@@ -1,12 +1,18 @@
 package gormf
 
+import (
+	"reflect"
+
+	"gorm.io/datatypes"
+)
+
 var (
-	// json is the string representation of datatypes.JSON
-	jsonType = "datatypes.JSON"
+	// jsonType is the type of datatypes.JSON
+	jsonType = reflect.TypeOf(datatypes.JSON{})
 
-	// date is the string representation of datatypes.Date
-	dateType = "datatypes.Date"
+	// dateType is the type of datatypes.Date
+	dateType = reflect.TypeOf(datatypes.Date{})
 
-	// time is the string representation of datatypes.Time
-	timeType = "datatypes.Time"
+	// timeType is the type of datatypes.Time
+	timeType = reflect.TypeOf(datatypes.Time(0))
 )
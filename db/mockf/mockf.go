@@ -0,0 +1,279 @@
+// Package mockf provides a mock database adapter whose behavior is scriptable.
+//
+// It's useful for testing the code using gofacto factories without a real database,
+// e.g. the failure paths of the fixture helpers.
+package mockf
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/eyo-chen/gofacto/internal/db"
+)
+
+// Config is the mock database adapter.
+// It sets the sequential IDs(1, 2, 3...) to the ID field unless the ID isn't generated, and captures the received values
+type Config struct {
+	mu sync.Mutex
+
+	// numCalls is the number of Insert and InsertList calls
+	numCalls int
+
+	// failures is a map from the call number to the error returned by the call
+	failures map[int]error
+
+	// latency is the latency injected before each call
+	latency time.Duration
+
+	// names is the names identifying the mock database, e.g. the database name and the host
+	names []string
+
+	// nextID is the next ID set to the ID field
+	nextID int
+
+	// inserted, updated, and deleted are maps from the storage name to the received values in order
+	inserted map[string][]interface{}
+	updated  map[string][]interface{}
+	deleted  map[string][]interface{}
+}
+
+// NewConfig creates a new mock database adapter
+func NewConfig() *Config {
+	return &Config{
+		failures: map[int]error{},
+		nextID:   1,
+		inserted: map[string][]interface{}{},
+		updated:  map[string][]interface{}{},
+		deleted:  map[string][]interface{}{},
+	}
+}
+
+// FailOnCall makes the nth(starting from 1) Insert or InsertList call return the error.
+// Nothing is inserted by the failed call
+func (c *Config) FailOnCall(n int, err error) *Config {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures[n] = err
+	return c
+}
+
+// WithLatency injects the latency before each call.
+// The call returns the context error if the context is done while waiting
+func (c *Config) WithLatency(d time.Duration) *Config {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.latency = d
+	return c
+}
+
+// WithNames sets the names identifying the mock database, the first one is the database name, followed by the others, e.g. the host.
+// The database name is checked against the allow-list set by WithAllowedDBs of the factory
+func (c *Config) WithNames(names ...string) *Config {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.names = names
+	return c
+}
+
+// NumCalls returns the number of Insert and InsertList calls, including the failed ones
+func (c *Config) NumCalls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.numCalls
+}
+
+// Inserted returns the values inserted into the storage in order
+func (c *Config) Inserted(storageName string) []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]interface{}(nil), c.inserted[storageName]...)
+}
+
+// Updated returns the values updated in the storage in order
+func (c *Config) Updated(storageName string) []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]interface{}(nil), c.updated[storageName]...)
+}
+
+// Deleted returns the values deleted from the storage in order
+func (c *Config) Deleted(storageName string) []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]interface{}(nil), c.deleted[storageName]...)
+}
+
+func (c *Config) Insert(ctx context.Context, params db.InsertParams) (interface{}, error) {
+	if err := c.call(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !params.NoGeneratedID {
+		c.setIDField(params.Value, params.IDField)
+	}
+	c.inserted[params.StorageName] = append(c.inserted[params.StorageName], params.Value)
+	return params.Value, nil
+}
+
+func (c *Config) InsertList(ctx context.Context, params db.InsertListParams) ([]interface{}, error) {
+	if err := c.call(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !params.NoGeneratedID {
+		for _, v := range params.Values {
+			c.setIDField(v, params.IDField)
+		}
+	}
+	c.inserted[params.StorageName] = append(c.inserted[params.StorageName], params.Values...)
+	return params.Values, nil
+}
+
+func (c *Config) Update(ctx context.Context, params db.UpdateParams) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.updated[params.StorageName] = append(c.updated[params.StorageName], params.Value)
+	return nil
+}
+
+func (c *Config) DeleteList(ctx context.Context, params db.DeleteListParams) error {
+	if err := c.wait(ctx); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deleted[params.StorageName] = append(c.deleted[params.StorageName], params.Values...)
+	return nil
+}
+
+// Find returns the values inserted into the storage in order, whose fields equal the values of all the conditions.
+// The deleted values are still returned, because they are only captured
+func (c *Config) Find(ctx context.Context, params db.FindParams) ([]interface{}, error) {
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var result []interface{}
+	for _, v := range c.inserted[params.StorageName] {
+		if params.Limit > 0 && len(result) >= params.Limit {
+			break
+		}
+
+		if matches(v, params.Conditions) {
+			result = append(result, v)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *Config) GenCustomType(reflect.Type) (interface{}, bool) {
+	return nil, false
+}
+
+func (c *Config) ConvertValue(reflect.StructField, interface{}) (interface{}, bool) {
+	return nil, false
+}
+
+// Identify returns the names set by WithNames
+func (c *Config) Identify(context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string(nil), c.names...), nil
+}
+
+// call counts the insert call, and returns the scripted error of the call
+func (c *Config) call(ctx context.Context) error {
+	c.mu.Lock()
+	c.numCalls++
+	err := c.failures[c.numCalls]
+	c.mu.Unlock()
+
+	if waitErr := c.wait(ctx); waitErr != nil {
+		return waitErr
+	}
+
+	return err
+}
+
+// wait waits for the injected latency
+func (c *Config) wait(ctx context.Context) error {
+	c.mu.Lock()
+	latency := c.latency
+	c.mu.Unlock()
+
+	if latency <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(latency)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// setIDField sets the next sequential ID to the ID field(name) if it's an integer or string
+func (c *Config) setIDField(v interface{}, name string) {
+	idField := reflect.ValueOf(v).Elem().FieldByName(name)
+	if name == "" || !idField.IsValid() || !idField.CanSet() {
+		return
+	}
+
+	switch idField.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		idField.SetInt(int64(c.nextID))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		idField.SetUint(uint64(c.nextID))
+	case reflect.String:
+		idField.SetString(strconv.Itoa(c.nextID))
+	default:
+		return
+	}
+
+	c.nextID++
+}
+
+// matches checks if the fields of the value(pointer to the struct) equal the values of all the conditions
+func matches(v interface{}, conds []db.Condition) bool {
+	val := reflect.ValueOf(v).Elem()
+	for _, cond := range conds {
+		field := val.FieldByName(cond.Field)
+		if !field.IsValid() || !reflect.DeepEqual(field.Interface(), cond.Value) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,140 @@
+package mockf
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/eyo-chen/gofacto"
+	"github.com/eyo-chen/gofacto/internal/db"
+)
+
+var (
+	mockCTX = context.Background()
+)
+
+type Author struct {
+	ID   int
+	Name string
+}
+
+type Book struct {
+	ID       int
+	AuthorID int `gofacto:"foreignKey,struct:Author"`
+	Title    string
+}
+
+func TestInsert(t *testing.T) {
+	c := NewConfig()
+	f := gofacto.New(Author{}).WithDB(c)
+
+	author, err := f.Build(mockCTX).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	authors, err := f.BuildList(mockCTX, 2).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if author.ID != 1 || authors[0].ID != 2 || authors[1].ID != 3 {
+		t.Fatalf("IDs should be sequential, got %v, %v, %v", author.ID, authors[0].ID, authors[1].ID)
+	}
+
+	inserted := c.Inserted("authors")
+	if len(inserted) != 3 || inserted[0].(*Author).Name != author.Name {
+		t.Fatalf("inserted values should be captured in order, got %v", inserted)
+	}
+	if c.NumCalls() != 2 {
+		t.Fatalf("NumCalls should be 2, got %v", c.NumCalls())
+	}
+}
+
+func TestFailOnCall(t *testing.T) {
+	wantErr := errors.New("insert error")
+	c := NewConfig().FailOnCall(2, wantErr)
+	f := gofacto.New(Book{}).WithDB(c)
+
+	// the author is inserted by the 1st call, and the book fails on the 2nd call
+	_, err := f.Build(mockCTX).WithOne(&Author{}).Insert()
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("error should be %v, got %v", wantErr, err)
+	}
+
+	if len(c.Inserted("books")) != 0 {
+		t.Fatalf("book should not be inserted")
+	}
+	if len(c.Deleted("authors")) != 1 {
+		t.Fatalf("inserted author should be rolled back")
+	}
+
+	// the following calls succeed
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestWithLatency(t *testing.T) {
+	c := NewConfig().WithLatency(50 * time.Millisecond)
+	f := gofacto.New(Author{}).WithDB(c)
+
+	ctx, cancel := context.WithTimeout(mockCTX, 10*time.Millisecond)
+	defer cancel()
+
+	_, err := f.Build(ctx).Insert()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error should be %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if len(c.Inserted("authors")) != 0 {
+		t.Fatalf("author should not be inserted")
+	}
+}
+
+func TestUpdateAndDelete(t *testing.T) {
+	c := NewConfig()
+	f := gofacto.New(Author{}).WithDB(c)
+
+	fx, err := f.Build(mockCTX).InsertFixture()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := fx.Update(mockCTX, func(a *Author) { a.Name = "updated" }); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := fx.Delete(mockCTX); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if updated := c.Updated("authors"); len(updated) != 1 || updated[0].(*Author).Name != "updated" {
+		t.Fatalf("updated value should be captured, got %v", updated)
+	}
+	if len(c.Deleted("authors")) != 1 {
+		t.Fatalf("deleted value should be captured")
+	}
+}
+
+func TestFind(t *testing.T) {
+	c := NewConfig()
+	f := gofacto.New(Author{}).WithDB(c)
+
+	authors, err := f.BuildList(mockCTX, 3).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	got := f.ExpectRow(mockCTX, t, gofacto.WithField("Name", authors[1].Name))
+	if got.ID != authors[1].ID {
+		t.Fatalf("ID should be %d, got %d", authors[1].ID, got.ID)
+	}
+
+	rows, err := c.Find(mockCTX, db.FindParams{StorageName: "authors", Value: &Author{}, Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("number of rows should be limited to 2, got %d", len(rows))
+	}
+}
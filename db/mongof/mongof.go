@@ -2,18 +2,64 @@ package mongof
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/eyo-chen/gofacto/internal/db"
 )
 
+// defaultSoftDeleteField is the field marked with the deleted time when soft deleting by default
+const defaultSoftDeleteField = "deleted_at"
+
+var (
+	// errIDTypeMismatch is the error representing that the generated ID can't be set to the ID field
+	errIDTypeMismatch = errors.New("generated ID type mismatch")
+
+	// errFieldNotFound is the error representing that the field of the condition is not found
+	errFieldNotFound = errors.New("field not found")
+)
+
+// IDGenerator is a client-defined function to generate the ID of the document inserted into the collection,
+// e.g. UUID or slug
+type IDGenerator func(collName string) interface{}
+
 // config is for MongoDB configuration
 type config struct {
 	// db is the database connection
 	db *mongo.Database
+
+	// sess is the session of the shared transaction, it's only set when the config is created by BeginTx
+	sess mongo.Session
+
+	// idGen generates the ID of the document when the ID field is zero, nil means generated by MongoDB
+	idGen IDGenerator
+
+	// ttlField is the field set to the expiry time of the document, empty means the document never expires
+	ttlField string
+	ttl      time.Duration
+
+	// ttlIndexed is the set of the collections whose TTL index is ensured
+	ttlIndexed *sync.Map
+
+	// metadata is the fields set on every inserted document, e.g. source="gofacto"
+	metadata map[string]interface{}
+
+	// softDeleteField is the field marked with the deleted time when soft deleting, empty means deleted_at
+	softDeleteField string
+}
+
+// txConfig is the config bound to a shared transaction
+type txConfig struct {
+	*config
 }
 
 // NewConfig creates a new MongoDB configuration
@@ -23,39 +69,397 @@ func NewConfig(db *mongo.Database) *config {
 	}
 }
 
+// WithIDGenerator sets the generator of the ID, it's called when the ID field of the document is zero.
+// The generated ID must be assignable or convertible to the type of the ID field
+func (c *config) WithIDGenerator(gen IDGenerator) *config {
+	c.idGen = gen
+	return c
+}
+
+// WithTTL sets the field(key of the document) to the expiry time, which is the insertion time plus the ttl,
+// so the seeded documents in the shared environments expire automatically.
+// The TTL index of the field is created on the collection when inserting the first document
+func (c *config) WithTTL(field string, ttl time.Duration) *config {
+	c.ttlField, c.ttl = field, ttl
+	c.ttlIndexed = &sync.Map{}
+	return c
+}
+
+// WithMetadata sets the fields(keys of the document) on every inserted document, e.g. createdBy or source.
+// The fields overwrite the fields of the value with the same keys
+func (c *config) WithMetadata(metadata map[string]interface{}) *config {
+	c.metadata = metadata
+	return c
+}
+
+// WithSoftDeleteField sets the field(key of the document) marked with the deleted time when soft deleting,
+// e.g. the factories configured with WithIsSoftDelete(true). By default, it's deleted_at
+func (c *config) WithSoftDeleteField(field string) *config {
+	c.softDeleteField = field
+	return c
+}
+
 func (c *config) Insert(ctx context.Context, params db.InsertParams) (interface{}, error) {
-	res, err := c.db.Collection(params.StorageName).InsertOne(ctx, params.Value)
+	if err := c.ensureTTLIndex(ctx, params.StorageName); err != nil {
+		return nil, err
+	}
+
+	ctx = c.withSession(ctx)
+	idField := idFieldName(params.Value, params.IDField)
+	if err := c.genID(params.StorageName, params.Value, idField); err != nil {
+		return nil, err
+	}
+
+	doc, err := c.document(params.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.db.Collection(params.StorageName).InsertOne(ctx, doc)
 	if err != nil {
 		return nil, err
 	}
 
-	id := res.InsertedID.(primitive.ObjectID)
-	setIDField(params.Value, id)
+	if !params.NoGeneratedID {
+		setIDField(params.Value, idField, res.InsertedID)
+	}
+
 	return params.Value, nil
 }
 
 func (c *config) InsertList(ctx context.Context, params db.InsertListParams) ([]interface{}, error) {
-	res, err := c.db.Collection(params.StorageName).InsertMany(ctx, params.Values)
-	if err != nil {
+	if len(params.Values) == 0 {
+		return params.Values, nil
+	}
+
+	if err := c.ensureTTLIndex(ctx, params.StorageName); err != nil {
 		return nil, err
 	}
 
-	for i, rawID := range res.InsertedIDs {
-		id := rawID.(primitive.ObjectID)
-		setIDField(params.Values[i], id)
+	ctx = c.withSession(ctx)
+	idField := idFieldName(params.Values[0], params.IDField)
+	docs := make([]interface{}, len(params.Values))
+	for i, v := range params.Values {
+		if err := c.genID(params.StorageName, v, idField); err != nil {
+			return nil, err
+		}
+
+		doc, err := c.document(v)
+		if err != nil {
+			return nil, err
+		}
+		docs[i] = doc
+	}
+
+	res, err := c.db.Collection(params.StorageName).InsertMany(ctx, docs)
+	if err != nil {
+		c.deleteInserted(ctx, params.StorageName, res, err)
+		return nil, &db.InsertError{Index: failedIndex(err), Err: err}
+	}
+
+	if !params.NoGeneratedID {
+		for i, id := range res.InsertedIDs {
+			setIDField(params.Values[i], idField, id)
+		}
 	}
 
 	return params.Values, nil
 }
 
+func (c *config) Update(ctx context.Context, params db.UpdateParams) error {
+	ctx = c.withSession(ctx)
+	id := reflect.ValueOf(params.Value).Elem().FieldByName(idFieldName(params.Value, params.IDField))
+	if !id.IsValid() {
+		return nil
+	}
+
+	// the expiry time is refreshed, so the updated document lives for the ttl as well
+	doc, err := c.document(params.Value)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.db.Collection(params.StorageName).ReplaceOne(ctx, bson.M{"_id": id.Interface()}, doc)
+	return err
+}
+
+func (c *config) DeleteList(ctx context.Context, params db.DeleteListParams) error {
+	ctx = c.withSession(ctx)
+	ids := make([]interface{}, 0, len(params.Values))
+	for _, v := range params.Values {
+		id := reflect.ValueOf(v).Elem().FieldByName(idFieldName(v, params.IDField))
+		if id.IsValid() {
+			ids = append(ids, id.Interface())
+		}
+	}
+
+	filter := bson.M{"_id": bson.M{"$in": ids}}
+	coll := c.db.Collection(params.StorageName)
+	if params.IsSoftDelete {
+		_, err := coll.UpdateMany(ctx, filter, bson.M{"$set": bson.M{c.softDeleteKey(): time.Now()}})
+		return err
+	}
+
+	_, err := coll.DeleteMany(ctx, filter)
+	return err
+}
+
+// softDeleteKey returns the field marked with the deleted time when soft deleting
+func (c *config) softDeleteKey() string {
+	if c.softDeleteField == "" {
+		return defaultSoftDeleteField
+	}
+
+	return c.softDeleteField
+}
+
+// Find returns the documents of the collection matching all the conditions, the documents are decoded into the values of the struct type.
+// The fields are mapped to the keys by the bson tags, or the lowercased field names by default
+func (c *config) Find(ctx context.Context, params db.FindParams) ([]interface{}, error) {
+	ctx = c.withSession(ctx)
+	typ := reflect.TypeOf(params.Value).Elem()
+
+	filter := bson.D{}
+	for _, cond := range params.Conditions {
+		field, ok := typ.FieldByName(cond.Field)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errFieldNotFound, cond.Field)
+		}
+
+		filter = append(filter, bson.E{Key: bsonKey(field), Value: cond.Value})
+	}
+
+	opts := options.Find()
+	if params.Limit > 0 {
+		opts.SetLimit(int64(params.Limit))
+	}
+
+	cur, err := c.db.Collection(params.StorageName).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := reflect.New(reflect.SliceOf(typ))
+	if err := cur.All(ctx, docs.Interface()); err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, docs.Elem().Len())
+	for i := range result {
+		result[i] = docs.Elem().Index(i).Addr().Interface()
+	}
+
+	return result, nil
+}
+
+// Identify returns the name of the database
+func (c *config) Identify(context.Context) ([]string, error) {
+	return []string{c.db.Name()}, nil
+}
+
+// BeginTx starts a shared transaction, all the operations of the returned config are executed within it.
+// Note that MongoDB only supports transactions on replica sets and sharded clusters
+func (c *config) BeginTx(ctx context.Context) (db.Tx, error) {
+	sess, err := c.db.Client().StartSession()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sess.StartTransaction(); err != nil {
+		sess.EndSession(ctx)
+		return nil, err
+	}
+
+	txc := *c
+	txc.sess = sess
+	return &txConfig{config: &txc}, nil
+}
+
+func (c *txConfig) Commit(ctx context.Context) error {
+	defer c.sess.EndSession(ctx)
+	return c.sess.CommitTransaction(ctx)
+}
+
+func (c *txConfig) Rollback(ctx context.Context) error {
+	defer c.sess.EndSession(ctx)
+	return c.sess.AbortTransaction(ctx)
+}
+
+// withSession binds the session of the shared transaction to the context if there is one
+func (c *config) withSession(ctx context.Context) context.Context {
+	if c.sess == nil {
+		return ctx
+	}
+
+	return mongo.NewSessionContext(ctx, c.sess)
+}
+
 func (c *config) GenCustomType(t reflect.Type) (interface{}, bool) {
 	return nil, false
 }
 
-// setIDField sets the ID field of the value to the given ID
-func setIDField(val interface{}, id primitive.ObjectID) {
-	v := reflect.ValueOf(val).Elem().FieldByName("ID")
-	if v.IsValid() && v.CanSet() {
-		v.Set(reflect.ValueOf(id))
+func (c *config) ConvertValue(field reflect.StructField, v interface{}) (interface{}, bool) {
+	return nil, false
+}
+
+// deleteInserted deletes the documents inserted before InsertMany failed.
+// InsertMany is ordered, so the documents before the first write error are inserted
+func (c *config) deleteInserted(ctx context.Context, collName string, res *mongo.InsertManyResult, err error) {
+	var bwe mongo.BulkWriteException
+	if res == nil || !errors.As(err, &bwe) || len(bwe.WriteErrors) == 0 {
+		return
+	}
+
+	n := bwe.WriteErrors[0].Index
+	if n > len(res.InsertedIDs) {
+		n = len(res.InsertedIDs)
+	}
+	if n == 0 {
+		return
+	}
+
+	// the original error is more important, so the error of compensation is ignored
+	_, _ = c.db.Collection(collName).DeleteMany(ctx, bson.M{"_id": bson.M{"$in": res.InsertedIDs[:n]}})
+}
+
+// failedIndex returns the index of the first document failed to be inserted by InsertMany, -1 means unknown
+func failedIndex(err error) int {
+	var bwe mongo.BulkWriteException
+	if !errors.As(err, &bwe) || len(bwe.WriteErrors) == 0 {
+		return -1
+	}
+
+	return bwe.WriteErrors[0].Index
+}
+
+// document returns the document of the value to insert, with the expiry time and the metadata fields.
+// It returns the value as is if there is no such field
+func (c *config) document(val interface{}) (interface{}, error) {
+	if c.ttlField == "" && len(c.metadata) == 0 {
+		return val, nil
+	}
+
+	b, err := bson.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc bson.D
+	if err := bson.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(c.metadata))
+	for k := range c.metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		doc = setDocField(doc, k, c.metadata[k])
+	}
+
+	if c.ttlField != "" {
+		doc = setDocField(doc, c.ttlField, time.Now().Add(c.ttl))
+	}
+
+	return doc, nil
+}
+
+// ensureTTLIndex creates the TTL index of the ttl field on the collection once.
+// The index expires the document at the time of the field
+func (c *config) ensureTTLIndex(ctx context.Context, collName string) error {
+	if c.ttlField == "" {
+		return nil
+	}
+
+	if _, ok := c.ttlIndexed.Load(collName); ok {
+		return nil
+	}
+
+	// the index can't be created within the transaction, so the session is not bound
+	_, err := c.db.Collection(collName).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: c.ttlField, Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("create TTL index on %s: %w", collName, err)
+	}
+
+	c.ttlIndexed.Store(collName, struct{}{})
+	return nil
+}
+
+// setDocField sets the field of the document, it replaces the field with the same key
+func setDocField(doc bson.D, key string, val interface{}) bson.D {
+	for i, e := range doc {
+		if e.Key == key {
+			doc[i].Value = val
+			return doc
+		}
+	}
+
+	return append(doc, bson.E{Key: key, Value: val})
+}
+
+// genID sets the ID generated by the ID generator when the ID field(name) of the value is zero
+func (c *config) genID(collName string, val interface{}, name string) error {
+	if c.idGen == nil {
+		return nil
+	}
+
+	v := reflect.ValueOf(val).Elem().FieldByName(name)
+	if name == "" || !v.IsValid() || !v.CanSet() || !v.IsZero() {
+		return nil
+	}
+
+	id := reflect.ValueOf(c.idGen(collName))
+	switch {
+	case !id.IsValid():
+		return nil
+	case id.Type().AssignableTo(v.Type()):
+		v.Set(id)
+	case id.Type().ConvertibleTo(v.Type()) && id.Kind() == v.Kind():
+		v.Set(id.Convert(v.Type()))
+	default:
+		return fmt.Errorf("%w: %v can't be set to %s(%v)", errIDTypeMismatch, id.Type(), name, v.Type())
+	}
+
+	return nil
+}
+
+// idFieldName returns the name of the field tagged with `bson:"_id"` of the value,
+// and falls back to the given name if there is no such field
+func idFieldName(val interface{}, name string) string {
+	typ := reflect.TypeOf(val).Elem()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if bsonName, _, _ := strings.Cut(field.Tag.Get("bson"), ","); bsonName == "_id" {
+			return field.Name
+		}
+	}
+
+	return name
+}
+
+// bsonKey returns the key of the field in the document, which is the name of the bson tag,
+// or the lowercased field name if the tag doesn't name it
+func bsonKey(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("bson"), ","); name != "" {
+		return name
+	}
+
+	return strings.ToLower(field.Name)
+}
+
+// setIDField sets the ID field(name) of the value to the inserted ID if the types match
+func setIDField(val interface{}, name string, id interface{}) {
+	v := reflect.ValueOf(val).Elem().FieldByName(name)
+	if name == "" || !v.IsValid() || !v.CanSet() || id == nil {
+		return
+	}
+
+	if idVal := reflect.ValueOf(id); idVal.Type().AssignableTo(v.Type()) {
+		v.Set(idVal)
 	}
 }
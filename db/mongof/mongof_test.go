@@ -2,8 +2,10 @@ package mongof
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"reflect"
 	"testing"
 	"time"
 
@@ -93,6 +95,9 @@ func (s *testingSuite) Run(t *testing.T) {
 	}{
 		{"TestInsert", s.TestInsert},
 		{"TestInsertList", s.TestInsertList},
+		{"TestInsertWithIDGenerator", s.TestInsertWithIDGenerator},
+		{"TestInsertWithTTLAndMetadata", s.TestInsertWithTTLAndMetadata},
+		{"TestExpectRow", s.TestExpectRow},
 	}
 
 	for _, test := range tests {
@@ -158,3 +163,174 @@ func (s *testingSuite) TestInsertList(t *testing.T) {
 		t.Fatalf("Inserted persons are not the same as the mock persons: %s", err)
 	}
 }
+
+type Slug struct {
+	Key  string `bson:"_id"`
+	Name string `bson:"name"`
+}
+
+type slugID string
+
+type TypedSlug struct {
+	ID   slugID `bson:"_id"`
+	Name string `bson:"name"`
+}
+
+func (s *testingSuite) TestInsertWithIDGenerator(t *testing.T) {
+	i := 0
+	f := gofacto.New(Slug{}).WithDB(NewConfig(s.db).WithIDGenerator(func(collName string) interface{} {
+		i++
+		return fmt.Sprintf("%s-%d", collName, i)
+	}))
+
+	mockSlugs, err := f.BuildList(mockCTX, 2).Insert()
+	if err != nil {
+		t.Fatalf("Failed to insert slugs: %s", err)
+	}
+
+	for i, mockSlug := range mockSlugs {
+		if want := fmt.Sprintf("slugs-%d", i+1); mockSlug.Key != want {
+			t.Fatalf("Key should be %s, got %s", want, mockSlug.Key)
+		}
+
+		var slug Slug
+		if err := s.db.Collection("slugs").FindOne(mockCTX, bson.M{"_id": mockSlug.Key}).Decode(&slug); err != nil {
+			t.Fatalf("Failed to find slug: %s", err)
+		}
+
+		if err := testutils.CompareVal(mockSlug, slug); err != nil {
+			t.Fatalf("Inserted slug is not the same as the mock slug: %s", err)
+		}
+	}
+}
+
+func (s *testingSuite) TestExpectRow(t *testing.T) {
+	// prepare mock data
+	mockPersons, err := s.f.BuildList(mockCTX, 2).Insert()
+	if err != nil {
+		t.Fatalf("Failed to insert persons: %s", err)
+	}
+
+	// assertion
+	person := s.f.ExpectRow(mockCTX, t, gofacto.WithField("Name", mockPersons[1].Name), gofacto.WithField("Age", mockPersons[1].Age))
+	if person.ID != mockPersons[1].ID {
+		t.Fatalf("ID should be %v, got %v", mockPersons[1].ID, person.ID)
+	}
+}
+
+func TestGenID(t *testing.T) {
+	c := NewConfig(nil).WithIDGenerator(func(collName string) interface{} {
+		return collName + "-1"
+	})
+
+	// ID field is detected by the bson tag, and the generated ID is converted to the type of the ID field
+	v := TypedSlug{}
+	if err := c.genID("slugs", &v, idFieldName(&v, "")); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if v.ID != "slugs-1" {
+		t.Fatalf("ID should be slugs-1, got %s", v.ID)
+	}
+
+	// the non-zero ID is not overwritten
+	v = TypedSlug{ID: "custom"}
+	if err := c.genID("slugs", &v, idFieldName(&v, "")); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if v.ID != "custom" {
+		t.Fatalf("ID should be custom, got %s", v.ID)
+	}
+
+	// the generated ID can't be set to ObjectID
+	p := Person{}
+	if err := c.genID("persons", &p, idFieldName(&p, "")); !errors.Is(err, errIDTypeMismatch) {
+		t.Fatalf("error should be %v, got %v", errIDTypeMismatch, err)
+	}
+}
+
+func (s *testingSuite) TestInsertWithTTLAndMetadata(t *testing.T) {
+	f := gofacto.New(Slug{}).WithDB(NewConfig(s.db).
+		WithTTL("expire_at", time.Hour).
+		WithMetadata(map[string]interface{}{"source": "gofacto"}))
+
+	mockSlug, err := f.Build(mockCTX).Overwrite(Slug{Key: "slug"}).Insert()
+	if err != nil {
+		t.Fatalf("Failed to insert slug: %s", err)
+	}
+
+	var doc bson.M
+	if err := s.db.Collection("slugs").FindOne(mockCTX, bson.M{"_id": mockSlug.Key}).Decode(&doc); err != nil {
+		t.Fatalf("Failed to find slug: %s", err)
+	}
+
+	if doc["name"] != mockSlug.Name || doc["source"] != "gofacto" {
+		t.Fatalf("Inserted document is not expected: %v", doc)
+	}
+
+	expireAt, ok := doc["expire_at"].(primitive.DateTime)
+	if !ok || expireAt.Time().Before(time.Now()) {
+		t.Fatalf("expire_at should be in the future, got %v", doc["expire_at"])
+	}
+
+	cursor, err := s.db.Collection("slugs").Indexes().List(mockCTX)
+	if err != nil {
+		t.Fatalf("Failed to list indexes: %s", err)
+	}
+
+	var indexes []bson.M
+	if err := cursor.All(mockCTX, &indexes); err != nil {
+		t.Fatalf("Failed to decode indexes: %s", err)
+	}
+
+	if len(indexes) != 2 {
+		t.Fatalf("TTL index should be created, got %v", indexes)
+	}
+}
+
+func TestDocument(t *testing.T) {
+	c := NewConfig(nil).
+		WithTTL("expire_at", time.Hour).
+		WithMetadata(map[string]interface{}{"source": "gofacto", "name": "overwritten"})
+
+	doc, err := c.document(&Slug{Key: "slug", Name: "name"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	d, ok := doc.(bson.D)
+	if !ok {
+		t.Fatalf("document should be bson.D, got %T", doc)
+	}
+
+	keys := make([]string, len(d))
+	for i, e := range d {
+		keys[i] = e.Key
+	}
+	if want := []string{"_id", "name", "source", "expire_at"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys should be %v, got %v", want, keys)
+	}
+
+	if d[1].Value != "overwritten" || d[2].Value != "gofacto" {
+		t.Fatalf("metadata should be set, got %v", d)
+	}
+
+	if expireAt, ok := d[3].Value.(time.Time); !ok || expireAt.Before(time.Now()) {
+		t.Fatalf("expire_at should be in the future, got %v", d[3].Value)
+	}
+
+	// the value is inserted as is without the ttl and metadata
+	v := &Slug{}
+	if doc, err := NewConfig(nil).document(v); err != nil || doc != v {
+		t.Fatalf("document should be the value, got %v, %v", doc, err)
+	}
+}
+
+func TestSoftDeleteKey(t *testing.T) {
+	if got := NewConfig(nil).softDeleteKey(); got != defaultSoftDeleteField {
+		t.Fatalf("soft delete field should be %s, got %s", defaultSoftDeleteField, got)
+	}
+
+	if got := NewConfig(nil).WithSoftDeleteField("removed_at").softDeleteKey(); got != "removed_at" {
+		t.Fatalf("soft delete field should be removed_at, got %s", got)
+	}
+}
@@ -3,37 +3,222 @@ package mysqlf
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/eyo-chen/gofacto/internal/sqllib"
+	"github.com/eyo-chen/gofacto/internal/utils"
 )
 
+const (
+	// packageName is the tag key of the package
+	packageName = "mysqlf"
+
+	// tagOptJSON is the tag option to mark the field as JSON column
+	tagOptJSON = "json"
+
+	// tagOptGeometry is the tag option to mark the field as spatial column
+	tagOptGeometry = "geometry"
+)
+
+var (
+	pointType = reflect.TypeOf(Point{})
+	timeType  = reflect.TypeOf(time.Time{})
+)
+
+var (
+	// errUnsupportedGeometry is the error representing that the value of the spatial column is neither a Point nor a WKT string
+	errUnsupportedGeometry = errors.New("unsupported geometry value")
+)
+
+// Point is the latitude and longitude of the POINT or GEOMETRY column
+type Point struct {
+	Lat float64
+	Lng float64
+}
+
 // NewConfig initializes interface for raw mySQL database operations
 func NewConfig(db *sql.DB) *sqllib.Config {
-	return sqllib.NewConfig(db, &mySQLDialect{}, "mysqlf")
+	return sqllib.NewConfig(db, &mySQLDialect{}, packageName)
 }
 
 // mySQLDialect defines the behavior for MySQL SQL dialect
 type mySQLDialect struct{}
 
-func (d *mySQLDialect) GenPlaceholder(_ int) string {
+func (d *mySQLDialect) GenPlaceholder(field reflect.StructField, _ int) string {
+	if isGeometry(field) {
+		return "ST_GeomFromText(?)"
+	}
+
 	return "?"
 }
 
-func (d *mySQLDialect) GenInsertStmt(tableName, fieldNames, placeholder string) string {
+func (d *mySQLDialect) GenInsertStmt(tableName, _, fieldNames, placeholder string) string {
 	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", tableName, fieldNames, placeholder)
 }
 
-func (d *mySQLDialect) InsertToDB(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, vals []interface{}) (int64, error) {
+func (d *mySQLDialect) InsertToDB(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, vals []interface{}) (interface{}, error) {
 	res, err := tx.Stmt(stmt).ExecContext(ctx, vals...)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	id, err := res.LastInsertId()
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	return id, nil
 }
+
+func (d *mySQLDialect) QuoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = "`" + p + "`"
+	}
+
+	return strings.Join(parts, ".")
+}
+
+func (d *mySQLDialect) GenIdentifyStmt() string {
+	return "SELECT DATABASE(), @@hostname"
+}
+
+func (d *mySQLDialect) GenColumnsStmt(tableName string) (string, []interface{}) {
+	schema, table, ok := strings.Cut(tableName, ".")
+	if !ok {
+		return "SELECT column_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ?", []interface{}{tableName}
+	}
+
+	return "SELECT column_name FROM information_schema.columns WHERE table_schema = ? AND table_name = ?", []interface{}{schema, table}
+}
+
+func (d *mySQLDialect) GenCustomType(t reflect.Type) (interface{}, bool) {
+	if t == pointType {
+		return Point{Lat: 1, Lng: 1}, true
+	}
+
+	return nil, false
+}
+
+func (d *mySQLDialect) ConvertValue(field reflect.StructField, v interface{}) (interface{}, bool) {
+	val := reflect.ValueOf(v)
+
+	// the nil interface is stored as NULL like the nil pointer, e.g. the interface field tagged as geometry
+	if !val.IsValid() {
+		return nil, false
+	}
+
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, false
+		}
+
+		val = val.Elem()
+	}
+
+	// DECIMAL receives the exact decimal string
+	if s, ok := utils.DecimalString(field, v); ok {
+		return s, true
+	}
+
+	if isGeometry(field) {
+		return toWKT(val), true
+	}
+
+	if hasTagOpt(field, tagOptJSON) {
+		return toJSON(val)
+	}
+
+	// the driver doesn't accept these kinds, store them as JSON
+	if _, ok := v.(driver.Valuer); ok {
+		return nil, false
+	}
+	switch val.Kind() {
+	case reflect.Map:
+		return toJSON(val)
+	case reflect.Struct:
+		if val.Type() == timeType {
+			return nil, false
+		}
+
+		return toJSON(val)
+	case reflect.Slice:
+		// []byte is accepted by the driver as BLOB
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			return nil, false
+		}
+
+		return toJSON(val)
+	default:
+		return nil, false
+	}
+}
+
+// toJSON marshals the value to JSON string.
+// The raw bytes, e.g. json.RawMessage, are regarded as the encoded JSON, and returned as they are
+func toJSON(val reflect.Value) (interface{}, bool) {
+	if val.Kind() == reflect.Slice && val.Type().Elem().Kind() == reflect.Uint8 {
+		return string(val.Bytes()), true
+	}
+
+	b, err := json.Marshal(val.Interface())
+	if err != nil {
+		return nil, false
+	}
+
+	return string(b), true
+}
+
+// toWKT converts the value to WKT(well-known text) string.
+// If the value is neither a Point nor a WKT string, e.g. generated string value,
+// the returned value fails with errUnsupportedGeometry when it's passed to the driver
+func toWKT(val reflect.Value) interface{} {
+	if val.Type() == pointType {
+		p := val.Interface().(Point)
+		return fmt.Sprintf("POINT(%v %v)", p.Lng, p.Lat)
+	}
+
+	if val.Kind() == reflect.String && strings.Contains(val.String(), "(") {
+		return val.String()
+	}
+
+	return invalidValue{err: fmt.Errorf("%w: %v", errUnsupportedGeometry, val.Interface())}
+}
+
+// invalidValue is the value which can't be converted, it fails with the error when it's passed to the driver,
+// so the insert returns the error instead of storing a wrong value
+type invalidValue struct {
+	err error
+}
+
+func (v invalidValue) Value() (driver.Value, error) {
+	return nil, v.err
+}
+
+// isGeometry checks if the field is a spatial column
+func isGeometry(field reflect.StructField) bool {
+	t := field.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t == pointType || hasTagOpt(field, tagOptGeometry)
+}
+
+// hasTagOpt checks if the mysqlf tag of the field contains the given option
+func hasTagOpt(field reflect.StructField, opt string) bool {
+	parts := strings.Split(field.Tag.Get(packageName), ",")
+	for _, p := range parts[1:] {
+		if p == opt {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,9 +3,14 @@ package mysqlf
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math/big"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -166,6 +171,8 @@ func (s *testingSuite) Run(t *testing.T) {
 		{"TestInsertList", s.TestInsertList},
 		{"TestWithOne", s.TestWithOne},
 		{"TestWithMany", s.TestWithMany},
+		{"TestWithSchemaCheck", s.TestWithSchemaCheck},
+		{"TestExpectRow", s.TestExpectRow},
 	}
 
 	for _, test := range tests {
@@ -862,3 +869,243 @@ func findAuthors(db *sql.DB, stmt string, args ...any) ([]Author, error) {
 
 	return authors, nil
 }
+
+func (s *testingSuite) TestWithSchemaCheck(t *testing.T) {
+	// the existing table and columns pass the check
+	if _, err := gofacto.New(Author{}).WithDB(NewConfig(s.db)).WithSchemaCheck(mockCTX).Build(mockCTX).Get(); err != nil {
+		t.Fatalf("Failed to check schema: %s", err)
+	}
+
+	// the missing table fails the check
+	_, err := gofacto.New(Author{}).
+		WithDB(NewConfig(s.db)).
+		WithStorageName("missing_authors").
+		WithSchemaCheck(mockCTX).
+		Build(mockCTX).
+		Get()
+	if err == nil || !strings.Contains(err.Error(), "table not found") {
+		t.Fatalf("error should be table not found, got %v", err)
+	}
+
+	// the field without the column fails the check
+	type AuthorNickname struct {
+		ID       int64
+		Nickname string
+	}
+	_, err = gofacto.New(AuthorNickname{}).
+		WithDB(NewConfig(s.db)).
+		WithStorageName("authors").
+		WithSchemaCheck(mockCTX).
+		Build(mockCTX).
+		Get()
+	if err == nil || !strings.Contains(err.Error(), "column not found: nickname") {
+		t.Fatalf("error should be column not found, got %v", err)
+	}
+}
+
+func (s *testingSuite) TestExpectRow(t *testing.T) {
+	// prepare mock data
+	mockAuthors, err := s.authorF.BuildList(mockCTX, 2).Insert()
+	if err != nil {
+		t.Fatalf("Failed to insert authors: %s", err)
+	}
+
+	// assertion
+	author := s.authorF.ExpectRow(mockCTX, t,
+		gofacto.WithField("Email", mockAuthors[1].Email),
+		gofacto.WithField("FirstName", mockAuthors[1].FirstName))
+	if author.ID != mockAuthors[1].ID {
+		t.Fatalf("ID should be %d, got %d", mockAuthors[1].ID, author.ID)
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		desc string
+		name string
+		want string
+	}{
+		{desc: "table name", name: "authors", want: "`authors`"},
+		{desc: "table name with schema", name: "mysql.authors", want: "`mysql`.`authors`"},
+	}
+
+	d := &mySQLDialect{}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := d.QuoteIdentifier(test.name); got != test.want {
+				t.Fatalf("QuoteIdentifier should be %s, got %s", test.want, got)
+			}
+		})
+	}
+}
+
+func TestGenColumnsStmt(t *testing.T) {
+	tests := []struct {
+		desc     string
+		name     string
+		wantStmt string
+		wantArgs []interface{}
+	}{
+		{desc: "table name", name: "authors", wantStmt: "SELECT column_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ?", wantArgs: []interface{}{"authors"}},
+		{desc: "table name with schema", name: "mysql.authors", wantStmt: "SELECT column_name FROM information_schema.columns WHERE table_schema = ? AND table_name = ?", wantArgs: []interface{}{"mysql", "authors"}},
+	}
+
+	d := &mySQLDialect{}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			stmt, args := d.GenColumnsStmt(test.name)
+			if stmt != test.wantStmt {
+				t.Fatalf("statement should be %s, got %s", test.wantStmt, stmt)
+			}
+
+			if err := testutils.CompareVal(args, test.wantArgs); err != nil {
+				t.Fatalf("arguments are not expected: %s", err)
+			}
+		})
+	}
+}
+
+func TestInvalidStorageName(t *testing.T) {
+	f := gofacto.New(Author{}).WithDB(NewConfig(nil)).WithStorageName("authors; DROP TABLE authors")
+
+	if _, err := f.Build(mockCTX).Insert(); err == nil || !strings.Contains(err.Error(), "invalid storage name") {
+		t.Fatalf("error should be invalid storage name, got %v", err)
+	}
+}
+
+type decimalStruct struct {
+	Rat         big.Rat
+	RatP        *big.Rat
+	RatScaled   big.Rat `gofacto:"decimal:10,2"`
+	Price       float64 `gofacto:"decimal:10,2"`
+	PlainFloat  float64
+	PriceString string `gofacto:"decimal:10,2"`
+}
+
+func TestConvertDecimal(t *testing.T) {
+	typ := reflect.TypeOf(decimalStruct{})
+	field := func(name string) reflect.StructField {
+		f, _ := typ.FieldByName(name)
+		return f
+	}
+
+	tests := []struct {
+		desc   string
+		field  string
+		value  interface{}
+		want   interface{}
+		wantOK bool
+	}{
+		{desc: "big.Rat", field: "Rat", value: *big.NewRat(1234, 100), want: "12.34", wantOK: true},
+		{desc: "pointer to big.Rat", field: "RatP", value: big.NewRat(1, 8), want: "0.125", wantOK: true},
+		{desc: "non-terminating big.Rat", field: "Rat", value: *big.NewRat(1, 3), want: "0." + strings.Repeat("3", 30), wantOK: true},
+		{desc: "big.Rat with decimal tag", field: "RatScaled", value: *big.NewRat(1, 8), want: "0.13", wantOK: true},
+		{desc: "float with decimal tag", field: "Price", value: 12.34, want: "12.34", wantOK: true},
+		{desc: "float without decimal tag", field: "PlainFloat", value: 12.34, want: nil, wantOK: false},
+		{desc: "string with decimal tag", field: "PriceString", value: "12.34", want: nil, wantOK: false},
+	}
+
+	d := &mySQLDialect{}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, ok := d.ConvertValue(field(test.field), test.value)
+			if ok != test.wantOK {
+				t.Fatalf("ok should be %v, got %v", test.wantOK, ok)
+			}
+
+			if got != test.want {
+				t.Fatalf("value should be %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+type geometryStruct struct {
+	Point    Point
+	PointP   *Point
+	Location interface{} `mysqlf:"location,geometry"`
+	Meta     interface{} `mysqlf:"meta,json"`
+}
+
+func TestConvertGeometry(t *testing.T) {
+	typ := reflect.TypeOf(geometryStruct{})
+	field := func(name string) reflect.StructField {
+		f, _ := typ.FieldByName(name)
+		return f
+	}
+
+	tests := []struct {
+		desc   string
+		field  string
+		value  interface{}
+		want   interface{}
+		wantOK bool
+	}{
+		{desc: "point", field: "Point", value: Point{Lat: 1, Lng: 2}, want: "POINT(2 1)", wantOK: true},
+		{desc: "pointer to point", field: "PointP", value: &Point{Lat: 1, Lng: 2}, want: "POINT(2 1)", wantOK: true},
+		{desc: "nil pointer to point", field: "PointP", value: (*Point)(nil), want: nil, wantOK: false},
+		{desc: "WKT string with geometry tag", field: "Location", value: "LINESTRING(0 0, 1 1)", want: "LINESTRING(0 0, 1 1)", wantOK: true},
+		{desc: "nil interface with geometry tag", field: "Location", value: nil, want: nil, wantOK: false},
+		{desc: "nil interface with json tag", field: "Meta", value: nil, want: nil, wantOK: false},
+	}
+
+	d := &mySQLDialect{}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, ok := d.ConvertValue(field(test.field), test.value)
+			if ok != test.wantOK {
+				t.Fatalf("ok should be %v, got %v", test.wantOK, ok)
+			}
+
+			if got != test.want {
+				t.Fatalf("value should be %v, got %v", test.want, got)
+			}
+		})
+	}
+
+	t.Run("generated string with geometry tag", func(t *testing.T) {
+		got, ok := d.ConvertValue(field("Location"), "test1")
+		if !ok {
+			t.Fatalf("ok should be true")
+		}
+
+		valuer, isValuer := got.(driver.Valuer)
+		if !isValuer {
+			t.Fatalf("value should be driver.Valuer, got %T", got)
+		}
+
+		if _, err := valuer.Value(); !errors.Is(err, errUnsupportedGeometry) {
+			t.Fatalf("error should be %v, got %v", errUnsupportedGeometry, err)
+		}
+	})
+}
+
+func TestConvertJSON(t *testing.T) {
+	field, _ := reflect.TypeOf(geometryStruct{}).FieldByName("Meta")
+
+	tests := []struct {
+		desc  string
+		value interface{}
+		want  interface{}
+	}{
+		{desc: "JSON-like string", value: "123", want: `"123"`},
+		{desc: "plain string", value: "abc", want: `"abc"`},
+		{desc: "map", value: map[string]int{"a": 1}, want: `{"a":1}`},
+		{desc: "json.RawMessage", value: json.RawMessage(`{"a":1}`), want: `{"a":1}`},
+		{desc: "bytes", value: []byte(`[1,2]`), want: `[1,2]`},
+	}
+
+	d := &mySQLDialect{}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, ok := d.ConvertValue(field, test.value)
+			if !ok {
+				t.Fatalf("ok should be true")
+			}
+
+			if got != test.want {
+				t.Fatalf("value should be %v, got %v", test.want, got)
+			}
+		})
+	}
+}
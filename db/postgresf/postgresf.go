@@ -3,9 +3,15 @@ package postgresf
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/eyo-chen/gofacto/internal/sqllib"
+	"github.com/eyo-chen/gofacto/internal/utils"
 )
 
 // NewConfig initializes interface for raw PostgreSQL database operations
@@ -16,20 +22,164 @@ func NewConfig(db *sql.DB) *sqllib.Config {
 // postgresDialect defines the behavior for PostgreSQL SQL dialect
 type postgresDialect struct{}
 
-func (d *postgresDialect) GenPlaceholder(placeholderIndex int) string {
+func (d *postgresDialect) GenPlaceholder(_ reflect.StructField, placeholderIndex int) string {
 	return fmt.Sprintf("$%d", placeholderIndex)
 }
 
-func (d *postgresDialect) GenInsertStmt(tableName, fieldNames, placeholder string) string {
-	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING id", tableName, fieldNames, placeholder)
+func (d *postgresDialect) GenInsertStmt(tableName, idColumn, fieldNames, placeholder string) string {
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING %s", tableName, fieldNames, placeholder, idColumn)
 }
 
-func (d *postgresDialect) InsertToDB(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, vals []interface{}) (int64, error) {
-	var id int64
+// GenBatchInsertStmt generates the insert statement of multiple rows.
+// The IDs are returned in the order of the rows of VALUES
+func (d *postgresDialect) GenBatchInsertStmt(tableName, idColumn, fieldNames string, rowPlaceholders []string) string {
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING %s", tableName, fieldNames, strings.Join(rowPlaceholders, "), ("), idColumn)
+}
+
+// QuoteIdentifier quotes the identifier with double quotes.
+// The identifier is lowercased first, because PostgreSQL folds the unquoted identifiers to lowercase
+func (d *postgresDialect) QuoteIdentifier(name string) string {
+	parts := strings.Split(strings.ToLower(name), ".")
+	for i, p := range parts {
+		parts[i] = `"` + p + `"`
+	}
+
+	return strings.Join(parts, ".")
+}
+
+func (d *postgresDialect) GenIdentifyStmt() string {
+	// inet_server_addr is NULL when connecting via the Unix socket
+	return "SELECT current_database(), COALESCE(host(inet_server_addr()), 'localhost')"
+}
+
+// GenColumnsStmt generates the statement querying the column names of the table,
+// the names are lowercased as QuoteIdentifier does
+func (d *postgresDialect) GenColumnsStmt(tableName string) (string, []interface{}) {
+	name := strings.ToLower(tableName)
+	schema, table, ok := strings.Cut(name, ".")
+	if !ok {
+		return "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1", []interface{}{name}
+	}
+
+	return "SELECT column_name FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2", []interface{}{schema, table}
+}
+
+func (d *postgresDialect) InsertToDB(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, vals []interface{}) (interface{}, error) {
+	var id interface{}
 	err := tx.Stmt(stmt).QueryRowContext(ctx, vals...).Scan(&id)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	return id, nil
 }
+
+func (d *postgresDialect) GenCustomType(_ reflect.Type) (interface{}, bool) {
+	return nil, false
+}
+
+func (d *postgresDialect) ConvertValue(field reflect.StructField, v interface{}) (interface{}, bool) {
+	// NUMERIC receives the exact decimal string
+	if s, ok := utils.DecimalString(field, v); ok {
+		return s, true
+	}
+
+	// the driver calls Value of the custom types, e.g. the map implementing driver.Valuer
+	if _, ok := v.(driver.Valuer); ok {
+		return nil, false
+	}
+
+	val := reflect.ValueOf(v)
+
+	switch val.Kind() {
+	case reflect.Map:
+		// map is not accepted by the driver, store it as JSON
+		return toJSON(v)
+	case reflect.Slice:
+		// []byte is accepted by the driver as BYTEA
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			return nil, false
+		}
+
+		return genArrayLiteral(val)
+	default:
+		return nil, false
+	}
+}
+
+// genArrayLiteral generates the PostgreSQL array literal of the given slice, e.g. {1,2,3}.
+// The slice of the non-scalar elements, e.g. the structs, is stored as JSON
+func genArrayLiteral(val reflect.Value) (interface{}, bool) {
+	if val.IsNil() {
+		return nil, true
+	}
+
+	elems := make([]string, val.Len())
+	for i := 0; i < val.Len(); i++ {
+		elem, ok := genArrayElem(val.Index(i))
+		if !ok {
+			return toJSON(val.Interface())
+		}
+
+		elems[i] = elem
+	}
+
+	return "{" + strings.Join(elems, ",") + "}", true
+}
+
+// genArrayElem generates the element of the PostgreSQL array literal,
+// it returns false if the element is not a scalar value
+func genArrayElem(e reflect.Value) (string, bool) {
+	if e.Kind() == reflect.Ptr {
+		if e.IsNil() {
+			return "NULL", true
+		}
+
+		e = e.Elem()
+	}
+
+	// the element is formatted as the value passed to the driver, e.g. UUID
+	if valuer, ok := e.Interface().(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return "", false
+		}
+
+		if v == nil {
+			return "NULL", true
+		}
+
+		e = reflect.ValueOf(v)
+	}
+
+	if t, ok := e.Interface().(time.Time); ok {
+		return quoteArrayElem(t.Format(time.RFC3339Nano)), true
+	}
+
+	switch e.Kind() {
+	case reflect.String:
+		return quoteArrayElem(e.String()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Bool:
+		return fmt.Sprint(e.Interface()), true
+	default:
+		return "", false
+	}
+}
+
+// quoteArrayElem quotes the element of the PostgreSQL array literal, and escapes the backslashes and double quotes
+func quoteArrayElem(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// toJSON marshals the value to JSON string
+func toJSON(v interface{}) (interface{}, bool) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, false
+	}
+
+	return string(b), true
+}
@@ -3,9 +3,12 @@ package postgresf
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"log"
+	"math/big"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -164,8 +167,11 @@ func (s *testingSuite) Run(t *testing.T) {
 	}{
 		{"TestInsert", s.TestInsert},
 		{"TestInsertList", s.TestInsertList},
+		{"TestInsertListBatch", s.TestInsertListBatch},
 		{"TestWithOne", s.TestWithOne},
 		{"TestWithMany", s.TestWithMany},
+		{"TestWithSchemaCheck", s.TestWithSchemaCheck},
+		{"TestExpectRow", s.TestExpectRow},
 	}
 
 	for _, test := range tests {
@@ -228,6 +234,26 @@ func (s *testingSuite) TestInsertList(t *testing.T) {
 	}
 }
 
+func (s *testingSuite) TestInsertListBatch(t *testing.T) {
+	// prepare mock data
+	mockAuthors, err := s.authorF.BuildList(mockCTX, 500).Insert()
+	if err != nil {
+		t.Fatalf("Failed to insert authors: %s", err)
+	}
+
+	// assertion, the IDs are set in the order of the rows
+	for _, mockAuthor := range mockAuthors {
+		author, err := findAuthor(s.db, "SELECT * FROM authors WHERE id = $1", mockAuthor.ID)
+		if err != nil {
+			t.Fatalf("Failed to find author: %s", err)
+		}
+
+		if author.FirstName != mockAuthor.FirstName {
+			t.Fatalf("Author %d should be %s, but got %s", mockAuthor.ID, mockAuthor.FirstName, author.FirstName)
+		}
+	}
+}
+
 func (s *testingSuite) TestWithOne(t *testing.T) {
 	for _, fn := range map[string]func(*testingSuite, *testing.T){
 		"when on builder, insert with association correctly":                              withOne_OnBuilder,
@@ -866,3 +892,206 @@ func findAuthors(db *sql.DB, stmt string, args ...any) ([]Author, error) {
 
 	return authors, nil
 }
+
+func (s *testingSuite) TestWithSchemaCheck(t *testing.T) {
+	// the existing table and columns pass the check
+	if _, err := gofacto.New(Author{}).WithDB(NewConfig(s.db)).WithSchemaCheck(mockCTX).Build(mockCTX).Get(); err != nil {
+		t.Fatalf("Failed to check schema: %s", err)
+	}
+
+	// the missing table fails the check
+	_, err := gofacto.New(Author{}).
+		WithDB(NewConfig(s.db)).
+		WithStorageName("missing_authors").
+		WithSchemaCheck(mockCTX).
+		Build(mockCTX).
+		Get()
+	if err == nil || !strings.Contains(err.Error(), "table not found") {
+		t.Fatalf("error should be table not found, got %v", err)
+	}
+
+	// the field without the column fails the check
+	type AuthorNickname struct {
+		ID       int64
+		Nickname string
+	}
+	_, err = gofacto.New(AuthorNickname{}).
+		WithDB(NewConfig(s.db)).
+		WithStorageName("authors").
+		WithSchemaCheck(mockCTX).
+		Build(mockCTX).
+		Get()
+	if err == nil || !strings.Contains(err.Error(), "column not found: nickname") {
+		t.Fatalf("error should be column not found, got %v", err)
+	}
+}
+
+func (s *testingSuite) TestExpectRow(t *testing.T) {
+	// prepare mock data
+	mockAuthors, err := s.authorF.BuildList(mockCTX, 2).Insert()
+	if err != nil {
+		t.Fatalf("Failed to insert authors: %s", err)
+	}
+
+	// assertion
+	author := s.authorF.ExpectRow(mockCTX, t,
+		gofacto.WithField("Email", mockAuthors[1].Email),
+		gofacto.WithField("FirstName", mockAuthors[1].FirstName))
+	if author.ID != mockAuthors[1].ID {
+		t.Fatalf("ID should be %d, got %d", mockAuthors[1].ID, author.ID)
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		desc string
+		name string
+		want string
+	}{
+		{desc: "table name", name: "authors", want: `"authors"`},
+		{desc: "table name with upper case", name: "Authors", want: `"authors"`},
+		{desc: "table name with schema", name: "public.authors", want: `"public"."authors"`},
+	}
+
+	d := &postgresDialect{}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := d.QuoteIdentifier(test.name); got != test.want {
+				t.Fatalf("QuoteIdentifier should be %s, got %s", test.want, got)
+			}
+		})
+	}
+}
+
+func TestGenColumnsStmt(t *testing.T) {
+	tests := []struct {
+		desc     string
+		name     string
+		wantStmt string
+		wantArgs []interface{}
+	}{
+		{desc: "table name", name: "authors", wantStmt: "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1", wantArgs: []interface{}{"authors"}},
+		{desc: "table name with upper case", name: "Authors", wantStmt: "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1", wantArgs: []interface{}{"authors"}},
+		{desc: "table name with schema", name: "public.authors", wantStmt: "SELECT column_name FROM information_schema.columns WHERE table_schema = $1 AND table_name = $2", wantArgs: []interface{}{"public", "authors"}},
+	}
+
+	d := &postgresDialect{}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			stmt, args := d.GenColumnsStmt(test.name)
+			if stmt != test.wantStmt {
+				t.Fatalf("statement should be %s, got %s", test.wantStmt, stmt)
+			}
+
+			if err := testutils.CompareVal(args, test.wantArgs); err != nil {
+				t.Fatalf("arguments are not expected: %s", err)
+			}
+		})
+	}
+}
+
+type decimalStruct struct {
+	Rat         big.Rat
+	RatP        *big.Rat
+	RatScaled   big.Rat `gofacto:"decimal:10,2"`
+	Price       float64 `gofacto:"decimal:10,2"`
+	PlainFloat  float64
+	PriceString string `gofacto:"decimal:10,2"`
+}
+
+func TestConvertDecimal(t *testing.T) {
+	typ := reflect.TypeOf(decimalStruct{})
+	field := func(name string) reflect.StructField {
+		f, _ := typ.FieldByName(name)
+		return f
+	}
+
+	tests := []struct {
+		desc   string
+		field  string
+		value  interface{}
+		want   interface{}
+		wantOK bool
+	}{
+		{desc: "big.Rat", field: "Rat", value: *big.NewRat(1234, 100), want: "12.34", wantOK: true},
+		{desc: "pointer to big.Rat", field: "RatP", value: big.NewRat(1, 8), want: "0.125", wantOK: true},
+		{desc: "nil pointer to big.Rat", field: "RatP", value: (*big.Rat)(nil), want: nil, wantOK: false},
+		{desc: "non-terminating big.Rat", field: "Rat", value: *big.NewRat(1, 3), want: "0." + strings.Repeat("3", 30), wantOK: true},
+		{desc: "big.Rat with decimal tag", field: "RatScaled", value: *big.NewRat(1, 8), want: "0.13", wantOK: true},
+		{desc: "float with decimal tag", field: "Price", value: 12.34, want: "12.34", wantOK: true},
+		{desc: "float without decimal tag", field: "PlainFloat", value: 12.34, want: nil, wantOK: false},
+		{desc: "string with decimal tag", field: "PriceString", value: "12.34", want: nil, wantOK: false},
+	}
+
+	d := &postgresDialect{}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, ok := d.ConvertValue(field(test.field), test.value)
+			if ok != test.wantOK {
+				t.Fatalf("ok should be %v, got %v", test.wantOK, ok)
+			}
+
+			if got != test.want {
+				t.Fatalf("value should be %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+// attrs is the map implementing driver.Valuer, which is passed to the driver as it is
+type attrs map[string]string
+
+func (a attrs) Value() (driver.Value, error) {
+	return "attrs", nil
+}
+
+// code is the element implementing driver.Valuer
+type code struct {
+	prefix string
+	n      int
+}
+
+func (c code) Value() (driver.Value, error) {
+	return fmt.Sprintf("%s-%d", c.prefix, c.n), nil
+}
+
+func TestConvertValue(t *testing.T) {
+	one := 1
+	date := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	type item struct {
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		desc   string
+		value  interface{}
+		want   interface{}
+		wantOK bool
+	}{
+		{desc: "map", value: map[string]int{"a": 1}, want: `{"a":1}`, wantOK: true},
+		{desc: "map implementing driver.Valuer", value: attrs{"a": "b"}, want: nil, wantOK: false},
+		{desc: "bytes", value: []byte("abc"), want: nil, wantOK: false},
+		{desc: "nil slice", value: []int(nil), want: nil, wantOK: true},
+		{desc: "int slice", value: []int{1, 2, 3}, want: "{1,2,3}", wantOK: true},
+		{desc: "string slice", value: []string{`a"b`, `c\d`}, want: `{"a\"b","c\\d"}`, wantOK: true},
+		{desc: "pointer slice", value: []*int{&one, nil}, want: "{1,NULL}", wantOK: true},
+		{desc: "time slice", value: []time.Time{date}, want: `{"2024-01-02T03:04:05Z"}`, wantOK: true},
+		{desc: "driver.Valuer slice", value: []code{{prefix: "a", n: 1}}, want: `{"a-1"}`, wantOK: true},
+		{desc: "struct slice", value: []item{{Name: "a"}}, want: `[{"name":"a"}]`, wantOK: true},
+		{desc: "int", value: 1, want: nil, wantOK: false},
+	}
+
+	d := &postgresDialect{}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, ok := d.ConvertValue(reflect.StructField{}, test.value)
+			if ok != test.wantOK {
+				t.Fatalf("ok should be %v, got %v", test.wantOK, ok)
+			}
+
+			if got != test.want {
+				t.Fatalf("value should be %v, got %v", test.want, got)
+			}
+		})
+	}
+}
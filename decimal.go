@@ -0,0 +1,161 @@
+package gofacto
+
+import (
+	"encoding"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	tagKeyDecimal = "decimal"
+
+	// defaultDecimalPrecision and defaultDecimalScale are the precision and the scale of big.Rat without the decimal tag
+	defaultDecimalPrecision = 10
+	defaultDecimalScale     = 2
+
+	// maxDecimalPrecision is the maximum precision of the decimal tag, which is the maximum of NUMERIC in MySQL
+	maxDecimalPrecision = 65
+)
+
+var (
+	ratType             = reflect.TypeOf(big.Rat{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// decimalOption is the option of how to generate the decimal field, e.g. `gofacto:"decimal:10,2"`
+type decimalOption struct {
+	// precision is the total number of digits, and scale is the number of digits after the decimal point
+	precision int
+	scale     int
+}
+
+// parseDecimalOption parses the decimal option of the tag, e.g. ["decimal:10", "2"].
+// The field must be big.Rat, the type whose pointer implements encoding.TextUnmarshaler, e.g. decimal.Decimal of shopspring,
+// the integer of the minor unit, e.g. cents, the float, the string, or the pointer to them
+func parseDecimalOption(field reflect.StructField, subParts []string) (*decimalOption, error) {
+	if len(subParts) != 2 {
+		return nil, fmt.Errorf("%s: %w: decimal must be decimal:precision,scale", field.Name, errTagFormat)
+	}
+
+	precision, err := strconv.Atoi(strings.TrimPrefix(subParts[0], tagKeyDecimal+":"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid precision %s", field.Name, errTagFormat, subParts[0])
+	}
+
+	scale, err := strconv.Atoi(subParts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid scale %s", field.Name, errTagFormat, subParts[1])
+	}
+
+	if precision <= 0 || precision > maxDecimalPrecision || scale < 0 || scale > precision {
+		return nil, fmt.Errorf("%s: %w: invalid precision %d and scale %d", field.Name, errTagFormat, precision, scale)
+	}
+
+	typ := indirectType(field.Type)
+	if !isDecimalType(typ) {
+		return nil, fmt.Errorf("%s: %w: %s can't be decimal", field.Name, errTagFormat, typ)
+	}
+
+	// the integer holds the unscaled value, e.g. 1234 for 12.34, which must fit the type
+	if k := typ.Kind(); (isIntType(k) || isUintType(k)) && (precision > 18 || !fitsInt(typ, pow10(precision)-1)) {
+		return nil, fmt.Errorf("%s: %w: precision %d overflows %s", field.Name, errTagFormat, precision, typ)
+	}
+
+	return &decimalOption{precision: precision, scale: scale}, nil
+}
+
+// isDecimalType checks if the decimal value can be set to the type
+func isDecimalType(t reflect.Type) bool {
+	if t == timeType {
+		return false
+	}
+
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+
+	k := t.Kind()
+	return isIntType(k) || isUintType(k) || k == reflect.Float32 || k == reflect.Float64 || k == reflect.String
+}
+
+// genDecimal generates the decimal string of the index within the precision and the scale,
+// e.g. 12 -> "12.12" for decimal:10,2.
+// The digits are exact, so there's no precision lost by the float
+func (o *decimalOption) genDecimal(index int) string {
+	if o == nil {
+		o = &decimalOption{precision: defaultDecimalPrecision, scale: defaultDecimalScale}
+	}
+
+	intPart := modPow10(index, o.precision-o.scale)
+	if o.scale == 0 {
+		return strconv.Itoa(max(intPart, 1))
+	}
+
+	frac := modPow10(index, o.scale)
+	if intPart == 0 && frac == 0 {
+		frac = 1
+	}
+
+	return fmt.Sprintf("%d.%0*d", intPart, o.scale, frac)
+}
+
+// setDecimalValue sets the decimal string to the field, see parseDecimalOption for the supported types
+func setDecimalValue(v reflect.Value, s string) {
+	if v.Kind() == reflect.Pointer {
+		ptr := reflect.New(v.Type().Elem())
+		setDecimalValue(ptr.Elem(), s)
+		v.Set(ptr)
+		return
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		_ = u.UnmarshalText([]byte(s))
+		return
+	}
+
+	switch k := v.Kind(); {
+	case k == reflect.String:
+		v.SetString(s)
+	case k == reflect.Float32 || k == reflect.Float64:
+		f, _ := strconv.ParseFloat(s, 64)
+		v.SetFloat(f)
+	case isIntType(k):
+		n, _ := strconv.ParseInt(strings.Replace(s, ".", "", 1), 10, 64)
+		v.SetInt(n)
+	case isUintType(k):
+		n, _ := strconv.ParseUint(strings.Replace(s, ".", "", 1), 10, 64)
+		v.SetUint(n)
+	}
+}
+
+// fitsInt checks if n fits the integer type
+func fitsInt(t reflect.Type, n int64) bool {
+	v := reflect.New(t).Elem()
+	if isIntType(t.Kind()) {
+		return !v.OverflowInt(n)
+	}
+
+	return !v.OverflowUint(uint64(n))
+}
+
+// modPow10 returns i modulo 10^n, i is returned as it is if 10^n overflows
+func modPow10(i, n int) int {
+	if n > 18 {
+		return i
+	}
+
+	return i % int(pow10(n))
+}
+
+// pow10 returns 10^n, n must be at most 18
+func pow10(n int) int64 {
+	p := int64(1)
+	for ; n > 0; n-- {
+		p *= 10
+	}
+
+	return p
+}
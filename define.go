@@ -0,0 +1,194 @@
+package gofacto
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Generator is a client-defined function to generate the value of a field by the index
+type Generator func(i int) interface{}
+
+// Choice returns the generator cycling through the values by the index,
+// e.g. Choice("new", "paid") generates "new", "paid", "new", ...
+func Choice(vals ...interface{}) Generator {
+	return func(i int) interface{} {
+		if len(vals) == 0 {
+			return nil
+		}
+
+		return vals[(i-1)%len(vals)]
+	}
+}
+
+// parentInserter is the factory inserting the parent value referenced by BelongsTo
+type parentInserter interface {
+	insertParent(ctx context.Context) (interface{}, string, error)
+}
+
+// definedField is the field generated by the generator
+type definedField struct {
+	name string
+	gen  Generator
+}
+
+// belongsTo is the parent association declared by BelongsTo
+type belongsTo struct {
+	name    string
+	fkField string
+	parent  parentInserter
+}
+
+// Definition is the builder defining a factory without the struct tags,
+// e.g. the models are generated code which can't be annotated
+type Definition[T any] struct {
+	fields    []definedField
+	belongsTo []belongsTo
+}
+
+// Define initializes a new factory definition.
+//
+// Example:
+//
+//	factory := gofacto.Define[Order]().
+//		Field("Status", gofacto.Choice("new", "paid")).
+//		BelongsTo("User", userFactory).
+//		Build().
+//		WithDB(db)
+func Define[T any]() *Definition[T] {
+	return &Definition[T]{}
+}
+
+// Field sets the generator of the field
+func (d *Definition[T]) Field(name string, gen Generator) *Definition[T] {
+	d.fields = append(d.fields, definedField{name: name, gen: gen})
+	return d
+}
+
+// BelongsTo declares the value belongs to the parent value inserted by the parent factory.
+// The foreign key field is the name followed by "ID", e.g. "UserID" for "User",
+// and the field of the name is set to the parent value if it exists.
+//
+// The parent value is inserted when inserting each value, unless the foreign key field is already set
+func (d *Definition[T]) BelongsTo(name string, parent parentInserter) *Definition[T] {
+	d.belongsTo = append(d.belongsTo, belongsTo{name: name, fkField: name + "ID", parent: parent})
+	return d
+}
+
+// Build creates the factory from the definition.
+// The fields are generated by the blueprint, so calling WithBlueprint on the factory replaces them
+func (d *Definition[T]) Build() *Factory[T] {
+	var v T
+	f := New(v)
+	if f.configErr != nil {
+		return f
+	}
+
+	for _, field := range d.fields {
+		if err := checkFieldsExist(f.dataType, []string{field.name}); err != nil {
+			f.configErr = err
+			return f
+		}
+	}
+
+	for _, bt := range d.belongsTo {
+		if err := checkFieldsExist(f.dataType, []string{bt.fkField}); err != nil {
+			f.configErr = err
+			return f
+		}
+
+		// the foreign key and the parent value are set when inserting
+		f.ignoreFields = append(f.ignoreFields, bt.fkField)
+		if _, ok := f.dataType.FieldByName(bt.name); ok {
+			f.ignoreFields = append(f.ignoreFields, bt.name)
+		}
+	}
+
+	if len(d.fields) > 0 {
+		f.WithBlueprintE(d.blueprint)
+	}
+
+	if len(d.belongsTo) > 0 {
+		f.Use(d.insertParents)
+	}
+
+	return f
+}
+
+// blueprint creates the value with the generated fields
+func (d *Definition[T]) blueprint(i int) (T, error) {
+	var v T
+	val := reflect.ValueOf(&v).Elem()
+
+	for _, field := range d.fields {
+		gv := field.gen(i)
+		if gv == nil {
+			continue
+		}
+
+		if err := setConvertedField(val, field.name, gv); err != nil {
+			return v, err
+		}
+	}
+
+	return v, nil
+}
+
+// insertParents is the middleware inserting the parent values before inserting the values
+func (d *Definition[T]) insertParents(next BuildStep) BuildStep {
+	return func(ctx context.Context, s Step) error {
+		if s.Kind != StepInsert {
+			return next(ctx, s)
+		}
+
+		for _, v := range s.Values {
+			for _, bt := range d.belongsTo {
+				if !reflect.ValueOf(v).Elem().FieldByName(bt.fkField).IsZero() {
+					continue
+				}
+
+				p, idField, err := bt.parent.insertParent(ctx)
+				if err != nil {
+					return fmt.Errorf("%s: %w", bt.name, err)
+				}
+
+				if err := setForeignKey(v, bt.fkField, p, idField); err != nil {
+					return err
+				}
+
+				if field, ok := reflect.TypeOf(v).Elem().FieldByName(bt.name); ok && indirectType(field.Type) == reflect.TypeOf(p).Elem() {
+					if err := setField(v, bt.name, p); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		return next(ctx, s)
+	}
+}
+
+// insertParent builds and inserts a value as the parent value of BelongsTo,
+// and returns the pointer to the inserted value and its ID field
+func (f *Factory[T]) insertParent(ctx context.Context) (interface{}, string, error) {
+	v, err := f.Build(ctx).Insert()
+	if err != nil {
+		return nil, "", err
+	}
+
+	idField := f.idField
+	if idField == "" {
+		idField = defaultIDFieldNames[0]
+	}
+
+	return &v, idField, nil
+}
+
+// indirectType returns the element type if the type is a pointer
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+
+	return t
+}
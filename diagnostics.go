@@ -0,0 +1,123 @@
+package gofacto
+
+import (
+	"reflect"
+	"slices"
+)
+
+// SkipReason is the reason why a field is left zero when building
+type SkipReason string
+
+const (
+	// SkipReasonIDField means the field is the ID field, which is populated by the database when inserting
+	SkipReasonIDField SkipReason = "ID field"
+
+	// SkipReasonOmitTag means the field is tagged with `gofacto:"omit"`
+	SkipReasonOmitTag SkipReason = "omit tag"
+
+	// SkipReasonIgnored means the field is ignored by the factory, e.g. the foreign key set by BelongsTo when inserting
+	SkipReasonIgnored SkipReason = "ignored"
+
+	// SkipReasonUnexported means the field is unexported, which can't be set by reflection
+	SkipReasonUnexported SkipReason = "unexported"
+
+	// SkipReasonInterface means the field is an interface, which has no concrete type to generate
+	SkipReasonInterface SkipReason = "interface"
+
+	// SkipReasonCustomType means the field is a client-defined type, which is only generated by the database custom types
+	SkipReasonCustomType SkipReason = "custom type"
+
+	// SkipReasonUnsupported means the type of the field is not supported, e.g. map, array, channel, and function
+	SkipReasonUnsupported SkipReason = "unsupported type"
+
+	// SkipReasonZeroValueDisabled means the non-zero values are disabled by WithIsSetZeroValue(false)
+	SkipReasonZeroValueDisabled SkipReason = "zero values disabled"
+
+	// SkipReasonSetZero means the field was generated, but set back to zero, e.g. by SetZero, traits, or the blueprint
+	SkipReasonSetZero SkipReason = "set to zero"
+)
+
+// Diagnostic is the field left zero after building and the reason why
+type Diagnostic struct {
+	// Field is the path of the field, e.g. "Address.Geo"
+	Field string
+
+	// Reason is the reason why the field is left zero
+	Reason SkipReason
+}
+
+// Diagnostics returns the fields left zero in the value and the reasons why, in the order of the fields.
+// The nested structs are inspected field by field.
+// It helps to understand why a column ended up NULL without stepping through the reflection code
+func (b *builder[T]) Diagnostics() []Diagnostic {
+	if b.err != nil {
+		return nil
+	}
+
+	return b.f.diagnose(reflect.ValueOf(b.v).Elem(), "")
+}
+
+// Diagnostics returns the fields left zero in each value of the list and the reasons why, see builder.Diagnostics
+func (b *builderList[T]) Diagnostics() [][]Diagnostic {
+	if b.err != nil {
+		return nil
+	}
+
+	output := make([][]Diagnostic, len(b.list))
+	for i, v := range b.list {
+		output[i] = b.f.diagnose(reflect.ValueOf(v).Elem(), "")
+	}
+
+	return output
+}
+
+// diagnose returns the fields left zero in the struct value and the reasons why
+func (f *Factory[T]) diagnose(val reflect.Value, prefix string) []Diagnostic {
+	typ := val.Type()
+	idField := f.idFieldName(typ)
+
+	var diags []Diagnostic
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		path := prefix + field.Name
+		curVal := val.Field(i)
+		isExported := field.PkgPath == ""
+		kind := fieldKindOf(field)
+
+		switch {
+		case isExported && kind == fieldKindStruct:
+			diags = append(diags, f.diagnose(curVal, path+".")...)
+		case isExported && kind == fieldKindPtrStruct && !curVal.IsNil():
+			diags = append(diags, f.diagnose(curVal.Elem(), path+".")...)
+		case curVal.IsZero():
+			diags = append(diags, Diagnostic{Field: path, Reason: f.skipReason(field, kind, idField)})
+		}
+	}
+
+	return diags
+}
+
+// skipReason returns the reason why the field(field) is left zero
+func (f *Factory[T]) skipReason(field reflect.StructField, kind fieldKind, idField string) SkipReason {
+	switch {
+	case field.PkgPath != "":
+		return SkipReasonUnexported
+	case field.Name == idField:
+		return SkipReasonIDField
+	case slices.Contains(f.ignoreFields, field.Name):
+		if t, ok, err := parseTag(field); err == nil && ok && t.omit {
+			return SkipReasonOmitTag
+		}
+		return SkipReasonIgnored
+	case !f.isSetZeroValue:
+		return SkipReasonZeroValueDisabled
+	case indirectType(field.Type).Kind() == reflect.Interface:
+		return SkipReasonInterface
+	case kind == fieldKindCustom:
+		return SkipReasonCustomType
+	case kind == fieldKindBasic && !isBasicKind(indirectType(field.Type).Kind()):
+		return SkipReasonUnsupported
+	default:
+		return SkipReasonSetZero
+	}
+}
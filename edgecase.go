@@ -0,0 +1,157 @@
+package gofacto
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// EdgeCase is the kind of the boundary values generated by BuildEdgeCase
+type EdgeCase int
+
+const (
+	// EdgeCaseEmpty generates the empty strings, zero numbers, false, and the pointers to them
+	EdgeCaseEmpty EdgeCase = iota + 1
+
+	// EdgeCaseMax generates the maximum numbers, the long strings, and the far-future times
+	EdgeCaseMax
+
+	// EdgeCaseMin generates the minimum numbers, the single-character strings, and the far-past times
+	EdgeCaseMin
+
+	// EdgeCaseUnicode generates the strings of the Unicode edge cases, e.g. emoji, combining marks, zero-width and right-to-left characters.
+	// The fields of other types are generated as usual
+	EdgeCaseUnicode
+)
+
+const (
+	// edgeCaseLongStringLen is the length of the long strings generated by EdgeCaseMax
+	edgeCaseLongStringLen = 1024
+
+	// edgeCaseUnicodeString is the string generated by EdgeCaseUnicode,
+	// it contains the combining mark, zero-width space, right-to-left override, emoji ZWJ sequence, CJK, and astral-plane emoji
+	edgeCaseUnicodeString = "Zoe\u0308 a\u200bb \u202eabc \U0001F469\u200d\U0001F469\u200d\U0001F467 日本語 \U0001F680"
+)
+
+var (
+	// edgeCaseMaxTime is the far-future time generated by EdgeCaseMax
+	edgeCaseMaxTime = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+	// edgeCaseMinTime is the far-past time generated by EdgeCaseMin, it's the minimum of MySQL DATETIME
+	edgeCaseMinTime = time.Date(1000, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// BuildEdgeCase builds a value with the boundary values of the given kind, for the robustness testing.
+//
+// The boundary values only fill the fields left zero by the blueprint,
+// and the ID field, the foreign key fields, and the ignored fields are kept as Build does,
+// so the required constraints are still satisfiable.
+// The constraints set by WithConstraint are checked as well.
+//
+// Example:
+//
+//	order, err := factory.BuildEdgeCase(ctx, gofacto.EdgeCaseMax).Insert()
+func (f *Factory[T]) BuildEdgeCase(ctx context.Context, kind EdgeCase) *builder[T] {
+	f.edgeCase = kind
+	defer func() { f.edgeCase = 0 }()
+
+	return f.Build(ctx)
+}
+
+// setEdgeValue sets the boundary value of the kind to the field of time or builtin type.
+// It returns false if the kind has no boundary value for the type, so the field is generated as usual
+func setEdgeValue(v reflect.Value, kind EdgeCase) bool {
+	if v.Kind() == reflect.Ptr {
+		e := reflect.New(v.Type().Elem())
+		if !setEdgeValue(e.Elem(), kind) {
+			return false
+		}
+
+		v.Set(e)
+		return true
+	}
+
+	if v.Type() == timeType {
+		switch kind {
+		case EdgeCaseEmpty:
+			v.Set(reflect.ValueOf(time.Time{}))
+		case EdgeCaseMax:
+			v.Set(reflect.ValueOf(edgeCaseMaxTime))
+		case EdgeCaseMin:
+			v.Set(reflect.ValueOf(edgeCaseMinTime))
+		default:
+			return false
+		}
+
+		return true
+	}
+
+	switch kind {
+	case EdgeCaseEmpty:
+		return isBasicKind(v.Kind())
+	case EdgeCaseMax:
+		return setMaxValue(v)
+	case EdgeCaseMin:
+		return setMinValue(v)
+	case EdgeCaseUnicode:
+		if v.Kind() != reflect.String {
+			return false
+		}
+
+		v.SetString(edgeCaseUnicodeString)
+		return true
+	default:
+		return false
+	}
+}
+
+// setMaxValue sets the maximum value of the type to the field
+func setMaxValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1<<(v.Type().Bits()-1) - 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(math.MaxUint64 >> (64 - v.Type().Bits()))
+	case reflect.Float32:
+		v.SetFloat(math.MaxFloat32)
+	case reflect.Float64:
+		v.SetFloat(math.MaxFloat64)
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.String:
+		v.SetString(strings.Repeat("x", edgeCaseLongStringLen))
+	default:
+		return false
+	}
+
+	return true
+}
+
+// setMinValue sets the minimum value of the type to the field
+func setMinValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(-1 << (v.Type().Bits() - 1))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(0)
+	case reflect.Float32:
+		v.SetFloat(-math.MaxFloat32)
+	case reflect.Float64:
+		v.SetFloat(-math.MaxFloat64)
+	case reflect.Bool:
+		v.SetBool(false)
+	case reflect.String:
+		v.SetString("x")
+	default:
+		return false
+	}
+
+	return true
+}
+
+// isBasicKind checks if the kind is a number, bool, or string
+func isBasicKind(k reflect.Kind) bool {
+	return isNumberKind(k) || k == reflect.Bool || k == reflect.String
+}
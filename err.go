@@ -55,4 +55,67 @@ var (
 
 	// errCycleDependency is the error representing that there is a cycle dependency
 	errCycleDependency = errors.New("cycle dependency")
+
+	// errFieldFrozen is the error representing that field is frozen
+	errFieldFrozen = errors.New("field is frozen")
+
+	// errValueResemblesPII is the error representing that value resembles real PII
+	errValueResemblesPII = errors.New("value resembles PII")
+
+	// errFieldsNotPopulated is the error representing that fields can't be populated in strict mode
+	errFieldsNotPopulated = errors.New("fields are not populated")
+
+	// errFractionOutOfRange is the error representing that fraction is not between 0 and 1
+	errFractionOutOfRange = errors.New("fraction must be between 0 and 1")
+
+	// errConstraintViolated is the error representing that value violates the constraint
+	errConstraintViolated = errors.New("constraint is violated")
+
+	// errInvalidConstraint is the error representing that constraint is invalid, e.g. field not found
+	errInvalidConstraint = errors.New("invalid constraint")
+
+	// errDBNotFound is the error representing that the named database connection is not found
+	errDBNotFound = errors.New("database connection not found")
+
+	// errTxNotSupported is the error representing that the database doesn't support transactions
+	errTxNotSupported = errors.New("transaction is not supported")
+
+	// errReadOnly is the error representing that inserting with the read-only factory
+	errReadOnly = errors.New("factory is read-only")
+
+	// errDBNotAllowed is the error representing that the database is not in the allow-list
+	errDBNotAllowed = errors.New("database is not allowed")
+
+	// errInvalidVariation is the error representing that variation is invalid, e.g. no choice
+	errInvalidVariation = errors.New("invalid variation")
+
+	// errCryptoRandType is the error representing that the type of the field can't be generated by crypto/rand
+	errCryptoRandType = errors.New("type can't be generated by crypto/rand")
+
+	// errInvalidIndexOffset is the error representing that the index offset is negative
+	errInvalidIndexOffset = errors.New("invalid index offset")
+
+	// errSchemaCheckNotSupported is the error representing that the database doesn't support checking the schema
+	errSchemaCheckNotSupported = errors.New("schema check is not supported")
+
+	// errInvalidChunkSize is the error representing that the chunk size is not positive
+	errInvalidChunkSize = errors.New("invalid chunk size")
+
+	// errChunkForeignKeys is the error representing that chunking the associations referenced by the slice of foreign keys
+	errChunkForeignKeys = errors.New("can't chunk associations referenced by foreign keys")
+
+	// errParentNotReferenced is the error representing that the child doesn't have the foreign key referencing the parent
+	errParentNotReferenced = errors.New("parent is not referenced by foreign key")
+
+	// errFindNotSupported is the error representing that the database doesn't support finding the rows
+	errFindNotSupported = errors.New("finding rows is not supported")
+
+	// errRowNotFound is the error representing that no row matches the expected conditions
+	errRowNotFound = errors.New("row not found")
+
+	// errUpdateNotSupported is the error representing that the database doesn't support updating the data
+	errUpdateNotSupported = errors.New("database does not support update")
+
+	// errDeleteNotSupported is the error representing that the database doesn't support deleting the data
+	errDeleteNotSupported = errors.New("database does not support delete")
 )
@@ -0,0 +1,51 @@
+package example_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	"github.com/eyo-chen/gofacto"
+	"github.com/eyo-chen/gofacto/db/mysqlf"
+	"github.com/eyo-chen/gofacto/httpf"
+)
+
+type account struct {
+	ID    int
+	Name  string
+	Token string
+}
+
+// meHandler returns the account authenticated by the X-Account-ID header
+func meHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.Header.Get("X-Account-ID"))
+	if err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]int{"id": id})
+}
+
+// Example_httpHandler demonstrates how to test an HTTP handler as the inserted entity
+func Example_httpHandler() {
+	f := gofacto.New(account{}).
+		WithDB(mysqlf.NewConfig(nil)) // you should pass db connection
+
+	// insert an account, and create the request authenticated as the account
+	req, acc, err := httpf.NewRequest(f.Build(ctx), http.MethodGet, "/me", nil,
+		httpf.Chain(
+			httpf.BearerToken(func(a account) string { return a.Token }),
+			httpf.Header("X-Account-ID", func(a account) string { return strconv.Itoa(a.ID) }),
+		))
+	if err != nil {
+		panic(err)
+	}
+
+	rec := httptest.NewRecorder()
+	meHandler(rec, req)
+
+	fmt.Println(acc.ID, rec.Code) // {{inserted ID}} 200
+}
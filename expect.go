@@ -0,0 +1,250 @@
+package gofacto
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/eyo-chen/gofacto/internal/db"
+)
+
+const (
+	// maxScannedRows is the maximum number of the rows scanned for the diff when no row matches
+	maxScannedRows = 100
+
+	// maxClosestRows is the maximum number of the closest rows shown in the diff
+	maxClosestRows = 3
+)
+
+// rowFinder is the database which is able to find the rows of the storage
+type rowFinder interface {
+	// Find returns the rows matching all the conditions, the rows are the pointers to the values of the type
+	Find(ctx context.Context, params db.FindParams) ([]interface{}, error)
+}
+
+// RowMatcher is the condition of the row expected by ExpectRow
+type RowMatcher struct {
+	field string
+	value interface{}
+}
+
+// WithField returns the condition that the field of the row equals the value.
+// The value is converted to the type of the field, and nil means the field is NULL
+func WithField(name string, value interface{}) RowMatcher {
+	return RowMatcher{field: name, value: value}
+}
+
+// ExpectRow checks if there is a row in the storage of the factory matching all the matchers, and returns the first one.
+// If no row matches, the test fails with the diff of the closest rows in the storage.
+// It replaces the SELECT, Scan, and compare boilerplate of verifying the database state.
+// It's supported by mysqlf, postgresf, gormf, mongof, and mockf
+//
+// Example:
+//
+//	factory.ExpectRow(ctx, t, gofacto.WithField("Email", "a@example.com"), gofacto.WithField("Age", 20))
+func (f *Factory[T]) ExpectRow(ctx context.Context, t testing.TB, matchers ...RowMatcher) T {
+	t.Helper()
+
+	v, err := f.expectRow(ctx, matchers)
+	if err != nil {
+		t.Fatalf("gofacto: %v", err)
+		return f.empty
+	}
+
+	return v
+}
+
+// expectRow returns the first row matching all the matchers, or the error describing the closest rows
+func (f *Factory[T]) expectRow(ctx context.Context, matchers []RowMatcher) (T, error) {
+	if f.configErr != nil {
+		return f.empty, f.configErr
+	}
+
+	if f.db == nil {
+		return f.empty, errDBIsNotProvided
+	}
+
+	finder, ok := f.db.(rowFinder)
+	if !ok {
+		return f.empty, errFindNotSupported
+	}
+
+	conds := make([]db.Condition, len(matchers))
+	for i, m := range matchers {
+		v, err := f.conditionValue(m.field, m.value)
+		if err != nil {
+			return f.empty, err
+		}
+
+		conds[i] = db.Condition{Field: m.field, Value: v}
+	}
+
+	params := db.FindParams{StorageName: f.storageName, IDField: f.idField, Value: &f.empty, Conditions: conds, Limit: 1}
+	rows, err := finder.Find(ctx, params)
+	if err != nil {
+		return f.empty, fmt.Errorf("find rows in %s: %w", f.storageName, err)
+	}
+
+	if len(rows) > 0 {
+		return *rows[0].(*T), nil
+	}
+
+	params.Conditions, params.Limit = nil, maxScannedRows
+	candidates, err := finder.Find(ctx, params)
+	if err != nil {
+		return f.empty, fmt.Errorf("find rows in %s: %w", f.storageName, err)
+	}
+
+	return f.empty, fmt.Errorf("%w\n%s", errRowNotFound, f.rowDiff(conds, candidates))
+}
+
+// conditionValue converts the value of the matcher to the type of the field(name)
+func (f *Factory[T]) conditionValue(name string, value interface{}) (interface{}, error) {
+	field, ok := f.dataType.FieldByName(name)
+	if !ok || !field.IsExported() {
+		return nil, fmt.Errorf("%w: %s", errFieldNotFound, name)
+	}
+
+	typ := field.Type
+	if value == nil {
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			return reflect.Zero(typ).Interface(), nil
+		default:
+			return nil, fmt.Errorf("%w: nil for %s(%v)", errValueNotTheSameType, name, typ)
+		}
+	}
+
+	val := reflect.ValueOf(value)
+	if val.Type().AssignableTo(typ) {
+		return value, nil
+	}
+
+	// e.g. "bob" for the *string field
+	if typ.Kind() == reflect.Ptr && isConvertible(val.Type(), typ.Elem()) {
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(val.Convert(typ.Elem()))
+		return ptr.Interface(), nil
+	}
+
+	if !isConvertible(val.Type(), typ) {
+		return nil, fmt.Errorf("%w: %v for %s(%v)", errValueNotTheSameType, val.Type(), name, typ)
+	}
+
+	return val.Convert(typ).Interface(), nil
+}
+
+// isConvertible checks if the type(from) can be converted to the type(to) without changing the meaning,
+// e.g. the integer can't be converted to the string
+func isConvertible(from, to reflect.Type) bool {
+	if !from.ConvertibleTo(to) {
+		return false
+	}
+
+	return (from.Kind() == reflect.String) == (to.Kind() == reflect.String)
+}
+
+// rowDiff describes the conditions and the rows closest to them, the rows are the pointers to the values of the type
+func (f *Factory[T]) rowDiff(conds []db.Condition, rows []interface{}) string {
+	wants := make([]string, len(conds))
+	for i, cond := range conds {
+		wants[i] = fmt.Sprintf("%s=%s", cond.Field, formatValue(cond.Value))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "no row in %s matches %s", f.storageName, strings.Join(wants, ", "))
+	if len(rows) == 0 {
+		fmt.Fprintf(&sb, "\n%s is empty", f.storageName)
+		return sb.String()
+	}
+
+	type rowMismatch struct {
+		index int
+		diffs []string
+	}
+
+	mismatches := make([]rowMismatch, len(rows))
+	for i, row := range rows {
+		val := reflect.ValueOf(row).Elem()
+		mismatches[i].index = i
+		for _, cond := range conds {
+			got := val.FieldByName(cond.Field).Interface()
+			if !equalValue(got, cond.Value) {
+				diff := fmt.Sprintf("%s: got %s, want %s", cond.Field, formatValue(got), formatValue(cond.Value))
+				mismatches[i].diffs = append(mismatches[i].diffs, diff)
+			}
+		}
+	}
+
+	sort.SliceStable(mismatches, func(i, j int) bool {
+		return len(mismatches[i].diffs) < len(mismatches[j].diffs)
+	})
+
+	fmt.Fprintf(&sb, "\nclosest rows of %d scanned:", len(rows))
+	for _, m := range mismatches[:min(len(mismatches), maxClosestRows)] {
+		fmt.Fprintf(&sb, "\n  %s:", f.rowLabel(rows[m.index], m.index))
+		for _, diff := range m.diffs {
+			fmt.Fprintf(&sb, "\n    %s", diff)
+		}
+	}
+
+	return sb.String()
+}
+
+// rowLabel returns the label of the row(pointer to the value) in the diff, which is the ID if there is the ID field
+func (f *Factory[T]) rowLabel(row interface{}, index int) string {
+	if f.idField != "" {
+		if id := reflect.ValueOf(row).Elem().FieldByName(f.idField); id.IsValid() {
+			return fmt.Sprintf("%s=%s", f.idField, formatValue(id.Interface()))
+		}
+	}
+
+	return fmt.Sprintf("row %d", index+1)
+}
+
+// equalValue checks if the values are equal, the times are compared by the instant
+func equalValue(a, b interface{}) bool {
+	ta, okA := derefValue(a).(time.Time)
+	tb, okB := derefValue(b).(time.Time)
+	if okA && okB {
+		return ta.Equal(tb)
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// formatValue formats the value in the diff, the pointers are dereferenced, and nil is shown as NULL
+func formatValue(v interface{}) string {
+	switch d := derefValue(v).(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return fmt.Sprintf("%q", d)
+	case time.Time:
+		return d.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", d)
+	}
+}
+
+// derefValue dereferences the pointer value, nil is returned if the pointer is nil
+func derefValue(v interface{}) interface{} {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil
+		}
+
+		val = val.Elem()
+	}
+
+	if !val.IsValid() {
+		return nil
+	}
+
+	return val.Interface()
+}
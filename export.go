@@ -0,0 +1,152 @@
+package gofacto
+
+import (
+	"reflect"
+	"strings"
+)
+
+// defaultTagNamespace is the tag namespace driving the exported field names by default
+const defaultTagNamespace = "json"
+
+// WithTagNamespace sets the tag namespace driving the field names of the exported values, e.g. "json", "bson", or "yaml".
+// The exported values match the shapes serialized by the target namespace:
+// the fields are named by the tags, the fields tagged with "-" are skipped,
+// and the empty fields tagged with omitempty are omitted.
+//
+// It's used by Export and Patch, and it's "json" by default
+func (f *Factory[T]) WithTagNamespace(ns string) *Factory[T] {
+	f.tagNamespace = ns
+	return f
+}
+
+// Export returns the value as a map keyed by the field names of the tag namespace set by WithTagNamespace,
+// e.g. to write the fixtures as JSON or YAML matching the API payload shapes exactly
+func (b *builder[T]) Export() (map[string]interface{}, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return exportValue(reflect.ValueOf(b.v).Elem(), b.f.namespace()), nil
+}
+
+// Export returns the list of values as maps keyed by the field names of the tag namespace set by WithTagNamespace
+func (b *builderList[T]) Export() ([]map[string]interface{}, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	ns := b.f.namespace()
+	output := make([]map[string]interface{}, len(b.list))
+	for i, v := range b.list {
+		output[i] = exportValue(reflect.ValueOf(v).Elem(), ns)
+	}
+
+	return output, nil
+}
+
+// namespace returns the tag namespace driving the exported field names
+func (f *Factory[T]) namespace() string {
+	if f.tagNamespace == "" {
+		return defaultTagNamespace
+	}
+
+	return f.tagNamespace
+}
+
+// exportValue converts the struct value to the map keyed by the field names of the tag namespace(ns)
+func exportValue(val reflect.Value, ns string) map[string]interface{} {
+	m := map[string]interface{}{}
+	exportFields(val, ns, m)
+	return m
+}
+
+// exportFields adds the exported fields of the struct value to the map,
+// the inlined fields are flattened into the map
+func exportFields(val reflect.Value, ns string, m map[string]interface{}) {
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Type().Field(i)
+		name, opts := tagName(field, ns)
+		if name == "-" {
+			continue
+		}
+
+		fv := val.Field(i)
+		if isInlined(field, ns, opts) {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+
+			exportFields(fv, ns, m)
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		if hasTagOpt(opts, "omitempty") && isEmptyValue(fv, ns) {
+			continue
+		}
+
+		m[name] = fv.Interface()
+	}
+}
+
+// tagName returns the name and the options of the tag of the namespace(ns),
+// the name falls back to the field name if the tag has no name
+func tagName(field reflect.StructField, ns string) (string, string) {
+	tag := field.Tag.Get(ns)
+	if tag == "-" {
+		return "-", ""
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+
+	return name, opts
+}
+
+// isInlined checks if the fields of the struct field are flattened into the parent.
+// The json namespace flattens the embedded structs without the tag name,
+// and the other namespaces flatten the struct fields with the inline option
+func isInlined(field reflect.StructField, ns, opts string) bool {
+	if indirectType(field.Type).Kind() != reflect.Struct {
+		return false
+	}
+
+	if ns == defaultTagNamespace {
+		name, _, _ := strings.Cut(field.Tag.Get(ns), ",")
+		return field.Anonymous && name == ""
+	}
+
+	return hasTagOpt(opts, "inline")
+}
+
+// hasTagOpt checks if the comma-separated options contain the option
+func hasTagOpt(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isEmptyValue checks if the value is omitted by omitempty of the namespace(ns).
+// The json namespace never omits the structs, while the other namespaces omit the zero structs
+func isEmptyValue(v reflect.Value, ns string) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Struct:
+		return ns != defaultTagNamespace && v.IsZero()
+	default:
+		return v.IsZero()
+	}
+}
@@ -0,0 +1,97 @@
+package gofacto
+
+import (
+	"context"
+
+	"github.com/eyo-chen/gofacto/internal/db"
+)
+
+// Fixture is the handle of an inserted value.
+// It's used to evolve the state of the inserted value through the same database.
+type Fixture[T any] struct {
+	v *T
+	f *Factory[T]
+
+	// dbName is the name of the database connection inserted into, empty means the default one
+	dbName string
+}
+
+// InsertFixture inserts the value into the database, and returns the handle of the inserted value
+func (b *builder[T]) InsertFixture() (*Fixture[T], error) {
+	if _, err := b.Insert(); err != nil {
+		return nil, err
+	}
+
+	return &Fixture[T]{v: b.v, f: b.f, dbName: b.dbName}, nil
+}
+
+// InsertFixtures inserts the list of values into the database, and returns the handles of the inserted values
+func (b *builderList[T]) InsertFixtures() ([]*Fixture[T], error) {
+	if _, err := b.Insert(); err != nil {
+		return nil, err
+	}
+
+	fxs := make([]*Fixture[T], len(b.list))
+	for i, v := range b.list {
+		fxs[i] = &Fixture[T]{v: v, f: b.f, dbName: b.dbName}
+	}
+
+	return fxs, nil
+}
+
+// Get returns the current value of the fixture
+func (fx *Fixture[T]) Get() T {
+	return *fx.v
+}
+
+// Update applies the modify function to the value, and updates it in the database.
+// The insert transforms apply as Insert does, so the stored value is transformed while the fixture keeps the plain value,
+// and the fields omitted by Insert, e.g. the ones not selected by WithColumns, are not updated.
+// An error is returned without applying the modify function if the database doesn't implement Update.
+//
+// Example:
+//
+//	fx, err := orderFactory.Build(ctx).InsertFixture()
+//	err = fx.Update(ctx, func(o *Order) { o.Status = "shipped" })
+func (fx *Fixture[T]) Update(ctx context.Context, modify func(*T)) error {
+	upd, ok := fx.f.dbByName(fx.dbName).(updater)
+	if !ok {
+		return errUpdateNotSupported
+	}
+
+	modify(fx.v)
+
+	stored, err := fx.f.transformValues([]interface{}{fx.v})
+	if err != nil {
+		return err
+	}
+
+	return upd.Update(ctx, db.UpdateParams{
+		StorageName: fx.f.storageName,
+		IDField:     fx.f.idField,
+		Value:       stored[0],
+		OmitFields:  fx.f.factoryOmitFields(stored),
+	})
+}
+
+// Delete deletes the value from the database.
+// If the factory is configured with WithIsSoftDelete(true), the value is marked as deleted instead of being removed
+func (fx *Fixture[T]) Delete(ctx context.Context) error {
+	del, ok := fx.f.dbByName(fx.dbName).(deleter)
+	if !ok {
+		return errDeleteNotSupported
+	}
+
+	if err := del.DeleteList(ctx, db.DeleteListParams{
+		StorageName:  fx.f.storageName,
+		IDField:      fx.f.idField,
+		Values:       []interface{}{fx.v},
+		IsSoftDelete: fx.f.isSoftDelete,
+	}); err != nil {
+		return err
+	}
+
+	// the value is already deleted, so Cleanup doesn't need to delete it again
+	fx.f.forgetInserted(fx.v)
+	return nil
+}
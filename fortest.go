@@ -0,0 +1,81 @@
+package gofacto
+
+import (
+	"context"
+	"hash/fnv"
+	"maps"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// ForTest returns a child factory for the test, which shares the configurations of the factory,
+// e.g. the blueprint, the traits, and the database, but has its own index, associations, and inserted records.
+// The data inserted by the child is cleaned up when the test and all its subtests complete.
+//
+// It's safe to call ForTest and use the children concurrently, e.g. in the tests calling t.Parallel(),
+// as long as the factory itself isn't configured or used at the same time.
+// The index of every child starts over, so use WithSequence or WithIndexOffset on the factory
+// if the values must be unique across the children, e.g. inserted into one shared database.
+// If the factory is configured with WithRand, the random source of the child is seeded by the test name,
+// so the values of the child are reproducible regardless of the order the tests run.
+//
+// Example:
+//
+//	var userFactory = gofacto.New(User{}).WithDB(mysqlf.NewConfig(db))
+//
+//	func TestUser(t *testing.T) {
+//		t.Parallel()
+//		f := userFactory.ForTest(t)
+//
+//		user, err := f.Build(ctx).Insert()
+//		...
+//	}
+func (f *Factory[T]) ForTest(t testing.TB) *Factory[T] {
+	t.Helper()
+
+	child := *f
+	child.index, child.seqID = f.firstIndex(), f.firstIndex()
+	child.seqStart, child.seqEnd = 0, 0
+	child.associations = [][]interface{}{}
+	child.sourceFields = nil
+	child.inserted = nil
+
+	// the maps and slices are copied, so configuring the child doesn't race with the other children
+	child.traits = maps.Clone(f.traits)
+	child.traitsE = maps.Clone(f.traitsE)
+	child.dbs = maps.Clone(f.dbs)
+	child.nonCustomTypes = maps.Clone(f.nonCustomTypes)
+	child.identifiedDBs = maps.Clone(f.identifiedDBs)
+	child.ignoreFields = slices.Clip(f.ignoreFields)
+	child.providers = slices.Clip(f.providers)
+	child.cryptoFields = slices.Clip(f.cryptoFields)
+	child.insertTransforms = slices.Clip(f.insertTransforms)
+	child.columns = slices.Clip(f.columns)
+	child.middlewares = slices.Clip(f.middlewares)
+	child.nodeInsertedFuncs = slices.Clip(f.nodeInsertedFuncs)
+	child.constraints = slices.Clip(f.constraints)
+
+	if f.rand != nil {
+		child.rand = rand.New(rand.NewSource(testSeed(t.Name())))
+	}
+
+	t.Cleanup(func() {
+		if len(child.inserted) == 0 {
+			return
+		}
+
+		if err := child.Cleanup(context.Background()); err != nil {
+			t.Errorf("gofacto: clean up %s: %v", t.Name(), err)
+		}
+	})
+
+	return &child
+}
+
+// testSeed returns the seed of the random source derived from the test name
+func testSeed(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
@@ -2,44 +2,166 @@ package gofacto
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
 	"reflect"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/eyo-chen/gofacto/internal/db"
-	"github.com/eyo-chen/gofacto/internal/utils"
 )
 
 // Factory is the gofacto factory to create mock data
 type Factory[T any] struct {
 	db             database
 	blueprint      blueprintFunc[T]
+	blueprintE     blueprintEFunc[T]
 	storageName    string
+	idField        string
 	dataType       reflect.Type
 	empty          T
 	index          int
 	ignoreFields   []string
 	isSetZeroValue bool
-	err            error
+	isSoftDelete   bool
+	isSetSeqID     bool
+	isLocalIndex   bool
+	isStrict       bool
+	seqID          int
+	stringFormat   string
+	edgeCase       EdgeCase
+
+	// configErr is the error of the configuration, which is kept across Reset
+	configErr error
+
+	// inserted is a list of inserted records in insertion order
+	inserted []insertedRecord
+
+	// dbs is the map from name to the named database connection, used by UseDB
+	dbs map[string]database
+
+	// dbName is the name of the database connection in use, empty means the default one
+	dbName string
+
+	// readOnly is whether inserting is forbidden
+	readOnly bool
+
+	// allowedDBs is the allow-list of the database names which the values can be inserted into,
+	// empty means all the databases are allowed
+	allowedDBs []string
+
+	// identifiedDBs caches the database name of each database connection checked against allowedDBs
+	identifiedDBs map[database]string
+
+	// providers is the list of providers of the default values of the fields
+	providers []ValueProvider
+
+	// rand is the random source of the generated values, nil means the global source of math/rand
+	rand *rand.Rand
+
+	// cryptoFields is the list of fields generated by crypto/rand
+	cryptoFields []string
+
+	// insertTransforms is the list of transform functions of the fields applied when inserting
+	insertTransforms []insertTransform
+
+	// columns is the list of the fields inserted into the database, empty means all the fields
+	columns []string
+
+	// noGeneratedID is whether the database doesn't generate the ID when inserting
+	noGeneratedID bool
+
+	// timestampMode is how the timestamp fields, CreatedAt and UpdatedAt, are set
+	timestampMode TimestampMode
+
+	// clock returns the current time, nil means time.Now
+	clock func() time.Time
+
+	// errorPreview is how the values of the failed row are previewed in InsertError
+	errorPreview ErrorPreview
+
+	// tagNamespace is the tag namespace driving the exported field names, empty means json
+	tagNamespace string
+
+	// anonymizeProfile is the profile to check PII, nil means disabled
+	anonymizeProfile *AnonymizeProfile
+
+	// nonCustomTypes caches the types which aren't the db custom types,
+	// it's cleared when the db connection is changed, and dropped while the db connection is switched or bound
+	nonCustomTypes map[reflect.Type]struct{}
+
+	// seq is the sequence which the indexes are reserved from, nil means the index is local to the factory
+	seq Sequence
+
+	// indexOffset is the offset of the index and the sequential ID, which start at indexOffset+1
+	indexOffset int
+
+	// seqStart and seqEnd are the range of the indexes reserved from the sequence
+	seqStart, seqEnd int
+
+	// middlewares is a list of middlewares wrapping the build-and-insert pipeline
+	middlewares []Middleware
+
+	// nodeInsertedFuncs is a list of functions called after each node is inserted
+	nodeInsertedFuncs []NodeInsertedFunc
+
+	// constraints is a list of constraint functions checked after generating each value
+	constraints []constraintFunc[T]
 
 	// map from name to trait function
 	traits map[string]setTraiter[T]
 
+	// map from name to trait function which might fail
+	traitsE map[string]setTraiterE[T]
+
 	// associations is a list of associations
 	associations [][]interface{}
+
+	// sourceFields is a map from the association value to the field referenced by the foreign key,
+	// it's set by WithOneFrom and takes precedence over the refField of the tag
+	sourceFields map[interface{}]string
+}
+
+// Meta is the metadata of a built value
+type Meta struct {
+	// Index is the sequence index used to generate the value,
+	// it's the index passed to the blueprint and used for the non-zero values
+	Index int
 }
 
 // blueprintFunc is a client-defined function to create a new value
 type blueprintFunc[T any] func(i int) T
 
+// blueprintEFunc is a client-defined function to create a new value, which might fail
+type blueprintEFunc[T any] func(i int) (T, error)
+
 // setTraiter is a client-defined function to add a trait to mutate the value
 type setTraiter[T any] func(v *T)
 
+// setTraiterE is a client-defined function to add a trait to mutate the value, which might fail
+type setTraiterE[T any] func(v *T) error
+
 // builder is for building a single value
 type builder[T any] struct {
 	ctx context.Context
 	v   *T
 	err error
 	f   *Factory[T]
+
+	// meta is the metadata of the value
+	meta Meta
+
+	// dbName is the name of the database connection to insert into, empty means the default one
+	dbName string
+
+	// frozen is the list of fields which can't be mutated
+	frozen []string
+
+	// skipInsert is whether the values are not inserted, while the associations are still inserted
+	skipInsert bool
 }
 
 // builderList is for building a list of values
@@ -48,6 +170,18 @@ type builderList[T any] struct {
 	list []*T
 	err  error
 	f    *Factory[T]
+
+	// metas is the metadata of each value in the list
+	metas []Meta
+
+	// dbName is the name of the database connection to insert into, empty means the default one
+	dbName string
+
+	// frozen is the list of fields which can't be mutated
+	frozen []string
+
+	// skipInsert is whether the values are not inserted, while the associations are still inserted
+	skipInsert bool
 }
 
 // New initializes a new factory
@@ -56,14 +190,14 @@ func New[T any](v T) *Factory[T] {
 
 	if dataType.Kind() != reflect.Struct {
 		return &Factory[T]{
-			err: fmt.Errorf("%w: %v", errInvalidType, dataType.Kind()),
+			configErr: fmt.Errorf("%w: %v", errInvalidType, dataType.Kind()),
 		}
 	}
 
 	ifd, err := extractTag(dataType)
 	if err != nil {
 		return &Factory[T]{
-			err: err,
+			configErr: err,
 		}
 	}
 
@@ -71,9 +205,11 @@ func New[T any](v T) *Factory[T] {
 		dataType:       dataType,
 		empty:          reflect.New(dataType).Elem().Interface().(T),
 		associations:   [][]interface{}{},
-		storageName:    fmt.Sprintf("%ss", utils.CamelToSnake(dataType.Name())),
+		storageName:    tableNameOfType(dataType),
+		idField:        getTypePlan(dataType).idField,
 		ignoreFields:   ifd,
 		index:          1,
+		seqID:          1,
 		isSetZeroValue: true,
 		traits:         map[string]setTraiter[T]{},
 	}
@@ -82,6 +218,17 @@ func New[T any](v T) *Factory[T] {
 // WithBlueprint sets the blueprint function
 func (f *Factory[T]) WithBlueprint(bp blueprintFunc[T]) *Factory[T] {
 	f.blueprint = bp
+	f.blueprintE = nil
+	return f
+}
+
+// WithBlueprintE sets the blueprint function which might fail.
+//
+// The error is returned by the builder, e.g. when calling Get or Insert.
+// It replaces the blueprint function set by WithBlueprint
+func (f *Factory[T]) WithBlueprintE(bp blueprintEFunc[T]) *Factory[T] {
+	f.blueprintE = bp
+	f.blueprint = nil
 	return f
 }
 
@@ -93,9 +240,31 @@ func (f *Factory[T]) WithStorageName(name string) *Factory[T] {
 	return f
 }
 
+// WithIDField sets the name of the ID(primary key) field.
+//
+// By default, the first field named as ID, Id, UUID, Uuid, GUID, or Guid is used.
+// The ID field is not filled when building, and it's populated by the database when inserting
+func (f *Factory[T]) WithIDField(name string) *Factory[T] {
+	f.idField = name
+	return f
+}
+
 // WithDB sets the database connection
 func (f *Factory[T]) WithDB(db database) *Factory[T] {
 	f.db = db
+	f.nonCustomTypes = nil
+	return f
+}
+
+// WithNamedDB adds the named database connection, e.g. the read replica or the sharded target.
+// The values are inserted into the default database connection set by WithDB,
+// unless the builder selects a named one by UseDB
+func (f *Factory[T]) WithNamedDB(name string, db database) *Factory[T] {
+	if f.dbs == nil {
+		f.dbs = map[string]database{}
+	}
+
+	f.dbs[name] = db
 	return f
 }
 
@@ -105,37 +274,120 @@ func (f *Factory[T]) WithIsSetZeroValue(isSetZeroValue bool) *Factory[T] {
 	return f
 }
 
+// WithIsSoftDelete sets whether to mark the inserted data as deleted instead of removing it when cleaning up
+func (f *Factory[T]) WithIsSoftDelete(isSoftDelete bool) *Factory[T] {
+	f.isSoftDelete = isSoftDelete
+	return f
+}
+
+// WithIsSetSeqID sets whether to set deterministic sequential IDs(1, 2, 3...) to the ID field
+// when building the values without db connection.
+// It's useful for snapshot or golden-file tests which require stable output
+func (f *Factory[T]) WithIsSetSeqID(isSetSeqID bool) *Factory[T] {
+	f.isSetSeqID = isSetSeqID
+	return f
+}
+
+// WithIsLocalIndex sets whether each Build and BuildList uses a local index starting at 1
+// instead of the index shared across the calls.
+// The generated values are independent of the call order, so Reset is unnecessary for determinism
+func (f *Factory[T]) WithIsLocalIndex(isLocalIndex bool) *Factory[T] {
+	f.isLocalIndex = isLocalIndex
+	return f
+}
+
+// WithIsStrict sets whether to return an error listing the fields which can't be populated when building,
+// e.g. the client-defined types, interfaces, maps, and unexported fields.
+// The ignored fields and the ID field are not listed.
+// It has no effect when WithIsSetZeroValue is false
+func (f *Factory[T]) WithIsStrict(isStrict bool) *Factory[T] {
+	f.isStrict = isStrict
+	return f
+}
+
+// WithStringFormat sets the format of the generated strings.
+// The placeholders {struct}, {field}, and {i} are replaced with the struct name, the field name, and the index,
+// e.g. "{struct}-{field}-{i}" generates "User-Name-1".
+// The format without {i} generates the same string for every value.
+// It only applies to the string and *string fields, and an empty format restores the default "testN"
+func (f *Factory[T]) WithStringFormat(format string) *Factory[T] {
+	f.stringFormat = format
+	return f
+}
+
 // WithTrait sets the trait function
 func (f *Factory[T]) WithTrait(name string, tr setTraiter[T]) *Factory[T] {
 	f.traits[name] = tr
+	delete(f.traitsE, name)
+	return f
+}
+
+// WithTraitE sets the trait function which might fail, e.g. validating or transforming the value.
+//
+// The error is returned by the builder, e.g. when calling Get or Insert.
+// It replaces the trait function of the same name set by WithTrait
+func (f *Factory[T]) WithTraitE(name string, tr setTraiterE[T]) *Factory[T] {
+	if f.traitsE == nil {
+		f.traitsE = map[string]setTraiterE[T]{}
+	}
+
+	f.traitsE[name] = tr
+	delete(f.traits, name)
 	return f
 }
 
+// trait returns the trait function of the name.
+// The trait function set by WithTrait is wrapped to return nil error
+func (f *Factory[T]) trait(name string) (setTraiterE[T], bool) {
+	if tr, ok := f.traitsE[name]; ok {
+		return tr, true
+	}
+
+	tr, ok := f.traits[name]
+	if !ok {
+		return nil, false
+	}
+
+	return func(v *T) error {
+		tr(v)
+		return nil
+	}, true
+}
+
 // Reset resets the factory to its initial state
 func (f *Factory[T]) Reset() {
-	f.index = 1
-	f.err = nil
+	f.index = f.firstIndex()
+	f.seqID = f.firstIndex()
 	f.associations = [][]interface{}{}
+	f.sourceFields = nil
+	f.inserted = nil
 }
 
 // Build builds a value
 func (f *Factory[T]) Build(ctx context.Context) *builder[T] {
-	var v T
-	if f.blueprint != nil {
-		v = f.blueprint(f.index)
-	}
-
-	if f.isSetZeroValue {
-		f.setNonZeroValues(&v, f.ignoreFields)
-		f.index++
+	if f.configErr != nil {
+		return &builder[T]{ctx: ctx, v: new(T), f: f, err: f.configErr}
 	}
 
-	return &builder[T]{
+	var v T
+	var metas []Meta
+	err := f.runStep(ctx, StepBuild, []interface{}{&v}, func(ctx context.Context, _ Step) error {
+		var err error
+		metas, err = f.genValues(ctx, []*T{&v})
+		return err
+	})
+
+	b := &builder[T]{
 		ctx: ctx,
 		v:   &v,
 		f:   f,
-		err: nil,
+		err: err,
+	}
+	if len(metas) > 0 {
+		b.meta = metas[0]
 	}
+
+	return b
 }
 
 // BuildList creates a list of n values
@@ -149,29 +401,139 @@ func (f *Factory[T]) BuildList(ctx context.Context, n int) *builderList[T] {
 		}
 	}
 
+	if f.configErr != nil {
+		return &builderList[T]{ctx: ctx, err: f.configErr, f: f}
+	}
+
 	list := make([]*T, n)
+	vals := make([]interface{}, n)
 	for i := 0; i < n; i++ {
-		var v T
-		if f.blueprint != nil {
-			v = f.blueprint(f.index)
+		list[i] = new(T)
+		vals[i] = list[i]
+	}
+
+	var metas []Meta
+	err := f.runStep(ctx, StepBuild, vals, func(ctx context.Context, _ Step) error {
+		var err error
+		metas, err = f.genValues(ctx, list)
+		return err
+	})
+
+	return &builderList[T]{
+		ctx:   ctx,
+		list:  list,
+		metas: metas,
+		err:   err,
+		f:     f,
+	}
+}
+
+// genValues generates the values with the blueprint and non-zero values, and returns the metadata of each value.
+// It stops when the blueprint fails or the constraints are violated, otherwise, it returns the first error after generating all the values
+func (f *Factory[T]) genValues(ctx context.Context, list []*T) ([]Meta, error) {
+	if f.isLocalIndex {
+		f.index, f.seqID = f.firstIndex(), f.firstIndex()
+	}
+
+	var err error
+	metas := make([]Meta, len(list))
+	for i, v := range list {
+		if err := f.reserveIndex(ctx); err != nil {
+			return metas, err
+		}
+
+		metas[i] = Meta{Index: f.index}
+
+		if err := f.genValue(v); err != nil {
+			return metas, err
 		}
 
 		if f.isSetZeroValue {
-			f.setNonZeroValues(&v, f.ignoreFields)
 			f.index++
+
+			if f.isStrict && err == nil {
+				if paths := f.findUnpopulated(reflect.ValueOf(v).Elem(), "", f.fillIgnoreFields(f.ignoreFields)); len(paths) > 0 {
+					err = fmt.Errorf("%w: %s", errFieldsNotPopulated, strings.Join(paths, ", "))
+				}
+			}
 		}
 
-		list[i] = &v
+		f.setSeqID(v)
+
+		if err == nil {
+			err = f.anonymize(v)
+		}
 	}
 
-	return &builderList[T]{
-		ctx:  ctx,
-		list: list,
-		err:  nil,
-		f:    f,
+	return metas, err
+}
+
+// genValue generates the value with the blueprint and non-zero values of the current index.
+// The value is re-generated until it satisfies the constraints or the attempts are exhausted
+func (f *Factory[T]) genValue(v *T) error {
+	for attempt := 1; ; attempt++ {
+		if err := f.applyBlueprint(v); err != nil {
+			return err
+		}
+
+		if err := f.applyProvidedValues(v); err != nil {
+			return err
+		}
+
+		if err := f.applyCryptoValues(v); err != nil {
+			return err
+		}
+
+		f.setTimestamps(v)
+
+		if f.isSetZeroValue {
+			f.setNonZeroValues(v, f.fillIgnoreFields(f.ignoreFields))
+		}
+
+		err := f.checkConstraints(v)
+		if err == nil {
+			return nil
+		}
+
+		if errors.Is(err, errInvalidConstraint) {
+			return err
+		}
+
+		if attempt == maxConstraintAttempts {
+			return fmt.Errorf("index %d after %d attempts: %w", f.index, attempt, err)
+		}
+
+		*v = f.empty
 	}
 }
 
+// applyBlueprint sets the value created by the blueprint function if there is one
+func (f *Factory[T]) applyBlueprint(v *T) error {
+	switch {
+	case f.blueprintE != nil:
+		bv, err := f.blueprintE(f.index)
+		if err != nil {
+			return fmt.Errorf("blueprint at index %d: %w", f.index, err)
+		}
+		*v = bv
+	case f.blueprint != nil:
+		*v = f.blueprint(f.index)
+	}
+
+	return nil
+}
+
+// Meta returns the metadata of the built value, e.g. the sequence index used to generate it.
+// It is useful to compute the expected values without assuming the state of the factory
+func (b *builder[T]) Meta() Meta {
+	return b.meta
+}
+
+// Meta returns the metadata of each built value in the list
+func (b *builderList[T]) Meta() []Meta {
+	return slices.Clone(b.metas)
+}
+
 // Get returns the value
 func (b *builder[T]) Get() (T, error) {
 	if b.err != nil {
@@ -181,6 +543,48 @@ func (b *builder[T]) Get() (T, error) {
 	return *b.v, nil
 }
 
+// Patch returns the partial update payload containing only the given fields of the value,
+// e.g. the request body of the PATCH API.
+// The keys are the names of the tags of the namespace set by WithTagNamespace(json by default),
+// and fall back to the field names if there is no tag.
+// The given fields are always included, even if they are empty and tagged with omitempty
+func (b *builder[T]) Patch(fields ...string) (map[string]interface{}, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	if err := checkFieldsExist(b.f.dataType, fields); err != nil {
+		return nil, err
+	}
+
+	val := reflect.ValueOf(b.v).Elem()
+	patch := make(map[string]interface{}, len(fields))
+	for _, name := range fields {
+		field, _ := b.f.dataType.FieldByName(name)
+		if field.PkgPath != "" {
+			return nil, fmt.Errorf("%w: %s is unexported", errFieldNotFound, name)
+		}
+
+		key, _ := tagName(field, b.f.namespace())
+		if key == "-" {
+			key = field.Name
+		}
+
+		patch[key] = val.FieldByIndex(field.Index).Interface()
+	}
+
+	return patch, nil
+}
+
+// GetP returns the pointer to the value, mutating it also mutates the value of the builder
+func (b *builder[T]) GetP() (*T, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return b.v, nil
+}
+
 // Get returns the list of values
 func (b *builderList[T]) Get() ([]T, error) {
 	if b.err != nil {
@@ -195,45 +599,126 @@ func (b *builderList[T]) Get() ([]T, error) {
 	return output, nil
 }
 
+// GetP returns the pointers to the values, mutating them also mutates the values of the builder
+func (b *builderList[T]) GetP() ([]*T, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return slices.Clone(b.list), nil
+}
+
 // Insert inserts the value into the database
 func (b *builder[T]) Insert() (T, error) {
+	v, err := b.InsertP()
+	if err != nil {
+		return b.f.empty, err
+	}
+
+	return *v, nil
+}
+
+// InsertP inserts the value into the database, and returns the pointer to the inserted value.
+// It avoids copying the large struct, and the returned value can be mutated in the follow-up test steps
+func (b *builder[T]) InsertP() (*T, error) {
 	if b.err != nil {
-		return b.f.empty, b.err
+		return nil, b.err
 	}
 
+	restore := b.f.useDB(b.dbName)
+	defer restore()
+
 	if b.f.db == nil {
-		return b.f.empty, errDBIsNotProvided
+		return nil, errDBIsNotProvided
+	}
+
+	if err := b.f.checkWritable(b.ctx); err != nil {
+		return nil, err
+	}
+
+	var res *T
+	err := b.f.runStep(b.ctx, StepInsert, []interface{}{b.v}, func(ctx context.Context, _ Step) error {
+		var err error
+		res, err = b.insert(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	return res, nil
+}
+
+// insert inserts the value into the database
+func (b *builder[T]) insert(ctx context.Context) (*T, error) {
 	if len(b.f.associations) > 0 {
-		return b.insertWithAssoc(b.ctx)
+		return b.insertWithAssoc(ctx)
+	}
+
+	if b.skipInsert {
+		return b.v, nil
 	}
 
-	val, err := b.f.db.Insert(b.ctx, db.InsertParams{StorageName: b.f.storageName, Value: b.v})
+	stored, err := b.f.transformValues([]interface{}{b.v})
 	if err != nil {
-		return b.f.empty, err
+		return nil, err
 	}
 
+	omitFields := b.f.factoryOmitFields(stored)
+	val, err := b.f.db.Insert(ctx, db.InsertParams{
+		StorageName:   b.f.storageName,
+		IDField:       b.f.idField,
+		Value:         stored[0],
+		OmitFields:    omitFields,
+		NoGeneratedID: b.f.noGeneratedID,
+	})
+	if err != nil {
+		return nil, b.f.insertError(b.f.storageName, b.f.idField, stored, err)
+	}
+	val = b.f.untransformValues([]interface{}{b.v}, []interface{}{val})[0]
+	if b.f.isCleanable(omitFields) {
+		b.f.recordInserted(b.f.storageName, b.f.idField, []interface{}{val})
+	}
+	b.f.nodeInserted(b.f.storageName, []interface{}{val})
+
 	v, ok := val.(*T)
 	if !ok {
-		return b.f.empty, errCantCvtToPtr
+		return nil, errCantCvtToPtr
 	}
 
-	return *v, nil
+	return v, nil
 }
 
 // Insert inserts the list of values into the database
 func (b *builderList[T]) Insert() ([]T, error) {
+	vals, err := b.InsertP()
+	if err != nil {
+		return nil, err
+	}
+
+	output := make([]T, len(vals))
+	for i, v := range vals {
+		output[i] = *v
+	}
+
+	return output, nil
+}
+
+// InsertP inserts the list of values into the database, and returns the pointers to the inserted values
+func (b *builderList[T]) InsertP() ([]*T, error) {
 	if b.err != nil {
 		return nil, b.err
 	}
 
+	restore := b.f.useDB(b.dbName)
+	defer restore()
+
 	if b.f.db == nil {
 		return nil, errDBIsNotProvided
 	}
 
-	if len(b.f.associations) > 0 {
-		return b.insertWithAssoc(b.ctx)
+	if err := b.f.checkWritable(b.ctx); err != nil {
+		return nil, err
 	}
 
 	// convert to any type
@@ -241,31 +726,162 @@ func (b *builderList[T]) Insert() ([]T, error) {
 	for i, v := range b.list {
 		input[i] = v
 	}
-	vals, err := b.f.db.InsertList(b.ctx, db.InsertListParams{StorageName: b.f.storageName, Values: input})
+
+	var res []*T
+	err := b.f.runStep(b.ctx, StepInsert, input, func(ctx context.Context, _ Step) error {
+		var err error
+		res, err = b.insert(ctx, input)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// convert to []T
-	output := make([]T, len(vals))
+	return res, nil
+}
+
+// insert inserts the list of values(input) into the database
+func (b *builderList[T]) insert(ctx context.Context, input []interface{}) ([]*T, error) {
+	if len(b.f.associations) > 0 {
+		return b.insertWithAssoc(ctx)
+	}
+
+	if b.skipInsert {
+		output := make([]*T, len(input))
+		for i, val := range input {
+			v, ok := val.(*T)
+			if !ok {
+				return nil, errCantCvtToPtr
+			}
+
+			output[i] = v
+		}
+
+		return output, nil
+	}
+
+	stored, err := b.f.transformValues(input)
+	if err != nil {
+		return nil, err
+	}
+
+	omitFields := b.f.factoryOmitFields(stored)
+	vals, err := b.f.db.InsertList(ctx, db.InsertListParams{
+		StorageName:   b.f.storageName,
+		IDField:       b.f.idField,
+		Values:        stored,
+		OmitFields:    omitFields,
+		NoGeneratedID: b.f.noGeneratedID,
+	})
+	if err != nil {
+		return nil, b.f.insertError(b.f.storageName, b.f.idField, stored, err)
+	}
+	vals = b.f.untransformValues(input, vals)
+	if b.f.isCleanable(omitFields) {
+		b.f.recordInserted(b.f.storageName, b.f.idField, vals)
+	}
+	b.f.nodeInserted(b.f.storageName, vals)
+
+	// convert to []*T
+	output := make([]*T, len(vals))
 	for i, val := range vals {
 		v, ok := val.(*T)
 		if !ok {
 			return nil, errCantCvtToPtr
 		}
 
-		output[i] = *v
+		output[i] = v
 	}
 
 	return output, nil
 }
 
+// SkipInsert sets the value not to be inserted by Insert, while the associations are still inserted,
+// and the foreign keys of the value are set to them.
+// It's useful when the value must be created through the application code under test
+//
+// Example:
+//
+//	order, err := orderFactory.Build(ctx).WithOne(&user).SkipInsert().Insert()
+//	// user is inserted, and order.UserID is set to user.ID
+func (b *builder[T]) SkipInsert() *builder[T] {
+	b.skipInsert = true
+	return b
+}
+
+// SkipInsert sets the values not to be inserted by Insert, while the associations are still inserted,
+// and the foreign keys of the values are set to them
+func (b *builderList[T]) SkipInsert() *builderList[T] {
+	b.skipInsert = true
+	return b
+}
+
+// UseDB selects the named database connection set by WithNamedDB to insert the value into
+func (b *builder[T]) UseDB(name string) *builder[T] {
+	if b.err != nil {
+		return b
+	}
+
+	if _, ok := b.f.dbs[name]; !ok {
+		b.err = fmt.Errorf("%w: %s", errDBNotFound, name)
+		return b
+	}
+
+	b.dbName = name
+	return b
+}
+
+// UseDB selects the named database connection set by WithNamedDB to insert the values into
+func (b *builderList[T]) UseDB(name string) *builderList[T] {
+	if b.err != nil {
+		return b
+	}
+
+	if _, ok := b.f.dbs[name]; !ok {
+		b.err = fmt.Errorf("%w: %s", errDBNotFound, name)
+		return b
+	}
+
+	b.dbName = name
+	return b
+}
+
+// useDB switches to the named database connection, and returns the function switching back.
+// The empty name means the default database connection.
+// The cache of the non-custom types is dropped while switched, because the custom types depend on the database
+func (f *Factory[T]) useDB(name string) func() {
+	if name == "" {
+		return func() {}
+	}
+
+	prevDB, prevName, prevTypes := f.db, f.dbName, f.nonCustomTypes
+	f.db, f.dbName, f.nonCustomTypes = f.dbs[name], name, nil
+
+	return func() {
+		f.db, f.dbName, f.nonCustomTypes = prevDB, prevName, prevTypes
+	}
+}
+
+// dbByName returns the database connection of the name, the empty name means the default one
+func (f *Factory[T]) dbByName(name string) database {
+	if name == "" {
+		return f.db
+	}
+
+	return f.dbs[name]
+}
+
 // Overwrite overwrites the value with the given value
 func (b *builder[T]) Overwrite(ow T) *builder[T] {
 	if b.err != nil {
 		return b
 	}
 
+	if err := checkFrozenOverwrite(ow, b.frozen); err != nil {
+		b.err = err
+		return b
+	}
+
 	if err := copyValues(b.v, ow); err != nil {
 		b.err = err
 		return b
@@ -281,6 +897,11 @@ func (b *builderList[T]) Overwrites(ows ...T) *builderList[T] {
 	}
 
 	for i := 0; i < len(ows) && i < len(b.list); i++ {
+		if err := checkFrozenOverwrite(ows[i], b.frozen); err != nil {
+			b.err = err
+			return b
+		}
+
 		if err := copyValues(b.list[i], ows[i]); err != nil {
 			b.err = err
 			return b
@@ -296,6 +917,11 @@ func (b *builderList[T]) Overwrite(ow T) *builderList[T] {
 		return b
 	}
 
+	if err := checkFrozenOverwrite(ow, b.frozen); err != nil {
+		b.err = err
+		return b
+	}
+
 	for i := 0; i < len(b.list); i++ {
 		if err := copyValues(b.list[i], ow); err != nil {
 			b.err = err
@@ -313,13 +939,16 @@ func (b *builder[T]) SetTrait(key string) *builder[T] {
 		return b
 	}
 
-	tr, ok := b.f.traits[key]
+	tr, ok := b.f.trait(key)
 	if !ok {
 		b.err = fmt.Errorf("%w: %s", errWithTraitNameNotFound, key)
 		return b
 	}
 
-	tr(b.v)
+	if err := applyTrait(b.v, key, tr, b.frozen); err != nil {
+		b.err = err
+		return b
+	}
 
 	return b
 }
@@ -332,13 +961,16 @@ func (b *builderList[T]) SetTraits(keys ...string) *builderList[T] {
 	}
 
 	for i := 0; i < len(keys) && i < len(b.list); i++ {
-		tr, ok := b.f.traits[keys[i]]
+		tr, ok := b.f.trait(keys[i])
 		if !ok {
 			b.err = fmt.Errorf("%w: %s", errWithTraitNameNotFound, keys[i])
 			return b
 		}
 
-		tr(b.list[i])
+		if err := applyTrait(b.list[i], keys[i], tr, b.frozen); err != nil {
+			b.err = err
+			return b
+		}
 	}
 
 	return b
@@ -351,14 +983,17 @@ func (b *builderList[T]) SetTrait(key string) *builderList[T] {
 		return b
 	}
 
-	tr, ok := b.f.traits[key]
+	tr, ok := b.f.trait(key)
 	if !ok {
 		b.err = fmt.Errorf("%w: %s", errWithTraitNameNotFound, key)
 		return b
 	}
 
 	for i := 0; i < len(b.list); i++ {
-		tr(b.list[i])
+		if err := applyTrait(b.list[i], key, tr, b.frozen); err != nil {
+			b.err = err
+			return b
+		}
 	}
 
 	return b
@@ -372,6 +1007,11 @@ func (b *builder[T]) SetZero(fields ...string) *builder[T] {
 	}
 
 	for _, field := range fields {
+		if slices.Contains(b.frozen, field) {
+			b.err = fmt.Errorf("%w: %s", errFieldFrozen, field)
+			return b
+		}
+
 		curField := reflect.ValueOf(b.v).Elem().FieldByName(field)
 		if !curField.IsValid() {
 			b.err = fmt.Errorf("%w: %s", errFieldNotFound, field)
@@ -403,6 +1043,11 @@ func (b *builderList[T]) SetZero(i int, fields ...string) *builderList[T] {
 	}
 
 	for _, field := range fields {
+		if slices.Contains(b.frozen, field) {
+			b.err = fmt.Errorf("%w: %s", errFieldFrozen, field)
+			return b
+		}
+
 		curField := reflect.ValueOf(b.list[i]).Elem().FieldByName(field)
 		if !curField.IsValid() {
 			b.err = fmt.Errorf("%w: %s", errFieldNotFound, field)
@@ -420,6 +1065,40 @@ func (b *builderList[T]) SetZero(i int, fields ...string) *builderList[T] {
 	return b
 }
 
+// Freeze marks the fields as immutable for the rest of the chain.
+// Subsequent Overwrite, SetTrait, and SetZero attempts on the fields return an error.
+// It returns an error if the field is not found.
+func (b *builder[T]) Freeze(fields ...string) *builder[T] {
+	if b.err != nil {
+		return b
+	}
+
+	if err := checkFieldsExist(b.f.dataType, fields); err != nil {
+		b.err = err
+		return b
+	}
+
+	b.frozen = append(b.frozen, fields...)
+	return b
+}
+
+// Freeze marks the fields of all the values as immutable for the rest of the chain.
+// Subsequent Overwrite, Overwrites, SetTrait, SetTraits, and SetZero attempts on the fields return an error.
+// It returns an error if the field is not found.
+func (b *builderList[T]) Freeze(fields ...string) *builderList[T] {
+	if b.err != nil {
+		return b
+	}
+
+	if err := checkFieldsExist(b.f.dataType, fields); err != nil {
+		b.err = err
+		return b
+	}
+
+	b.frozen = append(b.frozen, fields...)
+	return b
+}
+
 // WithOne sets one or more single-value associations for the factory.
 //
 // This function supports setting associations for both single-level and multi-level relationships.
@@ -481,6 +1160,122 @@ func (b *builderList[T]) WithOne(vals ...interface{}) *builderList[T] {
 	return b
 }
 
+// WithOneFrom sets a single-value association, whose field referenced by the foreign key is the given source field.
+//
+// It overrides the refField of the foreignKey tag, and the default ID field, just for this association value.
+//
+// Example:
+//
+//	orderFactory.Build(ctx).WithOneFrom(&customer, gofacto.SourceField("Code"))
+//	// order.CustomerCode == customer.Code
+func (b *builder[T]) WithOneFrom(v interface{}, field SourceField) *builder[T] {
+	if b.err != nil {
+		return b
+	}
+
+	if err := b.f.setSourceField(v, field); err != nil {
+		b.err = err
+		return b
+	}
+
+	b.f.associations = append(b.f.associations, []interface{}{v})
+	return b
+}
+
+// WithOneFrom sets a single-value association, whose field referenced by the foreign key is the given source field.
+//
+// It overrides the refField of the foreignKey tag, and the default ID field, just for this association value.
+//
+// Example:
+//
+//	orderFactory.BuildList(ctx, 2).WithOneFrom(&customer, gofacto.SourceField("Code"))
+//	// orders[0].CustomerCode == customer.Code
+//	// orders[1].CustomerCode == customer.Code
+func (b *builderList[T]) WithOneFrom(v interface{}, field SourceField) *builderList[T] {
+	if b.err != nil {
+		return b
+	}
+
+	if err := b.f.setSourceField(v, field); err != nil {
+		b.err = err
+		return b
+	}
+
+	b.f.associations = append(b.f.associations, []interface{}{v})
+	return b
+}
+
+// WithOneShared sets a single-value association shared by part of the list,
+// and the given fraction(0 to 1) of the values get their own freshly built associations instead.
+// It's useful to model the realistic one-to-many cardinalities in a single call.
+//
+// The first values get the fresh associations, and the rest share the given one.
+// The given association is shared by at least one value, so the fraction 1 means all but the last value.
+//
+// Example:
+//
+//	// orders[0] and orders[1] have their own customers, orders[2:] share the customer
+//	orderFactory.BuildList(ctx, 10).WithOneShared(&customer, 0.2)
+func (b *builderList[T]) WithOneShared(v interface{}, fraction float64) *builderList[T] {
+	if b.err != nil {
+		return b
+	}
+
+	if err := checkAssoc(v); err != nil {
+		b.err = err
+		return b
+	}
+
+	if fraction < 0 || fraction > 1 {
+		b.err = fmt.Errorf("%w: %v", errFractionOutOfRange, fraction)
+		return b
+	}
+
+	// the values are assigned to the associations in order, and the ones beyond the length reuse the last association.
+	// e.g. [fresh1, fresh2, shared] for 5 values is [fresh1, fresh2, shared, shared, shared]
+	numFresh := min(int(math.Round(fraction*float64(len(b.list)))), len(b.list)-1)
+	typ := reflect.TypeOf(v).Elem()
+	vals := make([]interface{}, 0, numFresh+1)
+	for i := 0; i < numFresh; i++ {
+		vals = append(vals, reflect.New(typ).Interface())
+	}
+	vals = append(vals, v)
+
+	b.f.associations = append(b.f.associations, vals)
+	return b
+}
+
+// WithMany sets multiple associations of the same type for the value.
+//
+// The input must be a slice of interface{}, where each element is a pointer to a struct of the same type.
+// The value references the first element, and all the elements are inserted.
+// It's useful when the deeper associations need several values of the type.
+//
+// Example:
+//
+//  1. Single-level association (e.g., Transaction references the first User, and all Users are inserted):
+//     transactionFactory.WithMany([]interface{}{&User{}, &User{}})
+//
+//  2. Multi-level association (e.g., Transaction -> Category -> User, each Category references one of the Users):
+//     transactionFactory.WithMany([]interface{}{&Category{}, &Category{}}).WithMany([]interface{}{&User{}, &User{}})
+//
+// Note:
+//   - All elements in the input slice must be pointers to structs of the same type.
+//   - Non-pointer, non-struct, or mixed-type arguments will result in an error.
+func (b *builder[T]) WithMany(vals []interface{}) *builder[T] {
+	if b.err != nil {
+		return b
+	}
+
+	if err := checkAssocs(vals); err != nil {
+		b.err = err
+		return b
+	}
+
+	b.f.associations = append(b.f.associations, vals)
+	return b
+}
+
 // WithMany sets multiple associations of the same type for each item in the factory list.
 //
 // The input must be a slice of interface{}, where each element is a pointer to a struct of the same type.
@@ -1,14 +1,27 @@
 package gofacto
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/eyo-chen/gofacto/db/mockf"
 	"github.com/eyo-chen/gofacto/internal/db"
 	"github.com/eyo-chen/gofacto/internal/testutils"
 )
@@ -19,12 +32,21 @@ var (
 )
 
 // mockDB is a mock implementation of the db.DB interface.
-type mockDB struct{}
+type mockDB struct {
+	// deleted records the params passed to DeleteList
+	deleted []db.DeleteListParams
+
+	// updated records the params passed to Update
+	updated []db.UpdateParams
+
+	// insertListErrs is the error returned by InsertList for the storage name
+	insertListErrs map[string]error
+}
 
 // Insert inserts a single value into the database.
 func (m *mockDB) Insert(ctx context.Context, params db.InsertParams) (interface{}, error) {
 	val := reflect.ValueOf(params.Value)
-	if err := setIDField(val); err != nil {
+	if err := setIDField(val, params.IDField); err != nil {
 		return nil, err
 	}
 
@@ -33,9 +55,13 @@ func (m *mockDB) Insert(ctx context.Context, params db.InsertParams) (interface{
 
 // InsertList inserts a list of values into the database.
 func (m *mockDB) InsertList(ctx context.Context, params db.InsertListParams) ([]interface{}, error) {
+	if err, ok := m.insertListErrs[params.StorageName]; ok {
+		return nil, err
+	}
+
 	for _, v := range params.Values {
 		val := reflect.ValueOf(v)
-		if err := setIDField(val); err != nil {
+		if err := setIDField(val, params.IDField); err != nil {
 			return nil, err
 		}
 	}
@@ -43,20 +69,36 @@ func (m *mockDB) InsertList(ctx context.Context, params db.InsertListParams) ([]
 	return params.Values, nil
 }
 
+// Update updates a value in the database.
+func (m *mockDB) Update(ctx context.Context, params db.UpdateParams) error {
+	m.updated = append(m.updated, params)
+	return nil
+}
+
+// DeleteList deletes a list of values from the database.
+func (m *mockDB) DeleteList(ctx context.Context, params db.DeleteListParams) error {
+	m.deleted = append(m.deleted, params)
+	return nil
+}
+
 // GenCustomType generates a custom type.
 func (m *mockDB) GenCustomType(reflect.Type) (interface{}, bool) {
 	return nil, false
 }
 
-// setIDField sets the ID field of a struct.
-// In this mock, it always sets the ID field to 1.
-func setIDField(val reflect.Value) error {
+// setIDField sets the ID field(name) of a struct.
+// In this mock, it sets the ID field to a random value.
+func setIDField(val reflect.Value, name string) error {
 	v := val.Elem()
-	idField := v.FieldByName("ID")
+	idField := v.FieldByName(name)
 	if !idField.IsValid() {
 		return errors.New("ID field not found")
 	}
 	randomID := rand.Intn(1000) + 1
+	if idField.Kind() == reflect.String {
+		idField.SetString(fmt.Sprintf("uuid-%d", randomID))
+		return nil
+	}
 	idField.SetInt(int64(randomID))
 
 	return nil
@@ -308,7 +350,7 @@ func new_PassNonStruct(t *testing.T) {
 	got := New(1)
 
 	want := &Factory[int]{
-		err: errInvalidType,
+		configErr: errInvalidType,
 	}
 
 	if err := checkFactory(got, want); err != nil {
@@ -323,7 +365,7 @@ func new_WrongTagKey(t *testing.T) {
 	got := New(testStructWithWrongTag{})
 
 	want := &Factory[testStructWithWrongTag]{
-		err: errTagFormat,
+		configErr: errTagFormat,
 	}
 
 	if err := checkFactory(got, want); err != nil {
@@ -338,7 +380,7 @@ func new_WrongTagForeignKey(t *testing.T) {
 	got := New(testStructWithWrongTagColon{})
 
 	want := &Factory[testStructWithWrongTagColon]{
-		err: errTagFormat,
+		configErr: errTagFormat,
 	}
 
 	if err := checkFactory(got, want); err != nil {
@@ -353,7 +395,7 @@ func new_EmptyTag(t *testing.T) {
 	got := New(testStructWithWrongTagColon{})
 
 	want := &Factory[testStructWithWrongTagColon]{
-		err: errTagFormat,
+		configErr: errTagFormat,
 	}
 
 	if err := checkFactory(got, want); err != nil {
@@ -368,7 +410,7 @@ func new_WrongTagFormat(t *testing.T) {
 	got := New(testStructWithWrongTagColon{})
 
 	want := &Factory[testStructWithWrongTagColon]{
-		err: errTagFormat,
+		configErr: errTagFormat,
 	}
 
 	if err := checkFactory(got, want); err != nil {
@@ -2907,6 +2949,177 @@ func withOne_OnBuilderListWithCycle(t *testing.T) {
 	}
 }
 
+func TestWithOneFrom(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when withOneFrom, foreign key should be the source field":          withOneFrom_CorrectCase,
+		"when withOneFrom on builder list, all should use the source field": withOneFrom_BuilderList,
+		"when withOneFrom with not exist source field, return error":        withOneFrom_FieldNotFound,
+		"when withOneFrom not pass ptr, return error":                       withOneFrom_NotPassPtr,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func withOneFrom_CorrectCase(t *testing.T) {
+	f := New(testAssocStruct{}).WithDB(&mockDB{})
+
+	// the tag references OtherID, which is overridden by the source field
+	assVal := testStructWithCustomFK{OtherID: -1}
+	val, err := f.Build(mockCTX).
+		WithOne(&testStructWithID{}).
+		WithOneFrom(&assVal, SourceField("ID")).
+		Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.CustomForeignKey != assVal.ID {
+		t.Fatalf("CustomForeignKey should be %v, got %v", assVal.ID, val.CustomForeignKey)
+	}
+}
+
+func withOneFrom_BuilderList(t *testing.T) {
+	f := New(testAssocStruct{}).WithDB(&mockDB{})
+
+	assVal := testStructWithCustomFK{OtherID: -1}
+	vals, err := f.BuildList(mockCTX, 2).
+		WithOne(&testStructWithID{}).
+		WithOneFrom(&assVal, SourceField("ID")).
+		Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, v := range vals {
+		if v.CustomForeignKey != assVal.ID {
+			t.Fatalf("CustomForeignKey should be %v, got %v", assVal.ID, v.CustomForeignKey)
+		}
+	}
+}
+
+func withOneFrom_FieldNotFound(t *testing.T) {
+	f := New(testAssocStruct{}).WithDB(&mockDB{})
+
+	_, err := f.Build(mockCTX).WithOneFrom(&testStructWithCustomFK{}, SourceField("Code")).Insert()
+	if !errors.Is(err, errFieldNotFound) {
+		t.Fatalf("error should be %v", errFieldNotFound)
+	}
+}
+
+func withOneFrom_NotPassPtr(t *testing.T) {
+	f := New(testAssocStruct{}).WithDB(&mockDB{})
+
+	_, err := f.BuildList(mockCTX, 2).WithOneFrom(testStructWithCustomFK{}, SourceField("ID")).Insert()
+	if !errors.Is(err, errIsNotPtr) {
+		t.Fatalf("error should be %v", errIsNotPtr)
+	}
+}
+
+func TestWithOneShared(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when withOneShared, fraction of values get fresh associations":   withOneShared_Fraction,
+		"when withOneShared with fraction 0, all share the association":   withOneShared_Zero,
+		"when withOneShared with fraction 1, last shares the association": withOneShared_One,
+		"when withOneShared with invalid fraction, return error":          withOneShared_InvalidFraction,
+		"when withOneShared not pass ptr, return error":                   withOneShared_NotPassPtr,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func withOneShared_Fraction(t *testing.T) {
+	f := New(testAssocStruct{}).WithDB(&mockDB{})
+
+	shared := testStructWithID{}
+	vals, err := f.BuildList(mockCTX, 10).WithOneShared(&shared, 0.3).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	assocs := insertedValues(f, "test_struct_with_ids")
+	if len(assocs) != 4 {
+		t.Fatalf("3 fresh associations and the shared one should be inserted, got %v", len(assocs))
+	}
+
+	for i, v := range vals {
+		want := &shared
+		if i < 3 {
+			want = assocs[i].(*testStructWithID)
+		}
+
+		if v.ForeignKey != want.ID {
+			t.Fatalf("ForeignKey of %d should be %v, got %v", i, want.ID, v.ForeignKey)
+		}
+	}
+}
+
+// insertedValues returns the values inserted into the storage by the factory
+func insertedValues[T any](f *Factory[T], storageName string) []interface{} {
+	var vals []interface{}
+	for _, r := range f.inserted {
+		if r.storageName == storageName {
+			vals = append(vals, r.vals...)
+		}
+	}
+
+	return vals
+}
+
+func withOneShared_Zero(t *testing.T) {
+	f := New(testAssocStruct{}).WithDB(&mockDB{})
+
+	shared := testStructWithID{}
+	vals, err := f.BuildList(mockCTX, 3).WithOneShared(&shared, 0).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, v := range vals {
+		if v.ForeignKey != shared.ID {
+			t.Fatalf("ForeignKey should be %v, got %v", shared.ID, v.ForeignKey)
+		}
+	}
+}
+
+func withOneShared_One(t *testing.T) {
+	f := New(testAssocStruct{}).WithDB(&mockDB{})
+
+	shared := testStructWithID{}
+	vals, err := f.BuildList(mockCTX, 3).WithOneShared(&shared, 1).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if vals[2].ForeignKey != shared.ID {
+		t.Fatalf("ForeignKey of the last should be %v, got %v", shared.ID, vals[2].ForeignKey)
+	}
+	if n := len(insertedValues(f, "test_struct_with_ids")); n != 3 {
+		t.Fatalf("values but the last should have their own associations, got %v associations", n)
+	}
+}
+
+func withOneShared_InvalidFraction(t *testing.T) {
+	f := New(testAssocStruct{}).WithDB(&mockDB{})
+
+	_, err := f.BuildList(mockCTX, 3).WithOneShared(&testStructWithID{}, 1.5).Insert()
+	if !errors.Is(err, errFractionOutOfRange) {
+		t.Fatalf("error should be %v", errFractionOutOfRange)
+	}
+}
+
+func withOneShared_NotPassPtr(t *testing.T) {
+	f := New(testAssocStruct{}).WithDB(&mockDB{})
+
+	_, err := f.BuildList(mockCTX, 3).WithOneShared(testStructWithID{}, 0.5).Insert()
+	if !errors.Is(err, errIsNotPtr) {
+		t.Fatalf("error should be %v", errIsNotPtr)
+	}
+}
+
 func TestWithMany(t *testing.T) {
 	for _, fn := range map[string]func(*testing.T){
 		"when withMany on builder, insert successfully":                  withMany_CorrectCase,
@@ -2916,6 +3129,8 @@ func TestWithMany(t *testing.T) {
 		"when withMany on builder pass diff struct, return error":        withMany_PassDiffStruct,
 		"when withMany on builder with cycle, return error":              withMany_WithCycle,
 		"when withMany on builder with err, return error":                withMany_WithErr,
+		"when withMany on single builder, reference the first one":       withMany_SingleBuilder,
+		"when withMany on single builder not pass ptr, return error":     withMany_SingleBuilderNotPassPtr,
 	} {
 		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
 			fn(t)
@@ -3103,10 +3318,56 @@ func withMany_WithErr(t *testing.T) {
 	}
 }
 
-func TestReset(t *testing.T) {
+func withMany_SingleBuilder(t *testing.T) {
+	f := New(testAssocStruct{}).WithDB(&mockDB{})
+
+	assVals2 := []interface{}{&testStructWithID2{}, &testStructWithID2{}}
+	assVals3 := []interface{}{&testStructWithID3{}, &testStructWithID3{}}
+	val, err := f.Build(mockCTX).
+		WithOne(&testStructWithID{}).
+		WithMany(assVals2).
+		WithMany(assVals3).
+		Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	first := assVals2[0].(*testStructWithID2)
+	if val.ForeignKey2 == nil || *val.ForeignKey2 != first.ID {
+		t.Fatalf("ForeignKey2 should be %v", first.ID)
+	}
+	if err := testutils.CompareVal(val.ForeignValue2, first); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for i := range assVals2 {
+		v2, v3 := assVals2[i].(*testStructWithID2), assVals3[i].(*testStructWithID3)
+		if v2.ID == 0 || v3.ID == 0 {
+			t.Fatalf("all associations should be inserted")
+		}
+		if v2.ForeignKey != v3.ID {
+			t.Fatalf("ForeignKey should be %v", v3.ID)
+		}
+	}
+}
+
+func withMany_SingleBuilderNotPassPtr(t *testing.T) {
+	f := New(testAssocStruct{}).WithDB(&mockDB{})
+
+	val, err := f.Build(mockCTX).WithMany([]interface{}{testStructWithID{}, testStructWithID{}}).Insert()
+	if !errors.Is(err, errIsNotPtr) {
+		t.Fatalf("error should be %v", errIsNotPtr)
+	}
+
+	if err := testutils.CompareVal(val, testAssocStruct{}); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func TestMeta(t *testing.T) {
 	for _, fn := range map[string]func(*testing.T){
-		"when reset, index should be 0":            reset_Index,
-		"when reset, associations should be empty": reset_Associations,
+		"when build, meta should have the index used":             meta_Build,
+		"when build list, meta should have the index of each one": meta_BuildList,
 	} {
 		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
 			fn(t)
@@ -3114,29 +3375,5794 @@ func TestReset(t *testing.T) {
 	}
 }
 
-func reset_Index(t *testing.T) {
-	f := New(testStruct{})
+func meta_Build(t *testing.T) {
+	f := New(testStructWithID3{})
 
-	_, err := f.BuildList(mockCTX, 5).Get()
+	// advance the index of the factory
+	if _, err := f.BuildList(mockCTX, 2).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	b := f.Build(mockCTX)
+	v, err := b.Get()
 	if err != nil {
-		t.Fatal(err.Error())
+		t.Fatalf("unexpected error %v", err)
 	}
 
-	f.Reset()
-	if f.index != 1 {
-		t.Fatalf("index should be 1")
+	meta := b.Meta()
+	if meta.Index != 3 {
+		t.Fatalf("Index should be 3, got %v", meta.Index)
+	}
+	if want := fmt.Sprintf("test%d", meta.Index); v.Name != want {
+		t.Fatalf("Name should be %v, got %v", want, v.Name)
 	}
 }
 
-func reset_Associations(t *testing.T) {
-	f := New(testAssocStruct{}).WithDB(&mockDB{})
+func meta_BuildList(t *testing.T) {
+	f := New(testStructWithID3{})
+	f.Build(mockCTX)
 
-	// explicitly set association and not insert to make the association not empty
-	f.Build(mockCTX).WithOne(&testStructWithID{})
+	b := f.BuildList(mockCTX, 3)
+	vals, err := b.Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
 
-	f.Reset()
-	if len(f.associations) != 0 {
-		t.Fatalf("associations should be empty")
+	metas := b.Meta()
+	if len(metas) != len(vals) {
+		t.Fatalf("len of metas should be %v, got %v", len(vals), len(metas))
+	}
+	for i, meta := range metas {
+		if meta.Index != i+2 {
+			t.Fatalf("Index of %d should be %v, got %v", i, i+2, meta.Index)
+		}
+		if want := fmt.Sprintf("test%d", meta.Index); vals[i].Name != want {
+			t.Fatalf("Name of %d should be %v, got %v", i, want, vals[i].Name)
+		}
+	}
+}
+
+// mockCustomTypeDB is the mock database counting the calls of GenCustomType.
+type mockCustomTypeDB struct {
+	mockDB
+
+	// calls is the number of calls of GenCustomType for each type
+	calls map[reflect.Type]int
+}
+
+// GenCustomType generates customType, and counts the calls.
+func (m *mockCustomTypeDB) GenCustomType(t reflect.Type) (interface{}, bool) {
+	m.calls[t]++
+	if t == reflect.TypeOf(customType1) {
+		return customType1, true
+	}
+
+	return nil, false
+}
+
+func TestGenCustomTypeCache(t *testing.T) {
+	mdb := &mockCustomTypeDB{calls: map[reflect.Type]int{}}
+	type testStructWithDBCustomType struct {
+		Name   string
+		Custom customType
+	}
+	f := New(testStructWithDBCustomType{}).WithDB(mdb)
+
+	vals, err := f.BuildList(mockCTX, 3).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, v := range vals {
+		if v.Custom != customType1 {
+			t.Fatalf("Custom should be generated by db, got %v", v.Custom)
+		}
+	}
+
+	if n := mdb.calls[reflect.TypeOf("")]; n != 1 {
+		t.Fatalf("non-custom type should be resolved once, got %v", n)
+	}
+	if n := mdb.calls[reflect.TypeOf(customType1)]; n != 3 {
+		t.Fatalf("custom type should be generated for each value, got %v", n)
+	}
+
+	// changing the db clears the cache
+	f.WithDB(mdb)
+	if _, err := f.Build(mockCTX).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if n := mdb.calls[reflect.TypeOf("")]; n != 2 {
+		t.Fatalf("non-custom type should be resolved again after changing db, got %v", n)
+	}
+}
+
+func TestGenCustomTypeCache_SwitchDB(t *testing.T) {
+	type testStructWithDBCustomType struct {
+		Name   string
+		Custom customType
+	}
+
+	for name, switchDB := range map[string]func(f *Factory[testStructWithDBCustomType], d database) func(){
+		"useDB": func(f *Factory[testStructWithDBCustomType], d database) func() {
+			f.WithNamedDB("custom", d)
+			return f.useDB("custom")
+		},
+		"bindDB": func(f *Factory[testStructWithDBCustomType], d database) func() {
+			unbind := f.bindDB(d)
+			return func() { unbind(false) }
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mdb := &mockCustomTypeDB{calls: map[reflect.Type]int{}}
+			f := New(testStructWithDBCustomType{}).WithDB(&mockDB{})
+
+			// customType is cached as the non-custom type of the default db
+			v, err := f.Build(mockCTX).Get()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if v.Custom == customType1 {
+				t.Fatalf("Custom should not be generated by the default db")
+			}
+
+			restore := switchDB(f, mdb)
+			v, err = f.Build(mockCTX).Get()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if v.Custom != customType1 {
+				t.Fatalf("Custom should be generated by the switched db, got %v", v.Custom)
+			}
+
+			// the cache of the default db is restored after switching back
+			restore()
+			if _, ok := f.nonCustomTypes[reflect.TypeOf(customType1)]; !ok {
+				t.Fatalf("cache of the default db should be restored")
+			}
+		})
+	}
+}
+
+// mockMinimalDB is the mock database implementing only the required methods,
+// e.g. the third-party adapters without GenCustomType, ConvertValue, Update, and DeleteList.
+type mockMinimalDB struct {
+	inserted []interface{}
+}
+
+// Insert inserts a value.
+func (m *mockMinimalDB) Insert(ctx context.Context, params db.InsertParams) (interface{}, error) {
+	m.inserted = append(m.inserted, params.Value)
+	return params.Value, nil
+}
+
+// InsertList inserts a list of values.
+func (m *mockMinimalDB) InsertList(ctx context.Context, params db.InsertListParams) ([]interface{}, error) {
+	m.inserted = append(m.inserted, params.Values...)
+	return params.Values, nil
+}
+
+func TestMinimalDB(t *testing.T) {
+	type testStructWithDBCustomType struct {
+		Name   string
+		Custom customType
+	}
+	mdb := &mockMinimalDB{}
+	f := New(testStructWithDBCustomType{}).WithDB(mdb)
+
+	v, err := f.Build(mockCTX).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if v.Name == "" || v.Custom != "" {
+		t.Fatalf("Name should be generated and Custom should be zero, got %v", v)
+	}
+	if len(mdb.inserted) != 1 {
+		t.Fatalf("inserted should be 1, got %v", len(mdb.inserted))
+	}
+
+	// the recorder forwards the optional methods only if the wrapped database supports them
+	rec := NewRecorder(mdb)
+	if _, ok := rec.GenCustomType(reflect.TypeOf(customType1)); ok {
+		t.Fatalf("GenCustomType should not be supported")
+	}
+	if _, ok := rec.ConvertValue(reflect.StructField{}, 1); ok {
+		t.Fatalf("ConvertValue should not be supported")
+	}
+	if err := rec.DeleteList(mockCTX, db.DeleteListParams{}); !errors.Is(err, errDeleteNotSupported) {
+		t.Fatalf("error should be %v, got %v", errDeleteNotSupported, err)
+	}
+
+	// the values inserted into the database which can't delete are kept for the later cleanup
+	if err := f.Cleanup(mockCTX); !errors.Is(err, errDeleteNotSupported) {
+		t.Fatalf("error should be %v, got %v", errDeleteNotSupported, err)
+	}
+	if len(f.inserted) != 1 {
+		t.Fatalf("inserted records should be kept, got %v", len(f.inserted))
+	}
+
+	fx, err := f.Build(mockCTX).InsertFixture()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := fx.Delete(mockCTX); !errors.Is(err, errDeleteNotSupported) {
+		t.Fatalf("error should be %v, got %v", errDeleteNotSupported, err)
+	}
+	if err := fx.Update(mockCTX, func(v *testStructWithDBCustomType) { v.Name = "updated" }); !errors.Is(err, errUpdateNotSupported) {
+		t.Fatalf("error should be %v, got %v", errUpdateNotSupported, err)
+	}
+	if fx.Get().Name == "updated" {
+		t.Fatalf("value should not be modified")
+	}
+	if err := rec.Update(mockCTX, db.UpdateParams{}); !errors.Is(err, errUpdateNotSupported) {
+		t.Fatalf("error should be %v, got %v", errUpdateNotSupported, err)
+	}
+}
+
+// testWideStruct is the struct with many fields of various kinds to test and benchmark the field plans
+type testWideStruct struct {
+	ID        int
+	Name      string
+	Email     string
+	Phone     string
+	Address   string
+	City      string
+	Country   string
+	Zip       string
+	Age       int
+	Score     int64
+	Rank      int32
+	Level     uint
+	Balance   float64
+	Rate      float32
+	Active    bool
+	Verified  bool
+	Nickname  *string
+	Bio       *string
+	Followers *int
+	Weight    *float64
+	Admin     *bool
+	Tags      []string
+	Scores    []int
+	Ratios    []float64
+	Aliases   *[]string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+	LoginAt   []time.Time
+	Custom    customType
+	Nested    testStruct
+	PtrNested *testStruct
+	Note1     string
+	Note2     string
+	Note3     string
+	Count1    int
+	Count2    int
+	Count3    int
+	Flag1     bool
+	Flag2     bool
+	internal  string
+}
+
+func TestTypePlanCache(t *testing.T) {
+	typ := reflect.TypeOf(testWideStruct{})
+	fieldPlanCache.Delete(typ)
+
+	f := New(testWideStruct{})
+	first, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	cached, ok := fieldPlanCache.Load(typ)
+	if !ok {
+		t.Fatalf("plan should be cached after building")
+	}
+
+	// the cached plan matches the plan computed by reflection
+	if want := newTypePlan(typ); !reflect.DeepEqual(cached, want) {
+		t.Fatalf("cached plan should be %v, got %v", want, cached)
+	}
+
+	for _, p := range cached.(typePlan).fields {
+		if want := fieldKindOf(typ.Field(p.index)); p.kind != want {
+			t.Fatalf("kind of %s should be %v, got %v", p.name, want, p.kind)
+		}
+	}
+
+	// the second build uses the cached plan, and fills the same fields
+	second, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	firstVal, secondVal := reflect.ValueOf(first), reflect.ValueOf(second)
+	for i := 0; i < typ.NumField(); i++ {
+		if firstVal.Field(i).IsZero() != secondVal.Field(i).IsZero() {
+			t.Fatalf("%s should be filled the same by both builds", typ.Field(i).Name)
+		}
+	}
+}
+
+func BenchmarkBuildList(b *testing.B) {
+	f := New(testWideStruct{})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.BuildList(mockCTX, 100).Get(); err != nil {
+			b.Fatalf("unexpected error %v", err)
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when reset, index should be 0":            reset_Index,
+		"when reset, associations should be empty": reset_Associations,
+		"when reset, keep the configuration error": reset_ConfigErr,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func reset_Index(t *testing.T) {
+	f := New(testStruct{})
+
+	_, err := f.BuildList(mockCTX, 5).Get()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	f.Reset()
+	if f.index != 1 {
+		t.Fatalf("index should be 1")
+	}
+}
+
+func reset_Associations(t *testing.T) {
+	f := New(testAssocStruct{}).WithDB(&mockDB{})
+
+	// explicitly set association and not insert to make the association not empty
+	f.Build(mockCTX).WithOne(&testStructWithID{})
+
+	f.Reset()
+	if len(f.associations) != 0 {
+		t.Fatalf("associations should be empty")
+	}
+}
+
+func reset_ConfigErr(t *testing.T) {
+	f := New(testExpectStruct{}).WithColumns("Unknown")
+
+	f.Reset()
+	if _, err := f.Build(mockCTX).Get(); !errors.Is(err, errFieldNotFound) {
+		t.Fatalf("error should be %v, but got %v", errFieldNotFound, err)
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when cleanup, delete in reverse insertion order":     cleanup_ReverseOrder,
+		"when cleanup with soft delete, mark as soft delete":  cleanup_SoftDelete,
+		"when cleanup without db, return error":               cleanup_WithoutDB,
+		"when cleanup twice, nothing to delete at the second": cleanup_Twice,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func cleanup_ReverseOrder(t *testing.T) {
+	mdb := &mockDB{}
+	f := New(testAssocStruct{}).WithDB(mdb)
+
+	if _, err := f.BuildList(mockCTX, 2).WithOne(&testStructWithID{}).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := f.Cleanup(mockCTX); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := []string{"test_assoc_structs", "test_struct_with_ids"}
+	if len(mdb.deleted) != len(want) {
+		t.Fatalf("deleted should be %v, got %v", len(want), len(mdb.deleted))
+	}
+	for i, d := range mdb.deleted {
+		if d.StorageName != want[i] {
+			t.Fatalf("deleted[%d] should be %s, got %s", i, want[i], d.StorageName)
+		}
+		if d.IsSoftDelete {
+			t.Fatalf("deleted[%d] should not be soft delete", i)
+		}
+	}
+
+	if len(mdb.deleted[0].Values) != 2 {
+		t.Fatalf("deleted values should be 2, got %v", len(mdb.deleted[0].Values))
+	}
+}
+
+func cleanup_SoftDelete(t *testing.T) {
+	mdb := &mockDB{}
+	f := New(testStructWithID{}).WithDB(mdb).WithIsSoftDelete(true)
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := f.Cleanup(mockCTX); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(mdb.deleted) != 1 || !mdb.deleted[0].IsSoftDelete {
+		t.Fatalf("should be soft deleted")
+	}
+}
+
+func cleanup_WithoutDB(t *testing.T) {
+	f := New(testStructWithID{})
+
+	if err := f.Cleanup(mockCTX); !errors.Is(err, errDBIsNotProvided) {
+		t.Fatalf("error should be %v", errDBIsNotProvided)
+	}
+}
+
+func cleanup_Twice(t *testing.T) {
+	mdb := &mockDB{}
+	f := New(testStructWithID{}).WithDB(mdb)
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := f.Cleanup(mockCTX); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := f.Cleanup(mockCTX); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(mdb.deleted) != 1 {
+		t.Fatalf("deleted should be 1, got %v", len(mdb.deleted))
+	}
+}
+
+func TestFixture(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when update fixture, value should be updated":           fixture_Update,
+		"when delete fixture, cleanup should not delete again":   fixture_Delete,
+		"when insert fixtures, return handle of each value":      fixture_InsertFixtures,
+		"when insert fixture without db, error should be return": fixture_WithoutDB,
+		"when insert transform is set, update transformed value": fixture_UpdateTransform,
+		"when columns are set, not update omitted fields":        fixture_UpdateColumns,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func fixture_Update(t *testing.T) {
+	mdb := &mockDB{}
+	f := New(testStructWithID3{}).WithDB(mdb)
+
+	fx, err := f.Build(mockCTX).InsertFixture()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := fx.Update(mockCTX, func(v *testStructWithID3) { v.Name = "updated" }); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if fx.Get().Name != "updated" {
+		t.Fatalf("Name should be updated, got %v", fx.Get().Name)
+	}
+	if len(mdb.updated) != 1 || mdb.updated[0].StorageName != "test_struct_with_id3s" {
+		t.Fatalf("Update should be called with the storage name")
+	}
+	if mdb.updated[0].Value.(*testStructWithID3).ID != fx.Get().ID {
+		t.Fatalf("Update should be called with the fixture value")
+	}
+}
+
+func fixture_Delete(t *testing.T) {
+	mdb := &mockDB{}
+	f := New(testStructWithID3{}).WithDB(mdb)
+
+	fx, err := f.Build(mockCTX).InsertFixture()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := fx.Delete(mockCTX); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := f.Cleanup(mockCTX); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(mdb.deleted) != 1 {
+		t.Fatalf("deleted should be 1, got %v", len(mdb.deleted))
+	}
+}
+
+func fixture_InsertFixtures(t *testing.T) {
+	f := New(testStructWithID3{}).WithDB(&mockDB{})
+
+	fxs, err := f.BuildList(mockCTX, 2).InsertFixtures()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(fxs) != 2 {
+		t.Fatalf("fixtures should be 2, got %v", len(fxs))
+	}
+	for _, fx := range fxs {
+		if fx.Get().ID == 0 {
+			t.Fatalf("ID should be set")
+		}
+	}
+}
+
+func fixture_WithoutDB(t *testing.T) {
+	f := New(testStructWithID3{})
+
+	if _, err := f.Build(mockCTX).InsertFixture(); !errors.Is(err, errDBIsNotProvided) {
+		t.Fatalf("error should be %v", errDBIsNotProvided)
+	}
+}
+
+func fixture_UpdateTransform(t *testing.T) {
+	mdb := &mockDB{}
+	f := New(testTransformStruct{}).WithDB(mdb).WithInsertTransform("SSN", reverseTransform)
+
+	fx, err := f.Build(mockCTX).InsertFixture()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := fx.Update(mockCTX, func(v *testTransformStruct) { v.SSN = "123-45" }); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if fx.Get().SSN != "123-45" {
+		t.Fatalf("SSN of fixture should be 123-45, got %v", fx.Get().SSN)
+	}
+
+	stored := mdb.updated[0].Value.(*testTransformStruct)
+	if stored.SSN != "54-321" || stored.ID != fx.Get().ID {
+		t.Fatalf("stored should have ID %v and reversed SSN, got %v", fx.Get().ID, stored)
+	}
+}
+
+func fixture_UpdateColumns(t *testing.T) {
+	mdb := &mockDB{}
+	f := New(testExpectStruct{}).WithDB(mdb).WithColumns("Email", "Age")
+
+	fx, err := f.Build(mockCTX).InsertFixture()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := fx.Update(mockCTX, func(v *testExpectStruct) { v.Age = 20 }); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := []string{"ID", "Nickname"}
+	if got := mdb.updated[0].OmitFields; !reflect.DeepEqual(got, want) {
+		t.Fatalf("OmitFields should be %v, got %v", want, got)
+	}
+}
+
+// mockTxDB is the mock database supporting transactions.
+type mockTxDB struct {
+	mockDB
+
+	// tx is the last transaction started by BeginTx
+	tx *mockTx
+}
+
+// BeginTx starts a transaction.
+func (m *mockTxDB) BeginTx(ctx context.Context) (db.Tx, error) {
+	m.tx = &mockTx{}
+	return m.tx, nil
+}
+
+// mockTx is the mock transaction.
+type mockTx struct {
+	mockDB
+
+	isCommitted  bool
+	isRolledBack bool
+}
+
+// Commit commits the transaction.
+func (m *mockTx) Commit(ctx context.Context) error {
+	m.isCommitted = true
+	return nil
+}
+
+// Rollback rolls back the transaction.
+func (m *mockTx) Rollback(ctx context.Context) error {
+	m.isRolledBack = true
+	return nil
+}
+
+func TestWithinTx(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when fn succeeds, transaction should be committed":    withinTx_Commit,
+		"when fn fails, transaction should be rolled back":     withinTx_Rollback,
+		"when fn panics, transaction should be rolled back":    withinTx_Panic,
+		"when db doesn't support tx, error should be returned": withinTx_NotSupported,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func withinTx_Commit(t *testing.T) {
+	mdb := &mockTxDB{}
+	f1 := New(testStructWithID3{}).WithDB(mdb)
+	f2 := New(testStructWithID{}).WithDB(mdb)
+
+	err := WithinTx(mockCTX, mdb, func(scope *TxScope) error {
+		scope.Bind(f1, f2)
+		if f1.db != mdb.tx || f2.db != mdb.tx {
+			t.Fatalf("factories should be bound to the transaction")
+		}
+
+		if _, err := f1.Build(mockCTX).Insert(); err != nil {
+			return err
+		}
+
+		_, err := f2.BuildList(mockCTX, 2).Insert()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if !mdb.tx.isCommitted || mdb.tx.isRolledBack {
+		t.Fatalf("transaction should be committed")
+	}
+	if f1.db != mdb || f2.db != mdb {
+		t.Fatalf("factories should be unbound after the scope")
+	}
+	if len(f1.inserted) != 1 || len(f2.inserted) != 1 {
+		t.Fatalf("inserted values should be tracked after commit")
+	}
+}
+
+func withinTx_Rollback(t *testing.T) {
+	mdb := &mockTxDB{}
+	f := New(testStructWithID3{}).WithDB(mdb)
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	wantErr := errors.New("fn error")
+	err := WithinTx(mockCTX, mdb, func(scope *TxScope) error {
+		scope.Bind(f)
+		if _, err := f.BuildList(mockCTX, 2).Insert(); err != nil {
+			return err
+		}
+
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("error should be %v, got %v", wantErr, err)
+	}
+
+	if !mdb.tx.isRolledBack || mdb.tx.isCommitted {
+		t.Fatalf("transaction should be rolled back")
+	}
+	if f.db != mdb {
+		t.Fatalf("factory should be unbound after the scope")
+	}
+	if len(f.inserted) != 1 {
+		t.Fatalf("values inserted within the rolled back transaction should be forgotten")
+	}
+}
+
+func withinTx_Panic(t *testing.T) {
+	mdb := &mockTxDB{}
+	f := New(testStructWithID3{}).WithDB(mdb)
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("panic should be propagated")
+		}
+
+		if !mdb.tx.isRolledBack {
+			t.Fatalf("transaction should be rolled back")
+		}
+		if f.db != mdb {
+			t.Fatalf("factory should be unbound after the scope")
+		}
+	}()
+
+	_ = WithinTx(mockCTX, mdb, func(scope *TxScope) error {
+		scope.Bind(f)
+		panic("boom")
+	})
+}
+
+func withinTx_NotSupported(t *testing.T) {
+	err := WithinTx(mockCTX, &mockDB{}, func(scope *TxScope) error {
+		t.Fatalf("fn should not be called")
+		return nil
+	})
+	if !errors.Is(err, errTxNotSupported) {
+		t.Fatalf("error should be %v", errTxNotSupported)
+	}
+}
+
+func TestUse(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when use middlewares, call in registration order":       use_Order,
+		"when middleware mutates values before insert, inserted": use_MutateBeforeInsert,
+		"when middleware returns error, return error":            use_Error,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func use_Order(t *testing.T) {
+	var calls []string
+	genMW := func(name string) Middleware {
+		return func(next BuildStep) BuildStep {
+			return func(ctx context.Context, s Step) error {
+				calls = append(calls, fmt.Sprintf("%s-before-%d", name, s.Kind))
+				err := next(ctx, s)
+				calls = append(calls, fmt.Sprintf("%s-after-%d", name, s.Kind))
+				return err
+			}
+		}
+	}
+
+	f := New(testStructWithID{}).WithDB(&mockDB{}).Use(genMW("a"), genMW("b"))
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := []string{
+		"a-before-0", "b-before-0", "b-after-0", "a-after-0",
+		"a-before-1", "b-before-1", "b-after-1", "a-after-1",
+	}
+	if err := testutils.CompareVal(calls, want); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func use_MutateBeforeInsert(t *testing.T) {
+	stamp := func(next BuildStep) BuildStep {
+		return func(ctx context.Context, s Step) error {
+			if s.Kind == StepInsert {
+				for _, v := range s.Values {
+					v.(*testStructWithID3).Name = "tenant"
+				}
+			}
+			return next(ctx, s)
+		}
+	}
+
+	f := New(testStructWithID3{}).WithDB(&mockDB{}).Use(stamp)
+	vals, err := f.BuildList(mockCTX, 2).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, v := range vals {
+		if v.Name != "tenant" {
+			t.Fatalf("Name should be tenant, got %v", v.Name)
+		}
+	}
+}
+
+func use_Error(t *testing.T) {
+	mockErr := errors.New("validation failed")
+	validate := func(next BuildStep) BuildStep {
+		return func(ctx context.Context, s Step) error {
+			if err := next(ctx, s); err != nil {
+				return err
+			}
+			return mockErr
+		}
+	}
+
+	f := New(testStructWithID{}).Use(validate)
+	if _, err := f.Build(mockCTX).Get(); !errors.Is(err, mockErr) {
+		t.Fatalf("error should be %v, got %v", mockErr, err)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when overwrite frozen field on builder, return error":       freeze_OverwriteOnBuilder,
+		"when overwrite non-frozen field on builder, overwrite":      freeze_OverwriteNonFrozen,
+		"when set trait mutating frozen field, return error":         freeze_SetTraitOnBuilder,
+		"when set zero on frozen field, return error":                freeze_SetZeroOnBuilder,
+		"when overwrites frozen field on builder list, return error": freeze_OverwritesOnBuilderList,
+		"when freeze not found field, return error":                  freeze_FieldNotFound,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func freeze_OverwriteOnBuilder(t *testing.T) {
+	f := New(testStruct{})
+
+	_, err := f.Build(mockCTX).Freeze("Str").Overwrite(testStruct{Str: "overwrite"}).Get()
+	if !errors.Is(err, errFieldFrozen) {
+		t.Fatalf("error should be %v, got %v", errFieldFrozen, err)
+	}
+}
+
+func freeze_OverwriteNonFrozen(t *testing.T) {
+	f := New(testStruct{})
+
+	val, err := f.Build(mockCTX).Freeze("Str").Overwrite(testStruct{Int: 100}).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Int != 100 {
+		t.Fatalf("Int should be 100, got %v", val.Int)
+	}
+}
+
+func freeze_SetTraitOnBuilder(t *testing.T) {
+	f := New(testStruct{}).WithTrait("str", func(v *testStruct) {
+		v.Str = "trait"
+	})
+
+	b := f.Build(mockCTX).Freeze("Str")
+	want := b.v.Str
+	b.SetTrait("str")
+	if !errors.Is(b.err, errFieldFrozen) {
+		t.Fatalf("error should be %v, got %v", errFieldFrozen, b.err)
+	}
+
+	if b.v.Str != want {
+		t.Fatalf("Str should be restored to %v, got %v", want, b.v.Str)
+	}
+}
+
+func freeze_SetZeroOnBuilder(t *testing.T) {
+	f := New(testStruct{})
+
+	_, err := f.Build(mockCTX).Freeze("Str").SetZero("Str").Get()
+	if !errors.Is(err, errFieldFrozen) {
+		t.Fatalf("error should be %v, got %v", errFieldFrozen, err)
+	}
+}
+
+func freeze_OverwritesOnBuilderList(t *testing.T) {
+	f := New(testStruct{})
+
+	_, err := f.BuildList(mockCTX, 2).Freeze("Int").Overwrites(testStruct{Str: "a"}, testStruct{Int: 1}).Get()
+	if !errors.Is(err, errFieldFrozen) {
+		t.Fatalf("error should be %v, got %v", errFieldFrozen, err)
+	}
+}
+
+func freeze_FieldNotFound(t *testing.T) {
+	f := New(testStruct{})
+
+	_, err := f.Build(mockCTX).Freeze("NotFound").Get()
+	if !errors.Is(err, errFieldNotFound) {
+		t.Fatalf("error should be %v, got %v", errFieldNotFound, err)
+	}
+}
+
+func TestRollbackOnFailure(t *testing.T) {
+	mockErr := errors.New("insert failed")
+	mdb := &mockDB{insertListErrs: map[string]error{"test_assoc_structs": mockErr}}
+	f := New(testAssocStruct{}).WithDB(mdb)
+
+	assVal, assVal2, assVal3 := testStructWithID{}, testStructWithID2{}, testStructWithID3{}
+	if _, err := f.Build(mockCTX).WithOne(&assVal, &assVal2, &assVal3).Insert(); !errors.Is(err, mockErr) {
+		t.Fatalf("error should be %v, got %v", mockErr, err)
+	}
+
+	// the associations inserted before the failure should be deleted in reverse order
+	if len(mdb.deleted) != 3 {
+		t.Fatalf("deleted should be 3, got %v", len(mdb.deleted))
+	}
+	pos := map[string]int{}
+	for i, d := range mdb.deleted {
+		pos[d.StorageName] = i
+	}
+	if pos["test_struct_with_id2s"] > pos["test_struct_with_id3s"] {
+		t.Fatalf("test_struct_with_id2s should be deleted before test_struct_with_id3s")
+	}
+
+	if len(f.inserted) != 0 {
+		t.Fatalf("inserted should be empty, got %v", len(f.inserted))
+	}
+}
+
+func TestWithAnonymizeProfile(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when generated values are safe, set marker field":     anonymize_SetMarker,
+		"when blueprint value resembles PII, return error":     anonymize_BlueprintPII,
+		"when list value resembles PII, return error":          anonymize_BuildListPII,
+		"when custom deny list is provided, use the deny list": anonymize_CustomDenyList,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testAnonymizeStruct struct {
+	ID         int
+	Email      string
+	Phone      *string
+	IsTestData bool
+}
+
+func anonymize_SetMarker(t *testing.T) {
+	f := New(testAnonymizeStruct{}).WithAnonymizeProfile(AnonymizeProfile{MarkerField: "IsTestData"})
+
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if !val.IsTestData {
+		t.Fatalf("IsTestData should be true")
+	}
+}
+
+func anonymize_BlueprintPII(t *testing.T) {
+	bp := func(i int) testAnonymizeStruct {
+		return testAnonymizeStruct{Email: "john.doe@example.com"}
+	}
+	f := New(testAnonymizeStruct{}).WithBlueprint(bp).WithAnonymizeProfile(AnonymizeProfile{})
+
+	if _, err := f.Build(mockCTX).Get(); !errors.Is(err, errValueResemblesPII) {
+		t.Fatalf("error should be %v, got %v", errValueResemblesPII, err)
+	}
+}
+
+func anonymize_BuildListPII(t *testing.T) {
+	bp := func(i int) testAnonymizeStruct {
+		if i == 2 {
+			phone := "+1 415-555-2671"
+			return testAnonymizeStruct{Phone: &phone}
+		}
+		return testAnonymizeStruct{}
+	}
+	f := New(testAnonymizeStruct{}).WithBlueprint(bp).WithAnonymizeProfile(AnonymizeProfile{})
+
+	if _, err := f.BuildList(mockCTX, 3).Get(); !errors.Is(err, errValueResemblesPII) {
+		t.Fatalf("error should be %v, got %v", errValueResemblesPII, err)
+	}
+}
+
+func anonymize_CustomDenyList(t *testing.T) {
+	f := New(testAnonymizeStruct{}).WithAnonymizeProfile(AnonymizeProfile{
+		DenyList: []*regexp.Regexp{regexp.MustCompile(`^test\d+$`)},
+	})
+
+	if _, err := f.Build(mockCTX).Get(); !errors.Is(err, errValueResemblesPII) {
+		t.Fatalf("error should be %v, got %v", errValueResemblesPII, err)
+	}
+}
+
+func TestWithIsSetSeqID(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when set seq id, ID should be sequential":          seqID_Sequential,
+		"when set seq id with db, ID should not be set":     seqID_WithDB,
+		"when reset, ID should start from 1 again":          seqID_Reset,
+		"when not set seq id, ID should be zero by default": seqID_Default,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func seqID_Sequential(t *testing.T) {
+	f := New(testStructWithID{}).WithIsSetSeqID(true)
+
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if val.ID != 1 {
+		t.Fatalf("ID should be 1, got %v", val.ID)
+	}
+
+	vals, err := f.BuildList(mockCTX, 2).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for i, v := range vals {
+		if v.ID != i+2 {
+			t.Fatalf("ID should be %v, got %v", i+2, v.ID)
+		}
+	}
+}
+
+func seqID_WithDB(t *testing.T) {
+	f := New(testStructWithID{}).WithIsSetSeqID(true).WithDB(&mockDB{})
+
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if val.ID != 0 {
+		t.Fatalf("ID should be 0, got %v", val.ID)
+	}
+}
+
+func seqID_Reset(t *testing.T) {
+	f := New(testStructWithID{}).WithIsSetSeqID(true)
+
+	if _, err := f.BuildList(mockCTX, 3).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	f.Reset()
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if val.ID != 1 {
+		t.Fatalf("ID should be 1, got %v", val.ID)
+	}
+}
+
+func seqID_Default(t *testing.T) {
+	f := New(testStructWithID{})
+
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if val.ID != 0 {
+		t.Fatalf("ID should be 0, got %v", val.ID)
+	}
+}
+
+func TestWithIsLocalIndex(t *testing.T) {
+	f := New(testStructWithID3{}).WithIsLocalIndex(true).WithIsSetSeqID(true)
+
+	first, err := f.BuildList(mockCTX, 2).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	second, err := f.BuildList(mockCTX, 2).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := testutils.CompareVal(first, second); err != nil {
+		t.Fatalf("values should be independent of the previous build: %v", err)
+	}
+	for i, v := range second {
+		if v.ID != i+1 {
+			t.Fatalf("ID should be %v, got %v", i+1, v.ID)
+		}
+		if want := fmt.Sprintf("test%d", i+1); v.Name != want {
+			t.Fatalf("Name should be %v, got %v", want, v.Name)
+		}
+	}
+
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if val.ID != 1 || val.Name != "test1" {
+		t.Fatalf("single build should start from 1, got %v", val)
+	}
+}
+
+func TestWithStringFormat(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when placeholders are set, replace with struct, field, and index": stringFormat_Placeholders,
+		"when field is pointer or nested, apply the format":                stringFormat_PtrAndNested,
+		"when format is empty, use default format":                         stringFormat_Empty,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testStringFormatNested struct {
+	Title string
+}
+
+type testStringFormatStruct struct {
+	ID     int
+	Name   string
+	PtrStr *string
+	Int    int
+	Nested testStringFormatNested
+}
+
+func stringFormat_Placeholders(t *testing.T) {
+	vals, err := New(testStructWithID3{}).WithStringFormat("{struct}-{field}-{i}").BuildList(mockCTX, 2).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for i, v := range vals {
+		want := fmt.Sprintf("testStructWithID3-Name-%d", i+1)
+		if v.Name != want {
+			t.Fatalf("Name should be %v, got %v", want, v.Name)
+		}
+	}
+}
+
+func stringFormat_PtrAndNested(t *testing.T) {
+	val, err := New(testStringFormatStruct{}).WithStringFormat("user-{field}-{i}").Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	str := "user-PtrStr-1"
+	want := testStringFormatStruct{
+		Name:   "user-Name-1",
+		PtrStr: &str,
+		Int:    1,
+		Nested: testStringFormatNested{Title: "user-Title-1"},
+	}
+	if err := testutils.CompareVal(val, want); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func stringFormat_Empty(t *testing.T) {
+	val, err := New(testStructWithID3{}).WithStringFormat("").Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Name != "test1" {
+		t.Fatalf("Name should be test1, got %v", val.Name)
+	}
+}
+
+func TestWithConstraint(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when constraint is satisfied, return value":                     constraint_Satisfied,
+		"when constraint is satisfied after re-generation, return value": constraint_Regenerate,
+		"when constraint is never satisfied, return error":               constraint_Violated,
+		"when constraint is invalid, return error without re-generation": constraint_Invalid,
+		"when after constraint is set, check the time fields":            constraint_After,
+		"when sum constraint is set, check the numeric fields":           constraint_Sum,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testConstraintStruct struct {
+	ID      int
+	StartAt time.Time
+	EndAt   *time.Time
+	Parts   []int
+	Total   float64
+	Name    string
+}
+
+func constraint_Satisfied(t *testing.T) {
+	vals, err := New(testConstraintStruct{}).
+		WithConstraint(func(v *testConstraintStruct) error {
+			if v.Name == "" {
+				return errors.New("empty name")
+			}
+			return nil
+		}).
+		BuildList(mockCTX, 2).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(vals) != 2 {
+		t.Fatalf("length should be 2, got %v", len(vals))
+	}
+}
+
+func constraint_Regenerate(t *testing.T) {
+	calls := 0
+	f := New(testConstraintStruct{}).
+		WithBlueprint(func(i int) testConstraintStruct {
+			calls++
+			return testConstraintStruct{Total: float64(calls)}
+		}).
+		WithConstraint(func(v *testConstraintStruct) error {
+			if v.Total < 3 {
+				return errors.New("too small")
+			}
+			return nil
+		})
+
+	b := f.Build(mockCTX)
+	val, err := b.Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Total != 3 {
+		t.Fatalf("Total should be 3, got %v", val.Total)
+	}
+
+	if b.Meta().Index != 1 || f.index != 2 {
+		t.Fatalf("index should not be advanced by re-generation, got %v and %v", b.Meta().Index, f.index)
+	}
+}
+
+func constraint_Violated(t *testing.T) {
+	errViolated := errors.New("always violated")
+	calls := 0
+	_, err := New(testConstraintStruct{}).
+		WithConstraint(func(v *testConstraintStruct) error {
+			calls++
+			return errViolated
+		}).
+		Build(mockCTX).Get()
+	if !errors.Is(err, errViolated) {
+		t.Fatalf("error should be %v, got %v", errViolated, err)
+	}
+
+	if calls != maxConstraintAttempts {
+		t.Fatalf("constraint should be checked %v times, got %v", maxConstraintAttempts, calls)
+	}
+}
+
+func constraint_Invalid(t *testing.T) {
+	_, err := New(testConstraintStruct{}).
+		WithConstraint(After[testConstraintStruct]("StartAt", "Name")).
+		Build(mockCTX).Get()
+	if !errors.Is(err, errInvalidConstraint) {
+		t.Fatalf("error should be %v, got %v", errInvalidConstraint, err)
+	}
+
+	_, err = New(testConstraintStruct{}).
+		WithConstraint(Sum[testConstraintStruct]("Unknown", "Total")).
+		Build(mockCTX).Get()
+	if !errors.Is(err, errFieldNotFound) {
+		t.Fatalf("error should be %v, got %v", errFieldNotFound, err)
+	}
+}
+
+func constraint_After(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+	after := After[testConstraintStruct]("StartAt", "EndAt")
+
+	if err := after(&testConstraintStruct{StartAt: now, EndAt: &later}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, v := range []testConstraintStruct{
+		{StartAt: now, EndAt: &earlier},
+		{StartAt: now, EndAt: &now},
+		{StartAt: now},
+	} {
+		if err := after(&v); !errors.Is(err, errConstraintViolated) {
+			t.Fatalf("error should be %v, got %v", errConstraintViolated, err)
+		}
+	}
+}
+
+func constraint_Sum(t *testing.T) {
+	sum := Sum[testConstraintStruct]("Parts", "Total")
+
+	if err := sum(&testConstraintStruct{Parts: []int{1, 2, 3}, Total: 6}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := sum(&testConstraintStruct{Parts: []int{1, 2, 3}, Total: 5}); !errors.Is(err, errConstraintViolated) {
+		t.Fatalf("error should be %v, got %v", errConstraintViolated, err)
+	}
+
+	if err := sum(&testConstraintStruct{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// the generated values satisfy the constraint, Parts is [i] and Total is i
+	if _, err := New(testConstraintStruct{}).WithConstraint(sum).BuildList(mockCTX, 3).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestDefine(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when field is defined, generate the field by the generator":    define_Field,
+		"when belongs to is defined, insert parent and set foreign key": define_BelongsTo,
+		"when foreign key is set, not insert parent":                    define_BelongsToFKSet,
+		"when field not found, return error":                            define_FieldNotFound,
+		"when generated value has different type, return error":         define_TypeDiff,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testDefineStatus string
+
+type testDefineUser struct {
+	ID   int
+	Name string
+}
+
+type testDefineOrder struct {
+	ID     int
+	Status testDefineStatus
+	UserID int
+	User   *testDefineUser
+	Note   string
+}
+
+func define_Field(t *testing.T) {
+	vals, err := Define[testDefineOrder]().
+		Field("Status", Choice("new", "paid")).
+		Build().
+		BuildList(mockCTX, 3).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for i, want := range []testDefineStatus{"new", "paid", "new"} {
+		if vals[i].Status != want {
+			t.Fatalf("Status should be %v, got %v", want, vals[i].Status)
+		}
+
+		if vals[i].Note == "" {
+			t.Fatalf("Note should be populated with non-zero value")
+		}
+	}
+}
+
+func define_BelongsTo(t *testing.T) {
+	d := mockf.NewConfig()
+	userF := New(testDefineUser{}).WithDB(d)
+	f := Define[testDefineOrder]().
+		BelongsTo("User", userF).
+		Build().
+		WithDB(d)
+
+	vals, err := f.BuildList(mockCTX, 2).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	users := d.Inserted("test_define_users")
+	if len(users) != 2 {
+		t.Fatalf("a user should be inserted for each order, got %v", len(users))
+	}
+
+	for i, v := range vals {
+		user := users[i].(*testDefineUser)
+		if v.UserID != user.ID {
+			t.Fatalf("UserID should be %v, got %v", user.ID, v.UserID)
+		}
+
+		if v.User == nil || *v.User != *user {
+			t.Fatalf("User should be %v, got %v", user, v.User)
+		}
+	}
+}
+
+func define_BelongsToFKSet(t *testing.T) {
+	d := mockf.NewConfig()
+	userF := New(testDefineUser{}).WithDB(d)
+	f := Define[testDefineOrder]().
+		BelongsTo("User", userF).
+		Build().
+		WithDB(d)
+
+	val, err := f.Build(mockCTX).Overwrite(testDefineOrder{UserID: 100}).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.UserID != 100 {
+		t.Fatalf("UserID should be 100, got %v", val.UserID)
+	}
+
+	if users := d.Inserted("test_define_users"); len(users) != 0 {
+		t.Fatalf("user should not be inserted, got %v", len(users))
+	}
+}
+
+func define_FieldNotFound(t *testing.T) {
+	f := Define[testDefineOrder]().Field("Unknown", Choice(1)).Build()
+	if _, err := f.Build(mockCTX).Get(); !errors.Is(err, errFieldNotFound) {
+		t.Fatalf("error should be %v, got %v", errFieldNotFound, err)
+	}
+
+	f = Define[testDefineOrder]().BelongsTo("Customer", New(testDefineUser{})).Build().WithDB(&mockDB{})
+	if _, err := f.Build(mockCTX).Insert(); !errors.Is(err, errFieldNotFound) {
+		t.Fatalf("error should be %v, got %v", errFieldNotFound, err)
+	}
+}
+
+func define_TypeDiff(t *testing.T) {
+	_, err := Define[testDefineOrder]().
+		Field("Status", Choice(1)).
+		Build().
+		Build(mockCTX).Get()
+	if !errors.Is(err, errTypeDiff) {
+		t.Fatalf("error should be %v, got %v", errTypeDiff, err)
+	}
+}
+
+func TestWithSequence(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when factories share sequence, indexes are unique":              sequence_SharedAcrossFactories,
+		"when block is used up, reserve new block":                       sequence_NewBlock,
+		"when sequence fails, return error":                              sequence_Error,
+		"when file sequence is reserved concurrently, ranges are unique": sequence_FileConcurrent,
+		"when file is locked, wait until context is done":                sequence_FileLocked,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+// mockSequence is the sequence returning the given error
+type mockSequence struct {
+	err error
+}
+
+func (s *mockSequence) Reserve(ctx context.Context, n int) (int, error) {
+	return 0, s.err
+}
+
+func sequence_SharedAcrossFactories(t *testing.T) {
+	seq := NewFileSequence(filepath.Join(t.TempDir(), "seq"))
+	f1 := New(testStructWithID3{}).WithSequence(seq)
+	f2 := New(testStructWithID3{}).WithSequence(seq)
+
+	v1, err := f1.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	v2, err := f2.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if v1.Name != "test1" {
+		t.Fatalf("Name should be test1, got %v", v1.Name)
+	}
+
+	if want := fmt.Sprintf("test%d", 1+sequenceBlockSize); v2.Name != want {
+		t.Fatalf("Name should be %v, got %v", want, v2.Name)
+	}
+}
+
+func sequence_NewBlock(t *testing.T) {
+	seq := NewFileSequence(filepath.Join(t.TempDir(), "seq"))
+	f1 := New(testStructWithID3{}).WithSequence(seq)
+	f2 := New(testStructWithID3{}).WithSequence(seq)
+
+	if _, err := f1.BuildList(mockCTX, 1).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// f2 reserves the 2nd block, so the 3rd block is reserved when the 1st block of f1 is used up
+	if _, err := f2.Build(mockCTX).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	b := f1.BuildList(mockCTX, sequenceBlockSize)
+	if _, err := b.Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	metas := b.Meta()
+	if metas[0].Index != 2 {
+		t.Fatalf("index should be 2, got %v", metas[0].Index)
+	}
+
+	if want := 2*sequenceBlockSize + 1; metas[len(metas)-1].Index != want {
+		t.Fatalf("index should be %v, got %v", want, metas[len(metas)-1].Index)
+	}
+}
+
+func sequence_Error(t *testing.T) {
+	errSeq := errors.New("sequence error")
+	_, err := New(testStructWithID3{}).WithSequence(&mockSequence{err: errSeq}).Build(mockCTX).Get()
+	if !errors.Is(err, errSeq) {
+		t.Fatalf("error should be %v, got %v", errSeq, err)
+	}
+}
+
+func sequence_FileConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seq")
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	starts := map[int]bool{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start, err := NewFileSequence(path).Reserve(mockCTX, 5)
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+				return
+			}
+
+			mu.Lock()
+			starts[start] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < 10; i++ {
+		if !starts[1+i*5] {
+			t.Fatalf("range starting at %v should be reserved, got %v", 1+i*5, starts)
+		}
+	}
+}
+
+func sequence_FileLocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seq")
+	if err := os.WriteFile(path+".lock", nil, 0o644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(mockCTX, 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := NewFileSequence(path).Reserve(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("error should be %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when fields are given, return only the fields":    patch_Fields,
+		"when field has json tag, use the json tag as key": patch_JSONTag,
+		"when field not found, return error":               patch_FieldNotFound,
+		"when builder has error, return error":             patch_BuilderErr,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testPatchStruct struct {
+	ID      int
+	Name    string `json:"name"`
+	Email   string `json:"email,omitempty"`
+	Age     int    `json:"-"`
+	private string
+}
+
+func patch_Fields(t *testing.T) {
+	b := New(testStructWithID3{}).Build(mockCTX)
+	patch, err := b.Patch("Name")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	val, _ := b.Get()
+	want := map[string]interface{}{"Name": val.Name}
+	if !reflect.DeepEqual(patch, want) {
+		t.Fatalf("patch should be %v, got %v", want, patch)
+	}
+}
+
+func patch_JSONTag(t *testing.T) {
+	b := New(testPatchStruct{}).Build(mockCTX).Overwrite(testPatchStruct{Name: "name", Email: "a@b.com", Age: 10})
+	patch, err := b.Patch("Name", "Email", "Age")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := map[string]interface{}{"name": "name", "email": "a@b.com", "Age": 10}
+	if !reflect.DeepEqual(patch, want) {
+		t.Fatalf("patch should be %v, got %v", want, patch)
+	}
+}
+
+func patch_FieldNotFound(t *testing.T) {
+	b := New(testPatchStruct{}).Build(mockCTX)
+	for _, field := range []string{"Unknown", "private"} {
+		if _, err := b.Patch(field); !errors.Is(err, errFieldNotFound) {
+			t.Fatalf("error should be %v, got %v", errFieldNotFound, err)
+		}
+	}
+}
+
+func patch_BuilderErr(t *testing.T) {
+	b := New(testPatchStruct{}).Build(mockCTX).SetTrait("unknown")
+	if _, err := b.Patch("Name"); !errors.Is(err, errWithTraitNameNotFound) {
+		t.Fatalf("error should be %v, got %v", errWithTraitNameNotFound, err)
+	}
+}
+
+func TestForeignKeys(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when with many, set all the IDs":                    foreignKeys_WithMany,
+		"when build list with many, set all the IDs to each": foreignKeys_BuildList,
+		"when no association, keep the generated value":      foreignKeys_NoAssoc,
+		"when field is not slice, return error":              foreignKeys_NotSlice,
+		"when replay, remap all the IDs":                     foreignKeys_Replay,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testFKTag struct {
+	ID   int
+	Name string
+}
+
+type testFKTagged struct {
+	ID     int
+	TagIDs []int64 `gofacto:"foreignKeys,struct:testFKTag,field:Tags"`
+	Tags   []*testFKTag
+	Name   string
+}
+
+type testFKInvalid struct {
+	ID    int
+	TagID int `gofacto:"foreignKeys,struct:testFKTag"`
+}
+
+func foreignKeys_WithMany(t *testing.T) {
+	tag1, tag2, tag3 := testFKTag{}, testFKTag{}, testFKTag{}
+	val, err := New(testFKTagged{}).WithDB(&mockDB{}).
+		Build(mockCTX).
+		WithMany([]interface{}{&tag1, &tag2, &tag3}).
+		Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	wantIDs := []int64{int64(tag1.ID), int64(tag2.ID), int64(tag3.ID)}
+	if !reflect.DeepEqual(val.TagIDs, wantIDs) {
+		t.Fatalf("TagIDs should be %v, got %v", wantIDs, val.TagIDs)
+	}
+
+	if len(val.Tags) != 3 {
+		t.Fatalf("Tags should have 3 values, got %v", len(val.Tags))
+	}
+	for i, tag := range []testFKTag{tag1, tag2, tag3} {
+		if *val.Tags[i] != tag {
+			t.Fatalf("Tags[%d] should be %v, got %v", i, tag, *val.Tags[i])
+		}
+	}
+}
+
+func foreignKeys_BuildList(t *testing.T) {
+	tag1, tag2 := testFKTag{}, testFKTag{}
+	vals, err := New(testFKTagged{}).WithDB(&mockDB{}).
+		BuildList(mockCTX, 2).
+		WithMany([]interface{}{&tag1, &tag2}).
+		Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	wantIDs := []int64{int64(tag1.ID), int64(tag2.ID)}
+	for _, val := range vals {
+		if !reflect.DeepEqual(val.TagIDs, wantIDs) {
+			t.Fatalf("TagIDs should be %v, got %v", wantIDs, val.TagIDs)
+		}
+	}
+}
+
+func foreignKeys_NoAssoc(t *testing.T) {
+	val, err := New(testFKTagged{}).WithDB(&mockDB{}).Build(mockCTX).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(val.TagIDs) != 1 {
+		t.Fatalf("TagIDs should be the generated value, got %v", val.TagIDs)
+	}
+}
+
+func foreignKeys_NotSlice(t *testing.T) {
+	f := New(testFKInvalid{})
+	if !errors.Is(f.configErr, errTagFormat) {
+		t.Fatalf("error should be %v, got %v", errTagFormat, f.configErr)
+	}
+}
+
+func foreignKeys_Replay(t *testing.T) {
+	rec := NewRecorder(&mockDB{})
+	if _, err := New(testFKTagged{}).WithDB(rec).
+		Build(mockCTX).
+		WithMany([]interface{}{&testFKTag{}, &testFKTag{}}).
+		Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := rec.Save(&buf); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	target := mockf.NewConfig()
+	if err := Replay(mockCTX, target, &buf, testFKTagged{}, testFKTag{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	tagged := target.Inserted("test_fktaggeds")
+	if len(tagged) != 1 {
+		t.Fatalf("value should be replayed, got %v", len(tagged))
+	}
+
+	want := []int64{1, 2}
+	if got := tagged[0].(*testFKTagged).TagIDs; !reflect.DeepEqual(got, want) {
+		t.Fatalf("TagIDs should be %v, got %v", want, got)
+	}
+}
+
+func TestPointerResults(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when get pointer, return the built value":                getP_Build,
+		"when get pointer of list, return the built values":       getP_BuildList,
+		"when insert pointer, return the inserted value":          insertP_Build,
+		"when insert pointer of list, return the inserted values": insertP_BuildList,
+		"when builder has error, return error":                    insertP_Err,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func getP_Build(t *testing.T) {
+	b := New(testStructWithID3{}).Build(mockCTX)
+	p, err := b.GetP()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	p.Name = "mutated"
+	v, _ := b.Get()
+	if v.Name != "mutated" {
+		t.Fatalf("Name should be mutated, got %v", v.Name)
+	}
+}
+
+func getP_BuildList(t *testing.T) {
+	b := New(testStructWithID3{}).BuildList(mockCTX, 2)
+	ps, err := b.GetP()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	vals, _ := b.Get()
+	for i, p := range ps {
+		if *p != vals[i] {
+			t.Fatalf("value should be %v, got %v", vals[i], *p)
+		}
+	}
+}
+
+func insertP_Build(t *testing.T) {
+	p, err := New(testStructWithID3{}).WithDB(&mockDB{}).Build(mockCTX).InsertP()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if p.ID == 0 || p.Name != "test1" {
+		t.Fatalf("value should be inserted, got %v", *p)
+	}
+
+	assVal := testStructWithID3{}
+	p2, err := New(testStructWithID2{}).WithDB(&mockDB{}).Build(mockCTX).WithOne(&assVal).InsertP()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if p2.ID == 0 || p2.ForeignKey != assVal.ID {
+		t.Fatalf("value should be inserted with association, got %v", *p2)
+	}
+}
+
+func insertP_BuildList(t *testing.T) {
+	ps, err := New(testStructWithID3{}).WithDB(&mockDB{}).BuildList(mockCTX, 2).InsertP()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for i, p := range ps {
+		if want := fmt.Sprintf("test%d", i+1); p.ID == 0 || p.Name != want {
+			t.Fatalf("value should be inserted, got %v", *p)
+		}
+	}
+}
+
+func insertP_Err(t *testing.T) {
+	if _, err := New(testStructWithID3{}).Build(mockCTX).InsertP(); !errors.Is(err, errDBIsNotProvided) {
+		t.Fatalf("error should be %v, got %v", errDBIsNotProvided, err)
+	}
+
+	if _, err := New(testStructWithID3{}).BuildList(mockCTX, 0).GetP(); !errors.Is(err, errBuildListNGreaterThanZero) {
+		t.Fatalf("error should be %v, got %v", errBuildListNGreaterThanZero, err)
+	}
+}
+
+func TestWithNamedDB(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when use named db, insert into the named db":        namedDB_UseDB,
+		"when cleanup, delete from the db inserted into":     namedDB_Cleanup,
+		"when fixture is updated, update in the db inserted": namedDB_Fixture,
+		"when named db not found, return error":              namedDB_NotFound,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func namedDB_UseDB(t *testing.T) {
+	primary, analytics := mockf.NewConfig(), mockf.NewConfig()
+	f := New(testStructWithID3{}).WithDB(primary).WithNamedDB("analytics", analytics)
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if _, err := f.BuildList(mockCTX, 2).UseDB("analytics").Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if n := len(primary.Inserted("test_struct_with_id3s")); n != 1 {
+		t.Fatalf("1 value should be inserted into default db, got %v", n)
+	}
+
+	if n := len(analytics.Inserted("test_struct_with_id3s")); n != 2 {
+		t.Fatalf("2 values should be inserted into named db, got %v", n)
+	}
+
+	if f.db != primary {
+		t.Fatalf("default db should be restored")
+	}
+}
+
+func namedDB_Cleanup(t *testing.T) {
+	primary, analytics := mockf.NewConfig(), mockf.NewConfig()
+	f := New(testStructWithID3{}).WithDB(primary).WithNamedDB("analytics", analytics)
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if _, err := f.Build(mockCTX).UseDB("analytics").Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := f.Cleanup(mockCTX); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if n := len(primary.Deleted("test_struct_with_id3s")); n != 1 {
+		t.Fatalf("1 value should be deleted from default db, got %v", n)
+	}
+
+	if n := len(analytics.Deleted("test_struct_with_id3s")); n != 1 {
+		t.Fatalf("1 value should be deleted from named db, got %v", n)
+	}
+}
+
+func namedDB_Fixture(t *testing.T) {
+	primary, analytics := mockf.NewConfig(), mockf.NewConfig()
+	f := New(testStructWithID3{}).WithDB(primary).WithNamedDB("analytics", analytics)
+
+	fx, err := f.Build(mockCTX).UseDB("analytics").InsertFixture()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := fx.Update(mockCTX, func(v *testStructWithID3) { v.Name = "updated" }); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if n := len(primary.Updated("test_struct_with_id3s")); n != 0 {
+		t.Fatalf("value should not be updated in default db, got %v", n)
+	}
+
+	if n := len(analytics.Updated("test_struct_with_id3s")); n != 1 {
+		t.Fatalf("value should be updated in named db, got %v", n)
+	}
+}
+
+func namedDB_NotFound(t *testing.T) {
+	f := New(testStructWithID3{}).WithDB(mockf.NewConfig())
+
+	if _, err := f.Build(mockCTX).UseDB("unknown").Insert(); !errors.Is(err, errDBNotFound) {
+		t.Fatalf("error should be %v, got %v", errDBNotFound, err)
+	}
+
+	if _, err := f.BuildList(mockCTX, 2).UseDB("unknown").Insert(); !errors.Is(err, errDBNotFound) {
+		t.Fatalf("error should be %v, got %v", errDBNotFound, err)
+	}
+}
+
+func TestBuildEdgeCase(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when max, generate the maximum values":                  edgeCase_Max,
+		"when min, generate the minimum values":                  edgeCase_Min,
+		"when empty, generate the empty values":                  edgeCase_Empty,
+		"when unicode, generate the unicode strings only":        edgeCase_Unicode,
+		"when blueprint and foreign keys are set, keep them":     edgeCase_KeepConstraints,
+		"when build after edge case, generate the normal values": edgeCase_Reset,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testEdgeCaseStruct struct {
+	ID      int
+	Int8    int8
+	Int64   int64
+	Uint16  uint16
+	Float32 float32
+	Bool    bool
+	Str     string
+	PtrInt  *int
+	Time    time.Time
+	PtrTime *time.Time
+}
+
+func edgeCase_Max(t *testing.T) {
+	val, err := New(testEdgeCaseStruct{}).BuildEdgeCase(mockCTX, EdgeCaseMax).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	maxInt := math.MaxInt
+	want := testEdgeCaseStruct{
+		Int8:    math.MaxInt8,
+		Int64:   math.MaxInt64,
+		Uint16:  math.MaxUint16,
+		Float32: math.MaxFloat32,
+		Bool:    true,
+		Str:     strings.Repeat("x", edgeCaseLongStringLen),
+		PtrInt:  &maxInt,
+		Time:    edgeCaseMaxTime,
+		PtrTime: &edgeCaseMaxTime,
+	}
+	if err := testutils.CompareVal(val, want); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func edgeCase_Min(t *testing.T) {
+	val, err := New(testEdgeCaseStruct{}).BuildEdgeCase(mockCTX, EdgeCaseMin).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	minInt := math.MinInt
+	want := testEdgeCaseStruct{
+		Int8:    math.MinInt8,
+		Int64:   math.MinInt64,
+		Float32: -math.MaxFloat32,
+		Str:     "x",
+		PtrInt:  &minInt,
+		Time:    edgeCaseMinTime,
+		PtrTime: &edgeCaseMinTime,
+	}
+	if err := testutils.CompareVal(val, want); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func edgeCase_Empty(t *testing.T) {
+	val, err := New(testEdgeCaseStruct{}).BuildEdgeCase(mockCTX, EdgeCaseEmpty).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	zeroInt, zeroTime := 0, time.Time{}
+	want := testEdgeCaseStruct{PtrInt: &zeroInt, PtrTime: &zeroTime}
+	if err := testutils.CompareVal(val, want); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func edgeCase_Unicode(t *testing.T) {
+	val, err := New(testEdgeCaseStruct{}).BuildEdgeCase(mockCTX, EdgeCaseUnicode).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Str != edgeCaseUnicodeString {
+		t.Fatalf("Str should be %q, got %q", edgeCaseUnicodeString, val.Str)
+	}
+
+	if val.Int64 != 1 || val.Time.IsZero() {
+		t.Fatalf("other fields should be generated as usual, got %v", val)
+	}
+}
+
+func edgeCase_KeepConstraints(t *testing.T) {
+	assVal := testStructWithID3{}
+	val, err := New(testStructWithID2{}).WithDB(&mockDB{}).
+		WithBlueprint(func(i int) testStructWithID2 {
+			return testStructWithID2{Name: "required"}
+		}).
+		BuildEdgeCase(mockCTX, EdgeCaseMax).
+		WithOne(&assVal).
+		Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Name != "required" {
+		t.Fatalf("Name should be kept, got %v", val.Name)
+	}
+
+	if val.ForeignKey != assVal.ID {
+		t.Fatalf("ForeignKey should be %v, got %v", assVal.ID, val.ForeignKey)
+	}
+}
+
+func edgeCase_Reset(t *testing.T) {
+	f := New(testStructWithID3{})
+	if _, err := f.BuildEdgeCase(mockCTX, EdgeCaseMax).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Name != "test2" {
+		t.Fatalf("Name should be test2, got %v", val.Name)
+	}
+}
+
+func TestCombinations(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when combinations, build all the combinations":        combinations_All,
+		"when vary traits, apply each trait":                   combinations_Traits,
+		"when insert, insert all the combinations":             combinations_Insert,
+		"when pairwise, cover all the pairs with fewer values": combinations_Pairwise,
+		"when pairwise with two variations, build all":         combinations_PairwiseTwo,
+		"when no variation, return error":                      combinations_NoVariation,
+		"when no choice, return error":                         combinations_NoChoice,
+		"when field not found, return error":                   combinations_FieldNotFound,
+		"when trait not found, return error":                   combinations_TraitNotFound,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testVariationStatus string
+
+type testVariationStruct struct {
+	ID      int
+	Status  testVariationStatus
+	Plan    string
+	Region  string
+	Seats   int
+	Enabled bool
+}
+
+func combinations_All(t *testing.T) {
+	vals, err := New(testVariationStruct{}).
+		Combinations(mockCTX,
+			Vary("Status", "new", "paid", "refunded"),
+			Vary("Plan", "free", "pro"),
+		).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := [][2]string{
+		{"new", "free"}, {"new", "pro"},
+		{"paid", "free"}, {"paid", "pro"},
+		{"refunded", "free"}, {"refunded", "pro"},
+	}
+	if len(vals) != len(want) {
+		t.Fatalf("len should be %d, but got %d", len(want), len(vals))
+	}
+
+	for i, w := range want {
+		if string(vals[i].Status) != w[0] || vals[i].Plan != w[1] {
+			t.Fatalf("vals[%d] should be %v, but got (%s, %s)", i, w, vals[i].Status, vals[i].Plan)
+		}
+	}
+}
+
+func combinations_Traits(t *testing.T) {
+	vals, err := New(testVariationStruct{}).
+		WithTrait("team", func(v *testVariationStruct) { v.Seats = 10 }).
+		WithTrait("solo", func(v *testVariationStruct) { v.Seats = 1 }).
+		Combinations(mockCTX,
+			VaryTraits("team", "solo"),
+			Vary("Enabled", true, false),
+		).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := []testVariationStruct{
+		{Seats: 10, Enabled: true}, {Seats: 10, Enabled: false},
+		{Seats: 1, Enabled: true}, {Seats: 1, Enabled: false},
+	}
+	if len(vals) != len(want) {
+		t.Fatalf("len should be %d, but got %d", len(want), len(vals))
+	}
+
+	for i, w := range want {
+		if vals[i].Seats != w.Seats || vals[i].Enabled != w.Enabled {
+			t.Fatalf("vals[%d] should be %+v, but got %+v", i, w, vals[i])
+		}
+	}
+}
+
+func combinations_Insert(t *testing.T) {
+	cfg := mockf.NewConfig()
+	vals, err := New(testVariationStruct{}).WithDB(cfg).
+		Combinations(mockCTX,
+			Vary("Plan", "free", "pro"),
+			Vary("Region", "eu", "us"),
+		).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(vals) != 4 {
+		t.Fatalf("len should be 4, but got %d", len(vals))
+	}
+
+	for i, v := range vals {
+		if v.ID != i+1 {
+			t.Fatalf("vals[%d].ID should be %d, but got %d", i, i+1, v.ID)
+		}
+	}
+}
+
+func combinations_Pairwise(t *testing.T) {
+	vars := []Variation{
+		Vary("Status", "new", "paid", "refunded"),
+		Vary("Plan", "free", "pro", "team"),
+		Vary("Region", "eu", "us", "ap"),
+		Vary("Enabled", true, false),
+	}
+	vals, err := New(testVariationStruct{}).Pairwise(mockCTX, vars...).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(vals) >= 3*3*3*2 {
+		t.Fatalf("len should be less than %d, but got %d", 3*3*3*2, len(vals))
+	}
+
+	fields := []string{"Status", "Plan", "Region", "Enabled"}
+	for i := range vars {
+		for j := i + 1; j < len(vars); j++ {
+			for _, a := range vars[i].vals {
+				for _, b := range vars[j].vals {
+					found := false
+					for _, v := range vals {
+						val := reflect.ValueOf(v)
+						if fmt.Sprint(val.FieldByName(fields[i]).Interface()) == fmt.Sprint(a) &&
+							fmt.Sprint(val.FieldByName(fields[j]).Interface()) == fmt.Sprint(b) {
+							found = true
+							break
+						}
+					}
+
+					if !found {
+						t.Fatalf("pair (%s=%v, %s=%v) is not covered", fields[i], a, fields[j], b)
+					}
+				}
+			}
+		}
+	}
+}
+
+func combinations_PairwiseTwo(t *testing.T) {
+	vals, err := New(testVariationStruct{}).
+		Pairwise(mockCTX,
+			Vary("Plan", "free", "pro"),
+			Vary("Seats", 1, 2, 3),
+		).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(vals) != 6 {
+		t.Fatalf("len should be 6, but got %d", len(vals))
+	}
+}
+
+func combinations_NoVariation(t *testing.T) {
+	_, err := New(testVariationStruct{}).Combinations(mockCTX).Get()
+	if !errors.Is(err, errInvalidVariation) {
+		t.Fatalf("error should be %v, but got %v", errInvalidVariation, err)
+	}
+}
+
+func combinations_NoChoice(t *testing.T) {
+	_, err := New(testVariationStruct{}).Combinations(mockCTX, Vary("Plan")).Get()
+	if !errors.Is(err, errInvalidVariation) {
+		t.Fatalf("error should be %v, but got %v", errInvalidVariation, err)
+	}
+}
+
+func combinations_FieldNotFound(t *testing.T) {
+	_, err := New(testVariationStruct{}).Combinations(mockCTX, Vary("Unknown", "a")).Get()
+	if !errors.Is(err, errFieldNotFound) {
+		t.Fatalf("error should be %v, but got %v", errFieldNotFound, err)
+	}
+}
+
+func combinations_TraitNotFound(t *testing.T) {
+	_, err := New(testVariationStruct{}).Pairwise(mockCTX, VaryTraits("unknown")).Get()
+	if !errors.Is(err, errWithTraitNameNotFound) {
+		t.Fatalf("error should be %v, but got %v", errWithTraitNameNotFound, err)
+	}
+}
+
+func TestWithReadOnly(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when read-only, insert returns error":               readOnly_Insert,
+		"when read-only, get still works":                    readOnly_Get,
+		"when read-only is unset, insert works":              readOnly_Unset,
+		"when database is allowed, insert works":             readOnly_AllowedDB,
+		"when database is not allowed, insert returns error": readOnly_NotAllowedDB,
+		"when only host is allowed, insert returns error":    readOnly_AllowedHost,
+		"when insert repeatedly, identify database once":     readOnly_IdentifyOnce,
+		"when database can't be identified, return error":    readOnly_NotIdentified,
+		"when use named database, check the named one":       readOnly_NamedDB,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func readOnly_Insert(t *testing.T) {
+	cfg := mockf.NewConfig()
+	f := New(testStructWithID{}).WithDB(cfg).WithReadOnly(true)
+
+	if _, err := f.Build(mockCTX).Insert(); !errors.Is(err, errReadOnly) {
+		t.Fatalf("error should be %v, but got %v", errReadOnly, err)
+	}
+
+	if _, err := f.BuildList(mockCTX, 2).Insert(); !errors.Is(err, errReadOnly) {
+		t.Fatalf("error should be %v, but got %v", errReadOnly, err)
+	}
+
+	if cfg.NumCalls() != 0 {
+		t.Fatalf("database should not be called, but got %d calls", cfg.NumCalls())
+	}
+}
+
+func readOnly_Get(t *testing.T) {
+	f := New(testStructWithID{}).WithDB(mockf.NewConfig()).WithReadOnly(true)
+
+	if _, err := f.Build(mockCTX).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if _, err := f.BuildList(mockCTX, 2).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func readOnly_Unset(t *testing.T) {
+	f := New(testStructWithID{}).WithDB(mockf.NewConfig()).WithReadOnly(true).WithReadOnly(false)
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func readOnly_AllowedDB(t *testing.T) {
+	cfg := mockf.NewConfig().WithNames("TEST_DB", "localhost")
+	f := New(testStructWithID{}).WithDB(cfg).WithAllowedDBs("test_db")
+
+	if _, err := f.BuildList(mockCTX, 2).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func readOnly_NotAllowedDB(t *testing.T) {
+	cfg := mockf.NewConfig().WithNames("prod_db", "db.example.com")
+	f := New(testStructWithID{}).WithDB(cfg).WithAllowedDBs("localhost", "test_db")
+
+	_, err := f.Build(mockCTX).Insert()
+	if !errors.Is(err, errDBNotAllowed) {
+		t.Fatalf("error should be %v, but got %v", errDBNotAllowed, err)
+	}
+
+	if !strings.Contains(err.Error(), "prod_db") {
+		t.Fatalf("error should contain the database name, but got %v", err)
+	}
+
+	if cfg.NumCalls() != 0 {
+		t.Fatalf("database should not be called, but got %d calls", cfg.NumCalls())
+	}
+}
+
+func readOnly_AllowedHost(t *testing.T) {
+	cfg := mockf.NewConfig().WithNames("prod_db", "localhost")
+	f := New(testStructWithID{}).WithDB(cfg).WithAllowedDBs("localhost")
+
+	if _, err := f.Build(mockCTX).Insert(); !errors.Is(err, errDBNotAllowed) {
+		t.Fatalf("error should be %v, but got %v", errDBNotAllowed, err)
+	}
+}
+
+// mockIdentifyDB is the mock database counting the calls of Identify.
+type mockIdentifyDB struct {
+	mockDB
+
+	// name is the database name returned by Identify
+	name string
+
+	// calls is the number of calls of Identify
+	calls int
+}
+
+// Identify returns the database name, and counts the calls.
+func (m *mockIdentifyDB) Identify(context.Context) ([]string, error) {
+	m.calls++
+	return []string{m.name}, nil
+}
+
+func readOnly_IdentifyOnce(t *testing.T) {
+	mdb, replica := &mockIdentifyDB{name: "test_db"}, &mockIdentifyDB{name: "test_db"}
+	f := New(testStructWithID{}).WithDB(mdb).WithNamedDB("replica", replica).WithAllowedDBs("test_db")
+
+	for i := 0; i < 3; i++ {
+		if _, err := f.Build(mockCTX).Insert(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		if _, err := f.Build(mockCTX).UseDB("replica").Insert(); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+
+	if mdb.calls != 1 || replica.calls != 1 {
+		t.Fatalf("each database should be identified once, but got %d and %d calls", mdb.calls, replica.calls)
+	}
+}
+
+func readOnly_NotIdentified(t *testing.T) {
+	f := New(testStructWithID{}).WithDB(&mockDB{}).WithAllowedDBs("localhost")
+
+	if _, err := f.Build(mockCTX).Insert(); !errors.Is(err, errDBNotAllowed) {
+		t.Fatalf("error should be %v, but got %v", errDBNotAllowed, err)
+	}
+}
+
+func readOnly_NamedDB(t *testing.T) {
+	f := New(testStructWithID{}).
+		WithDB(mockf.NewConfig().WithNames("test_db")).
+		WithNamedDB("replica", mockf.NewConfig().WithNames("prod_db")).
+		WithAllowedDBs("test_db")
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if _, err := f.Build(mockCTX).UseDB("replica").Insert(); !errors.Is(err, errDBNotAllowed) {
+		t.Fatalf("error should be %v, but got %v", errDBNotAllowed, err)
+	}
+}
+
+func TestOnNodeInserted(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when with associations, call in dependency order": onNodeInserted_Assoc,
+		"when without associations, call once":             onNodeInserted_NoAssoc,
+		"when multiple functions, call in order":           onNodeInserted_Multiple,
+		"when insert fails, not call for the failed node":  onNodeInserted_Fail,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+// nodeCall is the captured call of the function added by OnNodeInserted
+type nodeCall struct {
+	storageName string
+	ids         []int
+}
+
+// captureNodes returns the function capturing the storage names and the IDs of the inserted nodes
+func captureNodes(calls *[]nodeCall) NodeInsertedFunc {
+	return func(storageName string, vals []interface{}) {
+		ids := make([]int, len(vals))
+		for i, v := range vals {
+			ids[i] = int(reflect.ValueOf(v).Elem().FieldByName("ID").Int())
+		}
+		*calls = append(*calls, nodeCall{storageName: storageName, ids: ids})
+	}
+}
+
+func onNodeInserted_Assoc(t *testing.T) {
+	var calls []nodeCall
+	f := New(testAssocStruct{}).WithDB(mockf.NewConfig()).OnNodeInserted(captureNodes(&calls))
+
+	assVal := testStructWithID{}
+	assVal2 := testStructWithID2{}
+	assVal3 := testStructWithID3{}
+	val, err := f.BuildList(mockCTX, 2).
+		WithOne(&assVal, &assVal2, &assVal3).
+		Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := []nodeCall{
+		{storageName: "test_struct_with_ids", ids: []int{assVal.ID}},
+		{storageName: "test_struct_with_id3s", ids: []int{assVal3.ID}},
+		{storageName: "test_struct_with_id2s", ids: []int{assVal2.ID}},
+		{storageName: "test_assoc_structs", ids: []int{val[0].ID, val[1].ID}},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls should be %v, but got %v", want, calls)
+	}
+}
+
+func onNodeInserted_NoAssoc(t *testing.T) {
+	var calls []nodeCall
+	f := New(testStructWithID{}).WithDB(mockf.NewConfig()).OnNodeInserted(captureNodes(&calls))
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := f.BuildList(mockCTX, 2).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := []nodeCall{
+		{storageName: "test_struct_with_ids", ids: []int{1}},
+		{storageName: "test_struct_with_ids", ids: []int{2, 3}},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls should be %v, but got %v", want, calls)
+	}
+}
+
+func onNodeInserted_Multiple(t *testing.T) {
+	var order []string
+	f := New(testStructWithID{}).WithDB(mockf.NewConfig()).
+		OnNodeInserted(func(string, []interface{}) { order = append(order, "first") }).
+		OnNodeInserted(func(string, []interface{}) { order = append(order, "second") })
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if want := []string{"first", "second"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("order should be %v, but got %v", want, order)
+	}
+}
+
+func onNodeInserted_Fail(t *testing.T) {
+	var calls []nodeCall
+	mockErr := errors.New("insert error")
+	f := New(testAssocStruct{}).
+		WithDB(mockf.NewConfig().FailOnCall(2, mockErr)).
+		OnNodeInserted(captureNodes(&calls))
+
+	assVal := testStructWithID{}
+	_, err := f.Build(mockCTX).WithOne(&assVal).Insert()
+	if !errors.Is(err, mockErr) {
+		t.Fatalf("error should be %v, but got %v", mockErr, err)
+	}
+
+	if len(calls) != 1 {
+		t.Fatalf("calls should be 1, but got %d: %v", len(calls), calls)
+	}
+}
+
+func TestExport(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when json namespace, honor json tags":         export_JSON,
+		"when bson namespace, honor bson tags":         export_BSON,
+		"when yaml namespace, flatten inline fields":   export_YAMLInline,
+		"when export list, export each value":          export_List,
+		"when json namespace, match json.Marshal":      export_MatchMarshal,
+		"when patch with namespace, use the tag names": export_Patch,
+		"when builder has error, return error":         export_BuilderErr,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testExportBase struct {
+	CreatedBy string `json:"created_by" bson:"createdBy" yaml:"created_by"`
+}
+
+type testExportAddress struct {
+	City string `json:"city" bson:"city" yaml:"city"`
+}
+
+type testExportStruct struct {
+	testExportBase `bson:",inline" yaml:",inline"`
+	ID             int               `json:"id" bson:"_id" yaml:"id"`
+	Name           string            `json:"name" bson:"fullName" yaml:"name"`
+	Nickname       string            `json:"nickname,omitempty" bson:"nickname,omitempty" yaml:"nickname,omitempty"`
+	Secret         string            `json:"-" bson:"-" yaml:"-"`
+	Tags           []string          `json:"tags,omitempty" bson:"tags,omitempty" yaml:"tags,omitempty"`
+	Address        testExportAddress `json:"address,omitempty" bson:"address,omitempty" yaml:"address,omitempty"`
+	Plain          int
+	private        int
+}
+
+func export_JSON(t *testing.T) {
+	got, err := New(testExportStruct{}).
+		WithBlueprint(func(i int) testExportStruct {
+			return testExportStruct{testExportBase: testExportBase{CreatedBy: "admin"}, Name: "alice", Secret: "s"}
+		}).
+		WithIsSetZeroValue(false).
+		Build(mockCTX).Export()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := map[string]interface{}{
+		"created_by": "admin",
+		"id":         0,
+		"name":       "alice",
+		"address":    testExportAddress{},
+		"Plain":      0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("export should be %v, but got %v", want, got)
+	}
+}
+
+func export_BSON(t *testing.T) {
+	got, err := New(testExportStruct{}).
+		WithBlueprint(func(i int) testExportStruct {
+			return testExportStruct{testExportBase: testExportBase{CreatedBy: "admin"}, Name: "alice", Nickname: "al"}
+		}).
+		WithIsSetZeroValue(false).
+		WithTagNamespace("bson").
+		Build(mockCTX).Export()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := map[string]interface{}{
+		"createdBy": "admin",
+		"_id":       0,
+		"fullName":  "alice",
+		"nickname":  "al",
+		"Plain":     0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("export should be %v, but got %v", want, got)
+	}
+}
+
+func export_YAMLInline(t *testing.T) {
+	got, err := New(testExportStruct{}).
+		WithBlueprint(func(i int) testExportStruct {
+			return testExportStruct{testExportBase: testExportBase{CreatedBy: "admin"}, Address: testExportAddress{City: "Taipei"}}
+		}).
+		WithIsSetZeroValue(false).
+		WithTagNamespace("yaml").
+		Build(mockCTX).Export()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := map[string]interface{}{
+		"created_by": "admin",
+		"id":         0,
+		"name":       "",
+		"address":    testExportAddress{City: "Taipei"},
+		"Plain":      0,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("export should be %v, but got %v", want, got)
+	}
+}
+
+func export_List(t *testing.T) {
+	got, err := New(testExportStruct{}).
+		WithBlueprint(func(i int) testExportStruct {
+			return testExportStruct{Name: fmt.Sprintf("user%d", i), Tags: []string{"a"}}
+		}).
+		BuildList(mockCTX, 2).Export()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("len should be 2, but got %d", len(got))
+	}
+
+	for i, m := range got {
+		if want := fmt.Sprintf("user%d", i+1); m["name"] != want {
+			t.Fatalf("name should be %s, but got %v", want, m["name"])
+		}
+		if !reflect.DeepEqual(m["tags"], []string{"a"}) {
+			t.Fatalf("tags should be [a], but got %v", m["tags"])
+		}
+	}
+}
+
+func export_MatchMarshal(t *testing.T) {
+	b := New(testExportStruct{}).Build(mockCTX)
+
+	v, err := b.Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	m, err := b.Export()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	got, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if !bytes.Equal(canonicalJSON(t, got), canonicalJSON(t, want)) {
+		t.Fatalf("export should be %s, but got %s", want, got)
+	}
+}
+
+// canonicalJSON re-encodes the JSON object with the sorted keys
+func canonicalJSON(t *testing.T, b []byte) []byte {
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	out, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	return out
+}
+
+func export_Patch(t *testing.T) {
+	got, err := New(testExportStruct{}).
+		WithBlueprint(func(i int) testExportStruct {
+			return testExportStruct{Name: "alice"}
+		}).
+		WithIsSetZeroValue(false).
+		WithTagNamespace("bson").
+		Build(mockCTX).Patch("Name", "Nickname", "Secret")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := map[string]interface{}{"fullName": "alice", "nickname": "", "Secret": ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("patch should be %v, but got %v", want, got)
+	}
+}
+
+func export_BuilderErr(t *testing.T) {
+	_, err := New(testExportStruct{}).Build(mockCTX).SetTrait("unknown").Export()
+	if !errors.Is(err, errWithTraitNameNotFound) {
+		t.Fatalf("error should be %v, but got %v", errWithTraitNameNotFound, err)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when build, list the skipped fields and reasons":  diagnostics_Reasons,
+		"when set zero, list the zeroed fields":            diagnostics_SetZero,
+		"when ignored by factory, list the ignored fields": diagnostics_Ignored,
+		"when zero values disabled, list all the fields":   diagnostics_ZeroValueDisabled,
+		"when build list, list for each value":             diagnostics_List,
+		"when builder has error, return nil":               diagnostics_BuilderErr,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testDiagnosticsStatus string
+
+type testDiagnosticsNested struct {
+	ID   int
+	Name string
+	Any  interface{}
+}
+
+type testDiagnosticsStruct struct {
+	ID       int
+	Name     string
+	Note     string `gofacto:"omit"`
+	Status   testDiagnosticsStatus
+	Any      interface{}
+	Attrs    map[string]string
+	Nested   testDiagnosticsNested
+	PtrNest  *testDiagnosticsNested
+	internal int
+}
+
+func diagnostics_Reasons(t *testing.T) {
+	got := New(testDiagnosticsStruct{}).Build(mockCTX).Diagnostics()
+
+	want := []Diagnostic{
+		{Field: "ID", Reason: SkipReasonIDField},
+		{Field: "Note", Reason: SkipReasonOmitTag},
+		{Field: "Status", Reason: SkipReasonCustomType},
+		{Field: "Any", Reason: SkipReasonInterface},
+		{Field: "Attrs", Reason: SkipReasonUnsupported},
+		{Field: "Nested.ID", Reason: SkipReasonIDField},
+		{Field: "Nested.Any", Reason: SkipReasonInterface},
+		{Field: "PtrNest.ID", Reason: SkipReasonIDField},
+		{Field: "PtrNest.Any", Reason: SkipReasonInterface},
+		{Field: "internal", Reason: SkipReasonUnexported},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diagnostics should be %v, but got %v", want, got)
+	}
+}
+
+func diagnostics_SetZero(t *testing.T) {
+	got := New(testDiagnosticsStruct{}).Build(mockCTX).SetZero("Name").Diagnostics()
+
+	if d := got[1]; d.Field != "Name" || d.Reason != SkipReasonSetZero {
+		t.Fatalf("Name should be skipped by %s, but got %v", SkipReasonSetZero, d)
+	}
+}
+
+type testDiagnosticsOwned struct {
+	ID      int
+	OwnerID int
+	Name    string
+}
+
+func diagnostics_Ignored(t *testing.T) {
+	got := Define[testDiagnosticsOwned]().
+		BelongsTo("Owner", New(testStructWithID{})).
+		Build().
+		Build(mockCTX).Diagnostics()
+
+	want := []Diagnostic{
+		{Field: "ID", Reason: SkipReasonIDField},
+		{Field: "OwnerID", Reason: SkipReasonIgnored},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diagnostics should be %v, but got %v", want, got)
+	}
+}
+
+func diagnostics_ZeroValueDisabled(t *testing.T) {
+	got := New(testDiagnosticsStruct{}).
+		WithIsSetZeroValue(false).
+		WithBlueprint(func(i int) testDiagnosticsStruct {
+			return testDiagnosticsStruct{Name: "name"}
+		}).
+		Build(mockCTX).Diagnostics()
+
+	for _, d := range got {
+		if d.Field == "Name" {
+			t.Fatalf("Name should not be listed, but got %v", got)
+		}
+	}
+
+	if d := got[2]; d.Field != "Status" || d.Reason != SkipReasonZeroValueDisabled {
+		t.Fatalf("Status should be skipped by %s, but got %v", SkipReasonZeroValueDisabled, d)
+	}
+}
+
+func diagnostics_List(t *testing.T) {
+	got := New(testDiagnosticsStruct{}).BuildList(mockCTX, 2).Diagnostics()
+
+	if len(got) != 2 {
+		t.Fatalf("len should be 2, but got %d", len(got))
+	}
+
+	if !reflect.DeepEqual(got[0], got[1]) {
+		t.Fatalf("diagnostics should be the same, but got %v and %v", got[0], got[1])
+	}
+}
+
+func diagnostics_BuilderErr(t *testing.T) {
+	if got := New(testDiagnosticsStruct{}).Build(mockCTX).SetTrait("unknown").Diagnostics(); got != nil {
+		t.Fatalf("diagnostics should be nil, but got %v", got)
+	}
+}
+
+func TestSetConfig(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when all options are set, map onto the factory": setConfig_All,
+		"when options are zero, keep the settings":       setConfig_Zero,
+		"when ignore field not found, set error":         setConfig_IgnoreFieldNotFound,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testConfigStruct struct {
+	ID     int
+	Name   string
+	Note   string
+	Status string
+}
+
+func setConfig_All(t *testing.T) {
+	cfg := mockf.NewConfig()
+	f := New(testConfigStruct{}).SetConfig(Config[testConfigStruct]{
+		DB: cfg,
+		Blueprint: func(i int) testConfigStruct {
+			return testConfigStruct{Name: fmt.Sprintf("name%d", i)}
+		},
+		StorageName: "configs",
+		Traits: map[string]func(v *testConfigStruct){
+			"active": func(v *testConfigStruct) { v.Status = "active" },
+		},
+		IgnoreFields: []string{"Note"},
+	})
+
+	val, err := f.Build(mockCTX).SetTrait("active").Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := testConfigStruct{ID: 1, Name: "name1", Status: "active"}
+	if err := testutils.CompareVal(val, want); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if got := cfg.Inserted("configs"); len(got) != 1 {
+		t.Fatalf("1 value should be inserted into configs, but got %d", len(got))
+	}
+}
+
+func setConfig_Zero(t *testing.T) {
+	cfg := mockf.NewConfig()
+	f := New(testConfigStruct{}).
+		WithDB(cfg).
+		WithStorageName("configs").
+		SetConfig(Config[testConfigStruct]{})
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if got := cfg.Inserted("configs"); len(got) != 1 {
+		t.Fatalf("1 value should be inserted into configs, but got %d", len(got))
+	}
+}
+
+func setConfig_IgnoreFieldNotFound(t *testing.T) {
+	f := New(testConfigStruct{}).SetConfig(Config[testConfigStruct]{IgnoreFields: []string{"Unknown"}})
+	if _, err := f.Build(mockCTX).Get(); !errors.Is(err, errFieldNotFound) {
+		t.Fatalf("error should be %v, but got %v", errFieldNotFound, err)
+	}
+
+	if _, err := f.BuildList(mockCTX, 2).Get(); !errors.Is(err, errFieldNotFound) {
+		t.Fatalf("error should be %v, but got %v", errFieldNotFound, err)
+	}
+}
+
+func TestWithValueProvider(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when env variables are set, use the values":          valueProvider_Env,
+		"when env prefix is set, use the prefix":              valueProvider_EnvPrefix,
+		"when config file is set, use the values":             valueProvider_File,
+		"when multiple providers, use the first value":        valueProvider_Order,
+		"when blueprint sets the field, keep the value":       valueProvider_Blueprint,
+		"when value can't be parsed, return error":            valueProvider_ParseErr,
+		"when config file is invalid, return error":           valueProvider_InvalidFile,
+		"when value is not provided, generate non-zero value": valueProvider_NotProvided,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testProviderStruct struct {
+	ID        int
+	Currency  string
+	Quantity  int
+	Rate      *float64
+	IsActive  bool
+	ExpiresAt time.Time
+	Note      string `gofacto:"omit"`
+}
+
+func valueProvider_Env(t *testing.T) {
+	t.Setenv("GOFACTO_DEFAULT_CURRENCY", "USD")
+	t.Setenv("GOFACTO_DEFAULT_QUANTITY", "3")
+	t.Setenv("GOFACTO_DEFAULT_RATE", "1.5")
+	t.Setenv("GOFACTO_DEFAULT_IS_ACTIVE", "false")
+	t.Setenv("GOFACTO_DEFAULT_EXPIRES_AT", "2030-01-02T03:04:05Z")
+	t.Setenv("GOFACTO_DEFAULT_NOTE", "note")
+
+	val, err := New(testProviderStruct{}).
+		WithValueProvider(NewEnvProvider("")).
+		Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	rate := 1.5
+	want := testProviderStruct{
+		Currency:  "USD",
+		Quantity:  3,
+		Rate:      &rate,
+		IsActive:  true, // false is zero, so it's generated as usual
+		ExpiresAt: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	if err := testutils.CompareVal(val, want); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func valueProvider_EnvPrefix(t *testing.T) {
+	t.Setenv("APP_CURRENCY", "EUR")
+
+	val, err := New(testProviderStruct{}).
+		WithValueProvider(NewEnvProvider("APP_")).
+		Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Currency != "EUR" {
+		t.Fatalf("Currency should be EUR, but got %s", val.Currency)
+	}
+}
+
+func valueProvider_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "defaults.json")
+	content := `{"Currency": "TWD", "Quantity": 5, "Rate": 0.5, "ExpiresAt": "2030-01-02T03:04:05Z"}`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	p, err := NewFileProvider(path)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	val, err := New(testProviderStruct{}).WithValueProvider(p).Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Currency != "TWD" || val.Quantity != 5 || val.Rate == nil || *val.Rate != 0.5 {
+		t.Fatalf("values should be provided by the file, but got %+v", val)
+	}
+
+	if !val.ExpiresAt.Equal(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Fatalf("ExpiresAt should be provided by the file, but got %v", val.ExpiresAt)
+	}
+}
+
+func valueProvider_Order(t *testing.T) {
+	t.Setenv("GOFACTO_DEFAULT_CURRENCY", "USD")
+
+	path := filepath.Join(t.TempDir(), "defaults.json")
+	if err := os.WriteFile(path, []byte(`{"Currency": "TWD", "Quantity": 5}`), 0o644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	p, err := NewFileProvider(path)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	val, err := New(testProviderStruct{}).
+		WithValueProvider(NewEnvProvider(""), p).
+		Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Currency != "USD" || val.Quantity != 5 {
+		t.Fatalf("Currency should be USD and Quantity should be 5, but got %+v", val)
+	}
+}
+
+func valueProvider_Blueprint(t *testing.T) {
+	t.Setenv("GOFACTO_DEFAULT_CURRENCY", "USD")
+
+	val, err := New(testProviderStruct{}).
+		WithBlueprint(func(i int) testProviderStruct {
+			return testProviderStruct{Currency: "JPY"}
+		}).
+		WithValueProvider(NewEnvProvider("")).
+		Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Currency != "JPY" {
+		t.Fatalf("Currency should be JPY, but got %s", val.Currency)
+	}
+}
+
+func valueProvider_ParseErr(t *testing.T) {
+	t.Setenv("GOFACTO_DEFAULT_QUANTITY", "three")
+
+	_, err := New(testProviderStruct{}).
+		WithValueProvider(NewEnvProvider("")).
+		Build(mockCTX).Get()
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("error should be %v, but got %v", strconv.ErrSyntax, err)
+	}
+}
+
+func valueProvider_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "defaults.json")
+	if err := os.WriteFile(path, []byte(`["USD"]`), 0o644); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if _, err := NewFileProvider(path); err == nil {
+		t.Fatal("error should not be nil")
+	}
+
+	if _, err := NewFileProvider(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("error should be %v, but got %v", os.ErrNotExist, err)
+	}
+}
+
+func valueProvider_NotProvided(t *testing.T) {
+	val, err := New(testProviderStruct{}).
+		WithValueProvider(NewEnvProvider("GOFACTO_TEST_UNSET_")).
+		Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Currency != "test1" {
+		t.Fatalf("Currency should be test1, but got %s", val.Currency)
+	}
+}
+
+func TestInsertChunked(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when without associations, insert in chunks":          insertChunked_NoAssoc,
+		"when with many, insert associations with their chunk": insertChunked_WithMany,
+		"when with one, insert the association once":           insertChunked_WithOne,
+		"when more associations than values, insert the rest":  insertChunked_MoreAssoc,
+		"when invalid size, return error":                      insertChunked_InvalidSize,
+		"when foreign keys, return error":                      insertChunked_ForeignKeys,
+		"when fn returns error, stop and return error":         insertChunked_FnError,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func insertChunked_NoAssoc(t *testing.T) {
+	var calls []nodeCall
+	f := New(testStructWithID{}).WithDB(mockf.NewConfig()).OnNodeInserted(captureNodes(&calls))
+
+	var chunks [][]testStructWithID
+	err := f.BuildList(mockCTX, 5).InsertChunked(2, func(vals []testStructWithID) error {
+		chunks = append(chunks, vals)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	wantChunks := [][]testStructWithID{{{ID: 1}, {ID: 2}}, {{ID: 3}, {ID: 4}}, {{ID: 5}}}
+	if !reflect.DeepEqual(chunks, wantChunks) {
+		t.Fatalf("chunks should be %v, but got %v", wantChunks, chunks)
+	}
+
+	wantCalls := []nodeCall{
+		{storageName: "test_struct_with_ids", ids: []int{1, 2}},
+		{storageName: "test_struct_with_ids", ids: []int{3, 4}},
+		{storageName: "test_struct_with_ids", ids: []int{5}},
+	}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Fatalf("calls should be %v, but got %v", wantCalls, calls)
+	}
+}
+
+func insertChunked_WithMany(t *testing.T) {
+	var calls []nodeCall
+	f := New(testAssocStruct{}).WithDB(mockf.NewConfig()).OnNodeInserted(captureNodes(&calls))
+
+	assVals := make([]testStructWithID, 3)
+	assInput := make([]interface{}, len(assVals))
+	for i := range assVals {
+		assInput[i] = &assVals[i]
+	}
+
+	var vals []testAssocStruct
+	err := f.BuildList(mockCTX, 3).WithMany(assInput).InsertChunked(2, func(chunk []testAssocStruct) error {
+		vals = append(vals, chunk...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for i, v := range vals {
+		if v.ForeignKey != assVals[i].ID {
+			t.Fatalf("ForeignKey of value %d should be %v, but got %v", i, assVals[i].ID, v.ForeignKey)
+		}
+	}
+
+	wantCalls := []nodeCall{
+		{storageName: "test_struct_with_ids", ids: []int{assVals[0].ID, assVals[1].ID}},
+		{storageName: "test_assoc_structs", ids: []int{vals[0].ID, vals[1].ID}},
+		{storageName: "test_struct_with_ids", ids: []int{assVals[2].ID}},
+		{storageName: "test_assoc_structs", ids: []int{vals[2].ID}},
+	}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Fatalf("calls should be %v, but got %v", wantCalls, calls)
+	}
+}
+
+func insertChunked_WithOne(t *testing.T) {
+	var calls []nodeCall
+	f := New(testAssocStruct{}).WithDB(mockf.NewConfig()).OnNodeInserted(captureNodes(&calls))
+
+	assVal := testStructWithID{}
+	assVal2 := testStructWithID2{}
+	assVal3 := testStructWithID3{}
+
+	var vals []testAssocStruct
+	err := f.BuildList(mockCTX, 3).WithOne(&assVal, &assVal2, &assVal3).InsertChunked(2, func(chunk []testAssocStruct) error {
+		vals = append(vals, chunk...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for i, v := range vals {
+		if v.ForeignKey != assVal.ID {
+			t.Fatalf("ForeignKey of value %d should be %v, but got %v", i, assVal.ID, v.ForeignKey)
+		}
+		if *v.ForeignKey2 != assVal2.ID {
+			t.Fatalf("ForeignKey2 of value %d should be %v, but got %v", i, assVal2.ID, *v.ForeignKey2)
+		}
+	}
+
+	if assVal2.ForeignKey != assVal3.ID {
+		t.Fatalf("ForeignKey of association should be %v, but got %v", assVal3.ID, assVal2.ForeignKey)
+	}
+
+	wantCalls := []nodeCall{
+		{storageName: "test_struct_with_ids", ids: []int{assVal.ID}},
+		{storageName: "test_struct_with_id3s", ids: []int{assVal3.ID}},
+		{storageName: "test_struct_with_id2s", ids: []int{assVal2.ID}},
+		{storageName: "test_assoc_structs", ids: []int{vals[0].ID, vals[1].ID}},
+		{storageName: "test_assoc_structs", ids: []int{vals[2].ID}},
+	}
+	if !reflect.DeepEqual(calls, wantCalls) {
+		t.Fatalf("calls should be %v, but got %v", wantCalls, calls)
+	}
+}
+
+func insertChunked_MoreAssoc(t *testing.T) {
+	storage := mockf.NewConfig()
+	f := New(testAssocStruct{}).WithDB(storage)
+
+	assVals := make([]testStructWithID, 3)
+	assInput := make([]interface{}, len(assVals))
+	for i := range assVals {
+		assInput[i] = &assVals[i]
+	}
+
+	numChunks := 0
+	err := f.BuildList(mockCTX, 1).WithMany(assInput).InsertChunked(1, func([]testAssocStruct) error {
+		numChunks++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if numChunks != 1 {
+		t.Fatalf("fn should be called once, but got %v", numChunks)
+	}
+
+	if n := len(storage.Inserted("test_struct_with_ids")); n != 3 {
+		t.Fatalf("all associations should be inserted, but got %v", n)
+	}
+}
+
+func insertChunked_InvalidSize(t *testing.T) {
+	f := New(testStructWithID{}).WithDB(mockf.NewConfig())
+
+	err := f.BuildList(mockCTX, 2).InsertChunked(0, func([]testStructWithID) error { return nil })
+	if !errors.Is(err, errInvalidChunkSize) {
+		t.Fatalf("error should be %v, but got %v", errInvalidChunkSize, err)
+	}
+}
+
+func insertChunked_ForeignKeys(t *testing.T) {
+	f := New(testFKTagged{}).WithDB(mockf.NewConfig())
+
+	tag1, tag2 := testFKTag{}, testFKTag{}
+	err := f.BuildList(mockCTX, 2).
+		WithMany([]interface{}{&tag1, &tag2}).
+		InsertChunked(1, func([]testFKTagged) error { return nil })
+	if !errors.Is(err, errChunkForeignKeys) {
+		t.Fatalf("error should be %v, but got %v", errChunkForeignKeys, err)
+	}
+}
+
+func insertChunked_FnError(t *testing.T) {
+	storage := mockf.NewConfig()
+	f := New(testStructWithID{}).WithDB(storage)
+
+	errFn := errors.New("fn error")
+	numChunks := 0
+	err := f.BuildList(mockCTX, 4).InsertChunked(2, func([]testStructWithID) error {
+		numChunks++
+		return errFn
+	})
+	if !errors.Is(err, errFn) {
+		t.Fatalf("error should be %v, but got %v", errFn, err)
+	}
+
+	if numChunks != 1 {
+		t.Fatalf("fn should be called once, but got %v", numChunks)
+	}
+
+	if n := len(storage.Inserted("test_struct_with_ids")); n != 2 {
+		t.Fatalf("only the first chunk should be inserted, but got %v", n)
+	}
+}
+
+func TestSkipInsert(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when with associations, insert only associations": skipInsert_Assoc,
+		"when build list, set foreign keys to each":        skipInsert_BuildList,
+		"when without associations, insert nothing":        skipInsert_NoAssoc,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func skipInsert_Assoc(t *testing.T) {
+	var calls []nodeCall
+	storage := mockf.NewConfig()
+	f := New(testAssocStruct{}).WithDB(storage).OnNodeInserted(captureNodes(&calls))
+
+	assVal := testStructWithID{}
+	assVal2 := testStructWithID2{}
+	assVal3 := testStructWithID3{}
+	val, err := f.Build(mockCTX).WithOne(&assVal, &assVal2, &assVal3).SkipInsert().Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.ID != 0 {
+		t.Fatalf("ID should be 0, but got %v", val.ID)
+	}
+
+	if val.ForeignKey != assVal.ID {
+		t.Fatalf("ForeignKey should be %v, but got %v", assVal.ID, val.ForeignKey)
+	}
+
+	if *val.ForeignKey2 != assVal2.ID {
+		t.Fatalf("ForeignKey2 should be %v, but got %v", assVal2.ID, *val.ForeignKey2)
+	}
+
+	if val.ForeignValue != assVal {
+		t.Fatalf("ForeignValue should be %v, but got %v", assVal, val.ForeignValue)
+	}
+
+	if n := len(storage.Inserted("test_assoc_structs")); n != 0 {
+		t.Fatalf("value should not be inserted, but got %v", n)
+	}
+
+	want := []nodeCall{
+		{storageName: "test_struct_with_ids", ids: []int{assVal.ID}},
+		{storageName: "test_struct_with_id3s", ids: []int{assVal3.ID}},
+		{storageName: "test_struct_with_id2s", ids: []int{assVal2.ID}},
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Fatalf("calls should be %v, but got %v", want, calls)
+	}
+}
+
+func skipInsert_BuildList(t *testing.T) {
+	storage := mockf.NewConfig()
+	f := New(testAssocStruct{}).WithDB(storage)
+
+	assVal1, assVal2 := testStructWithID{}, testStructWithID{}
+	vals, err := f.BuildList(mockCTX, 2).
+		WithMany([]interface{}{&assVal1, &assVal2}).
+		SkipInsert().
+		Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for i, assVal := range []testStructWithID{assVal1, assVal2} {
+		if vals[i].ID != 0 {
+			t.Fatalf("ID of value %d should be 0, but got %v", i, vals[i].ID)
+		}
+
+		if vals[i].ForeignKey != assVal.ID {
+			t.Fatalf("ForeignKey of value %d should be %v, but got %v", i, assVal.ID, vals[i].ForeignKey)
+		}
+	}
+
+	if n := len(storage.Inserted("test_struct_with_ids")); n != 2 {
+		t.Fatalf("associations should be inserted, but got %v", n)
+	}
+
+	if n := len(storage.Inserted("test_assoc_structs")); n != 0 {
+		t.Fatalf("values should not be inserted, but got %v", n)
+	}
+}
+
+func skipInsert_NoAssoc(t *testing.T) {
+	storage := mockf.NewConfig()
+	f := New(testStructWithID{}).WithDB(storage)
+
+	val, err := f.Build(mockCTX).SkipInsert().Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.ID != 0 {
+		t.Fatalf("ID should be 0, but got %v", val.ID)
+	}
+
+	if storage.NumCalls() != 0 {
+		t.Fatalf("database should not be called, but got %v calls", storage.NumCalls())
+	}
+}
+
+func TestWithRand(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when same seed, generate same offsets":          withRand_SameSeed,
+		"when crypto rand, set unpredictable values":     withRand_Crypto,
+		"when crypto rand with blueprint, keep values":   withRand_CryptoBlueprint,
+		"when crypto rand invalid type, return error":    withRand_CryptoInvalidType,
+		"when crypto rand field not found, return error": withRand_CryptoNotFound,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+// testRandStruct is a struct to test the random source
+type testRandStruct struct {
+	ScheduledAt time.Time `gofacto:"future,window:24h"`
+}
+
+func withRand_SameSeed(t *testing.T) {
+	genOffsets := func() []time.Duration {
+		f := New(testRandStruct{}).WithRand(rand.New(rand.NewSource(42)))
+
+		offsets := make([]time.Duration, 3)
+		for i := range offsets {
+			now := time.Now()
+			val, err := f.Build(mockCTX).Get()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			offsets[i] = val.ScheduledAt.Sub(now)
+		}
+
+		return offsets
+	}
+
+	// the offsets differ only by the time passed between now and generating
+	first, second := genOffsets(), genOffsets()
+	for i := range first {
+		if diff := first[i] - second[i]; diff > time.Second || diff < -time.Second {
+			t.Fatalf("offsets should be the same, but got %v and %v", first, second)
+		}
+	}
+}
+
+// testSecretStruct is a struct to test the values generated by crypto/rand
+type testSecretStruct struct {
+	Name      string
+	Token     string
+	TokenPtr  *string
+	SecretKey []byte
+}
+
+func withRand_Crypto(t *testing.T) {
+	f := New(testSecretStruct{}).WithCryptoRand("Token", "TokenPtr", "SecretKey")
+
+	vals, err := f.BuildList(mockCTX, 2).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for i, v := range vals {
+		if len(v.Token) != 2*cryptoRandLen {
+			t.Fatalf("Token of value %d should have %d characters, but got %q", i, 2*cryptoRandLen, v.Token)
+		}
+
+		if _, err := hex.DecodeString(v.Token); err != nil {
+			t.Fatalf("Token of value %d should be hex, but got %q", i, v.Token)
+		}
+
+		if v.TokenPtr == nil || len(*v.TokenPtr) != 2*cryptoRandLen {
+			t.Fatalf("TokenPtr of value %d should have %d characters, but got %v", i, 2*cryptoRandLen, v.TokenPtr)
+		}
+
+		if len(v.SecretKey) != cryptoRandLen {
+			t.Fatalf("SecretKey of value %d should have %d bytes, but got %v", i, cryptoRandLen, v.SecretKey)
+		}
+	}
+
+	if vals[0].Token == vals[1].Token || bytes.Equal(vals[0].SecretKey, vals[1].SecretKey) {
+		t.Fatalf("values should be different, but got %v", vals)
+	}
+
+	if vals[0].Name != "test1" {
+		t.Fatalf("Name should be generated as usual, but got %v", vals[0].Name)
+	}
+}
+
+func withRand_CryptoBlueprint(t *testing.T) {
+	f := New(testSecretStruct{}).
+		WithBlueprint(func(i int) testSecretStruct { return testSecretStruct{Token: "fixed"} }).
+		WithCryptoRand("Token")
+
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Token != "fixed" {
+		t.Fatalf("Token should be fixed, but got %v", val.Token)
+	}
+}
+
+func withRand_CryptoInvalidType(t *testing.T) {
+	f := New(testRandStruct{}).WithCryptoRand("ScheduledAt")
+	if !errors.Is(f.configErr, errCryptoRandType) {
+		t.Fatalf("error should be %v, but got %v", errCryptoRandType, f.configErr)
+	}
+}
+
+func withRand_CryptoNotFound(t *testing.T) {
+	f := New(testSecretStruct{}).WithCryptoRand("Unknown")
+	if !errors.Is(f.configErr, errFieldNotFound) {
+		t.Fatalf("error should be %v, but got %v", errFieldNotFound, f.configErr)
+	}
+}
+
+func TestWithIndexOffset(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when offset is set, start at offset plus 1":       indexOffset_Start,
+		"when different offsets, generate disjoint ranges": indexOffset_Disjoint,
+		"when reset or local index, restart at offset":     indexOffset_Reset,
+		"when sequence, shift reserved indexes":            indexOffset_Sequence,
+		"when offset is negative, return error":            indexOffset_Negative,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func indexOffset_Start(t *testing.T) {
+	f := New(testStructWithID3{}).WithIsSetSeqID(true).WithIndexOffset(1_000_000)
+
+	vals, err := f.BuildList(mockCTX, 2).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for i, v := range vals {
+		want := 1_000_001 + i
+		if v.ID != want {
+			t.Fatalf("ID should be %v, got %v", want, v.ID)
+		}
+		if name := fmt.Sprintf("test%d", want); v.Name != name {
+			t.Fatalf("Name should be %v, got %v", name, v.Name)
+		}
+	}
+}
+
+func indexOffset_Disjoint(t *testing.T) {
+	f1 := New(testStructWithID3{}).WithIndexOffset(1_000_000)
+	f2 := New(testStructWithID3{}).WithIndexOffset(2_000_000)
+
+	vals1, err := f1.BuildList(mockCTX, 3).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	vals2, err := f2.BuildList(mockCTX, 3).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	names := map[string]bool{}
+	for _, v := range append(vals1, vals2...) {
+		if names[v.Name] {
+			t.Fatalf("Name %v should be unique", v.Name)
+		}
+		names[v.Name] = true
+	}
+}
+
+func indexOffset_Reset(t *testing.T) {
+	f := New(testStructWithID3{}).WithIndexOffset(100)
+
+	if _, err := f.BuildList(mockCTX, 2).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	f.Reset()
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if val.Name != "test101" {
+		t.Fatalf("Name after reset should be test101, got %v", val.Name)
+	}
+
+	f.WithIsLocalIndex(true)
+	val, err = f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if val.Name != "test101" {
+		t.Fatalf("Name with local index should be test101, got %v", val.Name)
+	}
+}
+
+func indexOffset_Sequence(t *testing.T) {
+	seq := NewFileSequence(filepath.Join(t.TempDir(), "seq"))
+	f := New(testStructWithID3{}).WithSequence(seq).WithIndexOffset(1_000)
+
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Name != "test1001" {
+		t.Fatalf("Name should be test1001, got %v", val.Name)
+	}
+}
+
+func indexOffset_Negative(t *testing.T) {
+	f := New(testStructWithID3{}).WithIndexOffset(-1)
+	if !errors.Is(f.configErr, errInvalidIndexOffset) {
+		t.Fatalf("error should be %v, but got %v", errInvalidIndexOffset, f.configErr)
+	}
+}
+
+func TestWithSchemaCheck(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when schema is valid, build as usual":          schemaCheck_Valid,
+		"when schema is invalid, return error on build": schemaCheck_Invalid,
+		"when schema passed, cache the check":           schemaCheck_Cached,
+		"when no database, skip the check":              schemaCheck_NoDB,
+		"when database can't check, return error":       schemaCheck_NotSupported,
+		"when columns set, skip the omitted fields":     schemaCheck_Columns,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+// schemaDB is the database checking the schema with the given error
+type schemaDB struct {
+	*mockf.Config
+	err    error
+	params []db.CheckSchemaParams
+}
+
+func (d *schemaDB) CheckSchema(ctx context.Context, params db.CheckSchemaParams) error {
+	d.params = append(d.params, params)
+	return d.err
+}
+
+func schemaCheck_Valid(t *testing.T) {
+	sdb := &schemaDB{Config: mockf.NewConfig()}
+	f := New(testStructWithID{}).WithDB(sdb).WithStorageName("valid_structs").WithSchemaCheck(mockCTX)
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := []db.CheckSchemaParams{{StorageName: "valid_structs", IDField: "ID", Value: &testStructWithID{}}}
+	if !reflect.DeepEqual(sdb.params, want) {
+		t.Fatalf("params should be %v, but got %v", want, sdb.params)
+	}
+}
+
+func schemaCheck_Invalid(t *testing.T) {
+	errSchema := errors.New("column not found")
+	f := New(testStructWithID{}).
+		WithDB(&schemaDB{Config: mockf.NewConfig(), err: errSchema}).
+		WithStorageName("invalid_structs").
+		WithSchemaCheck(mockCTX)
+
+	if _, err := f.Build(mockCTX).Get(); !errors.Is(err, errSchema) {
+		t.Fatalf("error should be %v, but got %v", errSchema, err)
+	}
+
+	if _, err := f.BuildList(mockCTX, 2).Get(); !errors.Is(err, errSchema) {
+		t.Fatalf("error should be %v, but got %v", errSchema, err)
+	}
+}
+
+func schemaCheck_Cached(t *testing.T) {
+	sdb := &schemaDB{Config: mockf.NewConfig()}
+	New(testStructWithID{}).WithDB(sdb).WithStorageName("cached_structs").WithSchemaCheck(mockCTX)
+	New(testStructWithID{}).WithDB(sdb).WithStorageName("cached_structs").WithSchemaCheck(mockCTX)
+
+	if len(sdb.params) != 1 {
+		t.Fatalf("schema should be checked once, but got %v", len(sdb.params))
+	}
+
+	// the other storage is checked again
+	New(testStructWithID{}).WithDB(sdb).WithStorageName("other_cached_structs").WithSchemaCheck(mockCTX)
+	if len(sdb.params) != 2 {
+		t.Fatalf("schema of the other storage should be checked, but got %v", len(sdb.params))
+	}
+}
+
+func schemaCheck_NoDB(t *testing.T) {
+	f := New(testStructWithID{}).WithSchemaCheck(mockCTX)
+
+	if _, err := f.Build(mockCTX).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func schemaCheck_Columns(t *testing.T) {
+	sdb := &schemaDB{Config: mockf.NewConfig()}
+	f := New(testExpectStruct{}).
+		WithDB(sdb).
+		WithStorageName("view_structs").
+		WithColumns("Email").
+		WithSchemaCheck(mockCTX)
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := []string{"ID", "Age", "Nickname"}
+	if len(sdb.params) != 1 || !reflect.DeepEqual(sdb.params[0].OmitFields, want) {
+		t.Fatalf("omitted fields should be %v, but got %v", want, sdb.params)
+	}
+
+	// the same storage with the other columns is checked again
+	New(testExpectStruct{}).WithDB(sdb).WithStorageName("view_structs").WithSchemaCheck(mockCTX)
+	if len(sdb.params) != 2 {
+		t.Fatalf("schema with the other columns should be checked, but got %v", len(sdb.params))
+	}
+}
+
+func schemaCheck_NotSupported(t *testing.T) {
+	f := New(testStructWithID{}).WithDB(mockf.NewConfig()).WithSchemaCheck(mockCTX)
+
+	if _, err := f.Build(mockCTX).Get(); !errors.Is(err, errSchemaCheckNotSupported) {
+		t.Fatalf("error should be %v, but got %v", errSchemaCheckNotSupported, err)
+	}
+}
+
+func TestTimeSlice(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when slice of times, set distinct times":    timeSlice_Distinct,
+		"when time tag, generate within the window":  timeSlice_Tag,
+		"when nested slice, set distinct times":      timeSlice_Nested,
+		"when time tag on other slice, return error": timeSlice_InvalidTag,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+// checkDistinctTimes checks if the times are distinct in ascending order
+func checkDistinctTimes(times []time.Time) error {
+	if len(times) != timeSliceLen {
+		return fmt.Errorf("times should have %d values, got %d", timeSliceLen, len(times))
+	}
+
+	for i := 1; i < len(times); i++ {
+		if !times[i].After(times[i-1]) {
+			return fmt.Errorf("times should be distinct in ascending order, got %v", times)
+		}
+	}
+
+	return nil
+}
+
+func timeSlice_Distinct(t *testing.T) {
+	type testStruct struct {
+		Schedule []time.Time
+		Windows  []*time.Time
+	}
+
+	val, err := New(testStruct{}).Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := checkDistinctTimes(val.Schedule); err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+
+	windows := make([]time.Time, len(val.Windows))
+	for i, w := range val.Windows {
+		if w == nil {
+			t.Fatalf("Windows[%d] should not be nil", i)
+		}
+		windows[i] = *w
+	}
+	if err := checkDistinctTimes(windows); err != nil {
+		t.Fatalf("Windows: %v", err)
+	}
+
+	// the distinct times are not truncated to the same second
+	if val.Schedule[1].Sub(val.Schedule[0]) < time.Second {
+		t.Fatalf("times should be spaced by a second, got %v", val.Schedule)
+	}
+}
+
+func timeSlice_Tag(t *testing.T) {
+	type testStruct struct {
+		Schedule []time.Time `gofacto:"future,window:1h,tz:UTC"`
+	}
+
+	now := time.Now()
+	val, err := New(testStruct{}).Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := checkDistinctTimes(val.Schedule); err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+
+	for _, s := range val.Schedule {
+		if !s.After(now) || s.After(now.Add(time.Hour+time.Second)) {
+			t.Fatalf("time should be within 1h after now, got %v", s)
+		}
+		if s.Location() != time.UTC {
+			t.Fatalf("time should be in UTC, got %v", s.Location())
+		}
+	}
+}
+
+func timeSlice_Nested(t *testing.T) {
+	type testStruct struct {
+		Schedules [][]time.Time
+	}
+
+	val, err := New(testStruct{}).Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(val.Schedules) != 1 {
+		t.Fatalf("Schedules should have 1 value, got %v", len(val.Schedules))
+	}
+
+	if err := checkDistinctTimes(val.Schedules[0]); err != nil {
+		t.Fatalf("Schedules[0]: %v", err)
+	}
+}
+
+func timeSlice_InvalidTag(t *testing.T) {
+	type testStruct struct {
+		Names []string `gofacto:"past"`
+	}
+
+	f := New(testStruct{})
+	if !errors.Is(f.configErr, errTagFormat) {
+		t.Fatalf("error should be %v, got %v", errTagFormat, f.configErr)
+	}
+}
+
+func TestInsertError(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when insert fails, wrap with fields and redacted row": insertError_Redacted,
+		"when full preview, preview values as they are":        insertError_Full,
+		"when no preview, omit values":                         insertError_None,
+		"when database reports row, use the reported context":  insertError_ReportedRow,
+		"when association fails, wrap with the failed node":    insertError_Assoc,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+// testErrorStruct is a struct to test the context of the insert error
+type testErrorStruct struct {
+	ID     int
+	UserID int
+	Email  string
+	Score  *float64
+}
+
+func insertError_Redacted(t *testing.T) {
+	errInsert := errors.New("duplicate key")
+	f := New(testErrorStruct{}).
+		WithDB(mockf.NewConfig().FailOnCall(1, errInsert)).
+		WithBlueprint(func(i int) testErrorStruct { return testErrorStruct{UserID: 5, Email: "a@b.c"} })
+
+	_, err := f.Build(mockCTX).Insert()
+	if !errors.Is(err, errInsert) {
+		t.Fatalf("error should be %v, but got %v", errInsert, err)
+	}
+
+	var ie *InsertError
+	if !errors.As(err, &ie) {
+		t.Fatalf("error should be InsertError, but got %T", err)
+	}
+
+	want := "insert into test_error_structs (UserID, Email, Score) row 0 [5, <redacted 5 chars>, 1]: duplicate key"
+	if err.Error() != want {
+		t.Fatalf("error should be %q, but got %q", want, err.Error())
+	}
+}
+
+func insertError_Full(t *testing.T) {
+	errInsert := errors.New("duplicate key")
+	f := New(testErrorStruct{}).
+		WithDB(mockf.NewConfig().FailOnCall(1, errInsert)).
+		WithBlueprint(func(i int) testErrorStruct { return testErrorStruct{UserID: 5, Email: "a@b.c"} }).
+		WithIsSetZeroValue(false).
+		WithErrorPreview(ErrorPreviewFull)
+
+	_, err := f.BuildList(mockCTX, 1).Insert()
+
+	want := `insert into test_error_structs (UserID, Email, Score) row 0 [5, "a@b.c", NULL]: duplicate key`
+	if err == nil || err.Error() != want {
+		t.Fatalf("error should be %q, but got %v", want, err)
+	}
+}
+
+func insertError_None(t *testing.T) {
+	errInsert := errors.New("duplicate key")
+	f := New(testErrorStruct{}).
+		WithDB(mockf.NewConfig().FailOnCall(1, errInsert)).
+		WithErrorPreview(ErrorPreviewNone)
+
+	_, err := f.BuildList(mockCTX, 2).Insert()
+
+	// the failed row of the list is unknown
+	want := "insert into test_error_structs (UserID, Email, Score): duplicate key"
+	if err == nil || err.Error() != want {
+		t.Fatalf("error should be %q, but got %v", want, err)
+	}
+}
+
+// rowErrorDB is the database failing InsertList with the context of the failed row
+type rowErrorDB struct {
+	*mockf.Config
+	err *db.InsertError
+}
+
+func (d *rowErrorDB) InsertList(ctx context.Context, params db.InsertListParams) ([]interface{}, error) {
+	return nil, d.err
+}
+
+func insertError_ReportedRow(t *testing.T) {
+	errInsert := errors.New("foreign key violation")
+	f := New(testErrorStruct{}).WithDB(&rowErrorDB{
+		Config: mockf.NewConfig(),
+		err:    &db.InsertError{Columns: []string{"user_id", "email"}, Index: 1, Row: []interface{}{7, []byte("secret")}, Err: errInsert},
+	})
+
+	_, err := f.BuildList(mockCTX, 2).Insert()
+	if !errors.Is(err, errInsert) {
+		t.Fatalf("error should be %v, but got %v", errInsert, err)
+	}
+
+	want := "insert into test_error_structs (user_id, email) row 1 [7, <redacted 6 bytes>]: foreign key violation"
+	if err.Error() != want {
+		t.Fatalf("error should be %q, but got %q", want, err.Error())
+	}
+}
+
+func insertError_Assoc(t *testing.T) {
+	errInsert := errors.New("check violation")
+	f := New(testAssocStruct{}).WithDB(mockf.NewConfig().FailOnCall(2, errInsert))
+
+	assVal := testStructWithID{}
+	assVal2 := testStructWithID2{}
+	assVal3 := testStructWithID3{}
+	_, err := f.Build(mockCTX).WithOne(&assVal, &assVal2, &assVal3).Insert()
+
+	var ie *InsertError
+	if !errors.As(err, &ie) {
+		t.Fatalf("error should be InsertError, but got %v", err)
+	}
+
+	if ie.StorageName != "test_struct_with_id3s" || ie.Index != 0 {
+		t.Fatalf("error should be of the row 0 of test_struct_with_id3s, but got %v", ie)
+	}
+}
+
+type testPerson struct {
+	ID   int
+	Name string
+}
+
+type testPersonNote struct {
+	ID       int
+	PersonID int `gofacto:"foreignKey,struct:testPerson"`
+	Person   *testPerson
+}
+
+func TestRegisterTableName(t *testing.T) {
+	RegisterTableName(reflect.TypeOf(&testPerson{}), "people")
+	t.Cleanup(func() {
+		tableNamesByType.Delete(reflect.TypeOf(testPerson{}))
+		tableNamesByStruct.Delete("testPerson")
+	})
+
+	for _, fn := range map[string]func(*testing.T){
+		"when factory of registered type, use registered name":        registerTableName_Factory,
+		"when foreignKey tag of registered type, use registered name": registerTableName_Tag,
+		"when WithStorageName, take precedence":                       registerTableName_WithStorageName,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func registerTableName_Factory(t *testing.T) {
+	storage := mockf.NewConfig()
+	f := New(testPerson{}).WithDB(storage)
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if n := len(storage.Inserted("people")); n != 1 {
+		t.Fatalf("people should have 1 value, but got %v", n)
+	}
+}
+
+func registerTableName_Tag(t *testing.T) {
+	storage := mockf.NewConfig()
+	f := New(testPersonNote{}).WithDB(storage)
+
+	person := testPerson{}
+	val, err := f.Build(mockCTX).WithOne(&person).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.PersonID != person.ID {
+		t.Fatalf("PersonID should be %v, but got %v", person.ID, val.PersonID)
+	}
+
+	if n := len(storage.Inserted("people")); n != 1 {
+		t.Fatalf("people should have 1 value, but got %v", n)
+	}
+
+	if n := len(storage.Inserted("test_persons")); n != 0 {
+		t.Fatalf("test_persons should have no value, but got %v", n)
+	}
+}
+
+func registerTableName_WithStorageName(t *testing.T) {
+	storage := mockf.NewConfig()
+	f := New(testPerson{}).WithDB(storage).WithStorageName("persons")
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if n := len(storage.Inserted("persons")); n != 1 {
+		t.Fatalf("persons should have 1 value, but got %v", n)
+	}
+}
+
+func TestForTest(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when parallel children, isolate index and clean up": forTest_Parallel,
+		"when child is configured, not affect parent":        forTest_Isolated,
+		"when WithRand, seed by test name":                   forTest_Rand,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func forTest_Parallel(t *testing.T) {
+	storage := mockf.NewConfig()
+	f := New(testPerson{}).WithDB(storage)
+
+	t.Run("group", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			t.Run(fmt.Sprintf("child%d", i), func(t *testing.T) {
+				t.Parallel()
+				child := f.ForTest(t)
+
+				vals, err := child.BuildList(mockCTX, 2).Insert()
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+
+				if vals[0].Name != "test1" || vals[1].Name != "test2" {
+					t.Fatalf("names should be test1 and test2, but got %v and %v", vals[0].Name, vals[1].Name)
+				}
+			})
+		}
+	})
+
+	if n := len(storage.Inserted("test_persons")); n != 10 {
+		t.Fatalf("inserted should be 10, but got %v", n)
+	}
+
+	if n := len(storage.Deleted("test_persons")); n != 10 {
+		t.Fatalf("deleted should be 10, but got %v", n)
+	}
+
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Name != "test1" {
+		t.Fatalf("name of parent should be test1, but got %v", val.Name)
+	}
+}
+
+func forTest_Isolated(t *testing.T) {
+	f := New(testPerson{}).WithTrait("named", func(p *testPerson) {
+		p.Name = "parent"
+	})
+
+	child := f.ForTest(t).WithTrait("named", func(p *testPerson) {
+		p.Name = "child"
+	})
+
+	childVal, err := child.Build(mockCTX).SetTrait("named").Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	val, err := f.Build(mockCTX).SetTrait("named").Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if childVal.Name != "child" {
+		t.Fatalf("name of child should be child, but got %v", childVal.Name)
+	}
+
+	if val.Name != "parent" {
+		t.Fatalf("name of parent should be parent, but got %v", val.Name)
+	}
+}
+
+func forTest_Rand(t *testing.T) {
+	f := New(testRandStruct{}).WithRand(rand.New(rand.NewSource(1)))
+
+	genOffset := func() time.Duration {
+		now := time.Now()
+		val, err := f.ForTest(t).Build(mockCTX).Get()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		return val.ScheduledAt.Sub(now)
+	}
+
+	// the offsets differ only by the time passed between now and generating
+	first, second := genOffset(), genOffset()
+	if diff := first - second; diff > time.Second || diff < -time.Second {
+		t.Fatalf("offsets of the children of the same test should be equal, but got %v and %v", first, second)
+	}
+}
+
+func TestBuildWithChildren(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when parent is referenced, insert parent and children":    buildWithChildren_Success,
+		"when parent factory is configured, insert parent by it":   buildWithChildren_ParentConfig,
+		"when inserting by child factory again, not insert parent": buildWithChildren_InsertAgain,
+		"when parent is not referenced, return error":              buildWithChildren_NotReferenced,
+		"when n is invalid, return error":                          buildWithChildren_InvalidN,
+		"when inserting children fails, delete parent":             buildWithChildren_ChildFails,
+		"when db supports tx, insert within transaction":           buildWithChildren_Tx,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func buildWithChildren_Success(t *testing.T) {
+	storage := mockf.NewConfig()
+	parent := New(testPerson{}).WithDB(storage).WithBlueprint(func(i int) testPerson {
+		return testPerson{Name: fmt.Sprintf("person%d", i)}
+	})
+	child := New(testPersonNote{}).WithDB(storage)
+
+	person, notes, err := BuildWithChildren(mockCTX, parent, child, 3)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if person.ID == 0 {
+		t.Fatalf("ID of parent should be set")
+	}
+
+	if person.Name != "person1" {
+		t.Fatalf("name of parent should be person1, but got %v", person.Name)
+	}
+
+	if len(notes) != 3 {
+		t.Fatalf("children should be 3, but got %v", len(notes))
+	}
+
+	for _, n := range notes {
+		if n.PersonID != person.ID {
+			t.Fatalf("PersonID should be %v, but got %v", person.ID, n.PersonID)
+		}
+	}
+
+	if n := len(storage.Inserted("test_persons")); n != 1 {
+		t.Fatalf("test_persons should have 1 value, but got %v", n)
+	}
+
+	if n := len(storage.Inserted("test_person_notes")); n != 3 {
+		t.Fatalf("test_person_notes should have 3 values, but got %v", n)
+	}
+}
+
+func buildWithChildren_ChildFails(t *testing.T) {
+	errInsert := errors.New("insert error")
+	storage := mockf.NewConfig().FailOnCall(2, errInsert)
+	parent := New(testPerson{}).WithDB(storage)
+	child := New(testPersonNote{}).WithDB(storage)
+
+	if _, _, err := BuildWithChildren(mockCTX, parent, child, 3); !errors.Is(err, errInsert) {
+		t.Fatalf("error should be %v, but got %v", errInsert, err)
+	}
+
+	if n := len(storage.Deleted("test_persons")); n != 1 {
+		t.Fatalf("parent should be deleted, but got %v", n)
+	}
+
+	if len(parent.inserted) != 0 {
+		t.Fatalf("parent should not be tracked by Cleanup, but got %v", parent.inserted)
+	}
+}
+
+func buildWithChildren_Tx(t *testing.T) {
+	mdb := &mockTxDB{}
+	parent := New(testPerson{}).WithDB(mdb)
+	child := New(testPersonNote{}).WithDB(mdb)
+
+	person, notes, err := BuildWithChildren(mockCTX, parent, child, 2)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if mdb.tx == nil || !mdb.tx.isCommitted {
+		t.Fatalf("transaction should be committed")
+	}
+
+	for _, n := range notes {
+		if n.PersonID != person.ID {
+			t.Fatalf("PersonID should be %v, but got %v", person.ID, n.PersonID)
+		}
+	}
+
+	if parent.db != mdb || child.db != mdb {
+		t.Fatalf("database of factories should be restored")
+	}
+}
+
+func buildWithChildren_ParentConfig(t *testing.T) {
+	parentDB, childDB := mockf.NewConfig(), mockf.NewConfig()
+	parent := New(testPerson{}).WithDB(parentDB).WithStorageName("people").
+		WithBlueprint(func(i int) testPerson {
+			return testPerson{Name: "admin"}
+		})
+	child := New(testPersonNote{}).WithDB(childDB)
+
+	person, _, err := BuildWithChildren(mockCTX, parent, child, 2)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if person.Name != "admin" {
+		t.Fatalf("name of parent should be admin, but got %v", person.Name)
+	}
+
+	if n := len(parentDB.Inserted("people")); n != 1 {
+		t.Fatalf("people should have 1 value, but got %v", n)
+	}
+
+	if n := len(childDB.Inserted("test_persons")) + len(childDB.Inserted("people")); n != 0 {
+		t.Fatalf("parent should not be inserted into the database of the child factory, but got %v", n)
+	}
+
+	if n := len(childDB.Inserted("test_person_notes")); n != 2 {
+		t.Fatalf("test_person_notes should have 2 values, but got %v", n)
+	}
+}
+
+func buildWithChildren_InsertAgain(t *testing.T) {
+	storage := mockf.NewConfig()
+	parent := New(testPerson{}).WithDB(storage)
+	child := New(testPersonNote{}).WithDB(storage)
+
+	person, _, err := BuildWithChildren(mockCTX, parent, child, 2)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if n := len(child.associations); n != 0 {
+		t.Fatalf("child factory should have no association, but got %v", n)
+	}
+
+	note, err := child.Build(mockCTX).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if n := len(storage.Inserted("test_persons")); n != 1 {
+		t.Fatalf("test_persons should have 1 value, but got %v", n)
+	}
+
+	if note.PersonID == person.ID {
+		t.Fatalf("PersonID of the later child should not point at the parent %v", person.ID)
+	}
+}
+
+func buildWithChildren_NotReferenced(t *testing.T) {
+	parent := New(testPerson{})
+	child := New(testStructWithID{}).WithDB(mockf.NewConfig())
+
+	_, _, err := BuildWithChildren(mockCTX, parent, child, 3)
+	if !errors.Is(err, errParentNotReferenced) {
+		t.Fatalf("error should be %v, but got %v", errParentNotReferenced, err)
+	}
+}
+
+func buildWithChildren_InvalidN(t *testing.T) {
+	parent := New(testPerson{})
+	child := New(testPersonNote{}).WithDB(mockf.NewConfig())
+
+	_, _, err := BuildWithChildren(mockCTX, parent, child, 0)
+	if !errors.Is(err, errBuildListNGreaterThanZero) {
+		t.Fatalf("error should be %v, but got %v", errBuildListNGreaterThanZero, err)
+	}
+}
+
+// testMoney simulates the decimal types of the third-party packages, e.g. decimal.Decimal of shopspring,
+// which have the unexported fields and implement encoding.TextUnmarshaler
+type testMoney struct {
+	value string
+}
+
+func (m *testMoney) UnmarshalText(b []byte) error {
+	m.value = string(b)
+	return nil
+}
+
+type testDecimalStruct struct {
+	Rat      big.Rat
+	RatP     *big.Rat
+	Cents    int64      `gofacto:"decimal:10,2"`
+	Price    float64    `gofacto:"decimal:6,2"`
+	PriceP   *float64   `gofacto:"decimal:6,2"`
+	Amount   string     `gofacto:"decimal:5,3"`
+	Money    testMoney  `gofacto:"decimal:12,0"`
+	MoneyP   *testMoney `gofacto:"decimal:12,4"`
+	Fraction big.Rat    `gofacto:"decimal:2,2"`
+}
+
+func TestDecimal(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when decimal types, set exact values":       decimal_Values,
+		"when index exceeds precision, wrap around":  decimal_Wrap,
+		"when strict mode, decimal types are filled": decimal_Strict,
+		"when invalid decimal tag, return error":     decimal_InvalidTag,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func decimal_Values(t *testing.T) {
+	f := New(testDecimalStruct{})
+
+	vals, err := f.BuildList(mockCTX, 12).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	val := vals[11]
+	if got := val.Rat.FloatString(2); got != "12.12" {
+		t.Fatalf("Rat should be 12.12, got %v", got)
+	}
+
+	if got := val.RatP.FloatString(2); got != "12.12" {
+		t.Fatalf("RatP should be 12.12, got %v", got)
+	}
+
+	if val.Cents != 1212 {
+		t.Fatalf("Cents should be 1212, got %v", val.Cents)
+	}
+
+	if val.Price != 12.12 || *val.PriceP != 12.12 {
+		t.Fatalf("Price and PriceP should be 12.12, got %v and %v", val.Price, *val.PriceP)
+	}
+
+	if val.Amount != "12.012" {
+		t.Fatalf("Amount should be 12.012, got %v", val.Amount)
+	}
+
+	if val.Money.value != "12" {
+		t.Fatalf("Money should be 12, got %v", val.Money.value)
+	}
+
+	if val.MoneyP.value != "12.0012" {
+		t.Fatalf("MoneyP should be 12.0012, got %v", val.MoneyP.value)
+	}
+
+	if got := val.Fraction.FloatString(2); got != "0.12" {
+		t.Fatalf("Fraction should be 0.12, got %v", got)
+	}
+}
+
+func decimal_Wrap(t *testing.T) {
+	type testStruct struct {
+		Price float64 `gofacto:"decimal:3,1"`
+		Ratio string  `gofacto:"decimal:1,1"`
+	}
+
+	f := New(testStruct{})
+
+	vals, err := f.BuildList(mockCTX, 100).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if got := vals[98].Price; got != 99.9 {
+		t.Fatalf("Price should be 99.9, got %v", got)
+	}
+
+	// 100 wraps around to 0.0, which is replaced by the smallest non-zero value
+	if got := vals[99].Price; got != 0.1 {
+		t.Fatalf("Price should be 0.1, got %v", got)
+	}
+
+	if got := vals[99].Ratio; got != "0.1" {
+		t.Fatalf("Ratio should be 0.1, got %v", got)
+	}
+}
+
+func decimal_Strict(t *testing.T) {
+	f := New(testDecimalStruct{}).WithIsStrict(true)
+
+	if _, err := f.Build(mockCTX).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func decimal_InvalidTag(t *testing.T) {
+	type missingScale struct {
+		Price float64 `gofacto:"decimal:10"`
+	}
+	type scaleExceeded struct {
+		Price float64 `gofacto:"decimal:2,3"`
+	}
+	type invalidType struct {
+		IsPaid bool `gofacto:"decimal:10,2"`
+	}
+	type overflow struct {
+		Cents int16 `gofacto:"decimal:6,2"`
+	}
+
+	for name, err := range map[string]error{
+		"missing scale":  New(missingScale{}).configErr,
+		"scale exceeded": New(scaleExceeded{}).configErr,
+		"invalid type":   New(invalidType{}).configErr,
+		"overflow":       New(overflow{}).configErr,
+	} {
+		if !errors.Is(err, errTagFormat) {
+			t.Fatalf("%s: error should be %v, got %v", name, errTagFormat, err)
+		}
+	}
+}
+
+type testTimestampStruct struct {
+	ID        int
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+	DueAt     time.Time `gofacto:"future,window:24h"`
+}
+
+// omitDB is the database recording the omitted fields of each insert
+type omitDB struct {
+	*mockf.Config
+	omitFields [][]string
+}
+
+func (d *omitDB) Insert(ctx context.Context, params db.InsertParams) (interface{}, error) {
+	d.omitFields = append(d.omitFields, params.OmitFields)
+	return d.Config.Insert(ctx, params)
+}
+
+func (d *omitDB) InsertList(ctx context.Context, params db.InsertListParams) ([]interface{}, error) {
+	d.omitFields = append(d.omitFields, params.OmitFields)
+	return d.Config.InsertList(ctx, params)
+}
+
+func TestWithTimestamps(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when now mode, set same time of clock":          withTimestamps_Now,
+		"when now mode with blueprint, keep values":      withTimestamps_NowBlueprint,
+		"when clock, generate time tag relative to it":   withTimestamps_ClockTimeTag,
+		"when db default mode, leave zero and omit":      withTimestamps_DBDefault,
+		"when db default mode with set value, not omit":  withTimestamps_DBDefaultSet,
+		"when db default mode with strict, not complain": withTimestamps_DBDefaultStrict,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+var testClockNow = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func withTimestamps_Now(t *testing.T) {
+	f := New(testTimestampStruct{}).
+		WithTimestamps(TimestampNow).
+		WithClock(func() time.Time { return testClockNow })
+
+	vals, err := f.BuildList(mockCTX, 2).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, v := range vals {
+		if !v.CreatedAt.Equal(testClockNow) {
+			t.Fatalf("CreatedAt should be %v, but got %v", testClockNow, v.CreatedAt)
+		}
+
+		if !v.UpdatedAt.Equal(testClockNow) {
+			t.Fatalf("UpdatedAt should be %v, but got %v", testClockNow, *v.UpdatedAt)
+		}
+	}
+}
+
+func withTimestamps_NowBlueprint(t *testing.T) {
+	createdAt := testClockNow.Add(-time.Hour)
+	f := New(testTimestampStruct{}).
+		WithTimestamps(TimestampNow).
+		WithClock(func() time.Time { return testClockNow }).
+		WithBlueprint(func(i int) testTimestampStruct {
+			return testTimestampStruct{CreatedAt: createdAt}
+		})
+
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if !val.CreatedAt.Equal(createdAt) {
+		t.Fatalf("CreatedAt should be %v, but got %v", createdAt, val.CreatedAt)
+	}
+
+	if !val.UpdatedAt.Equal(testClockNow) {
+		t.Fatalf("UpdatedAt should be %v, but got %v", testClockNow, *val.UpdatedAt)
+	}
+}
+
+func withTimestamps_ClockTimeTag(t *testing.T) {
+	f := New(testTimestampStruct{}).WithClock(func() time.Time { return testClockNow })
+
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if !val.DueAt.After(testClockNow) || val.DueAt.After(testClockNow.Add(24*time.Hour)) {
+		t.Fatalf("DueAt should be within 24h after %v, but got %v", testClockNow, val.DueAt)
+	}
+
+	// the timestamp fields are generated as the other time fields by default
+	if !val.CreatedAt.Equal(testClockNow) {
+		t.Fatalf("CreatedAt should be %v, but got %v", testClockNow, val.CreatedAt)
+	}
+}
+
+func withTimestamps_DBDefault(t *testing.T) {
+	storage := &omitDB{Config: mockf.NewConfig()}
+	f := New(testTimestampStruct{}).WithDB(storage).WithTimestamps(TimestampDBDefault)
+
+	val, err := f.Build(mockCTX).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if !val.CreatedAt.IsZero() || val.UpdatedAt != nil {
+		t.Fatalf("timestamps should be zero, but got %v and %v", val.CreatedAt, val.UpdatedAt)
+	}
+
+	if val.Name == "" || val.DueAt.IsZero() {
+		t.Fatalf("other fields should be set, but got %v", val)
+	}
+
+	if _, err := f.BuildList(mockCTX, 2).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := [][]string{{"CreatedAt", "UpdatedAt"}, {"CreatedAt", "UpdatedAt"}}
+	if !reflect.DeepEqual(storage.omitFields, want) {
+		t.Fatalf("omitted fields should be %v, but got %v", want, storage.omitFields)
+	}
+}
+
+func withTimestamps_DBDefaultSet(t *testing.T) {
+	storage := &omitDB{Config: mockf.NewConfig()}
+	f := New(testTimestampStruct{}).WithDB(storage).WithTimestamps(TimestampDBDefault)
+
+	ow := testTimestampStruct{CreatedAt: testClockNow}
+	if _, err := f.BuildList(mockCTX, 2).Overwrites(ow).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := [][]string{{"UpdatedAt"}}
+	if !reflect.DeepEqual(storage.omitFields, want) {
+		t.Fatalf("omitted fields should be %v, but got %v", want, storage.omitFields)
+	}
+}
+
+func withTimestamps_DBDefaultStrict(t *testing.T) {
+	f := New(testTimestampStruct{}).WithTimestamps(TimestampDBDefault).WithIsStrict(true)
+
+	if _, err := f.Build(mockCTX).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+type testTransformStruct struct {
+	ID       int
+	SSN      string
+	PersonID int `gofacto:"foreignKey,struct:testPerson"`
+}
+
+// reverseTransform reverses the string, which stands for encrypting
+func reverseTransform(v interface{}) (interface{}, error) {
+	r := []rune(v.(string))
+	slices.Reverse(r)
+	return string(r), nil
+}
+
+func TestWithInsertTransform(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when insert, store transformed value":           withInsertTransform_Insert,
+		"when insert list, store transformed values":     withInsertTransform_InsertList,
+		"when with associations, transform only factory": withInsertTransform_Assoc,
+		"when transform fails, return error":             withInsertTransform_Error,
+		"when type is different, return error":           withInsertTransform_TypeDiff,
+		"when field not found, return error":             withInsertTransform_NotFound,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func withInsertTransform_Insert(t *testing.T) {
+	storage := mockf.NewConfig()
+	f := New(testTransformStruct{}).WithDB(storage).WithInsertTransform("SSN", reverseTransform)
+
+	val, err := f.Build(mockCTX).Overwrite(testTransformStruct{SSN: "123-45"}).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.ID == 0 {
+		t.Fatalf("ID should be set")
+	}
+
+	if val.SSN != "123-45" {
+		t.Fatalf("SSN should be 123-45, but got %v", val.SSN)
+	}
+
+	inserted := storage.Inserted("test_transform_structs")
+	if len(inserted) != 1 {
+		t.Fatalf("inserted should be 1, but got %v", len(inserted))
+	}
+
+	want := &testTransformStruct{ID: val.ID, SSN: "54-321", PersonID: val.PersonID}
+	if !reflect.DeepEqual(inserted[0], want) {
+		t.Fatalf("inserted should be %v, but got %v", want, inserted[0])
+	}
+
+	if err := f.Cleanup(mockCTX); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if n := len(storage.Deleted("test_transform_structs")); n != 1 {
+		t.Fatalf("deleted should be 1, but got %v", n)
+	}
+}
+
+func withInsertTransform_InsertList(t *testing.T) {
+	storage := mockf.NewConfig()
+	f := New(testTransformStruct{}).WithDB(storage).WithInsertTransform("SSN", reverseTransform)
+
+	vals, err := f.BuildList(mockCTX, 2).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	inserted := storage.Inserted("test_transform_structs")
+	for i, v := range vals {
+		if v.SSN != fmt.Sprintf("test%d", i+1) {
+			t.Fatalf("SSN should be test%d, but got %v", i+1, v.SSN)
+		}
+
+		stored := inserted[i].(*testTransformStruct)
+		if stored.ID != v.ID || stored.SSN != fmt.Sprintf("%dtset", i+1) {
+			t.Fatalf("stored should have ID %v and reversed SSN, but got %v", v.ID, stored)
+		}
+	}
+}
+
+func withInsertTransform_Assoc(t *testing.T) {
+	storage := mockf.NewConfig()
+	f := New(testTransformStruct{}).WithDB(storage).WithInsertTransform("SSN", reverseTransform)
+
+	person := testPerson{Name: "abc"}
+	val, err := f.Build(mockCTX).Overwrite(testTransformStruct{SSN: "123"}).WithOne(&person).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.SSN != "123" || val.PersonID != person.ID || val.ID == 0 {
+		t.Fatalf("value should have plain SSN, PersonID %v, and ID, but got %v", person.ID, val)
+	}
+
+	stored := storage.Inserted("test_transform_structs")[0].(*testTransformStruct)
+	if stored.SSN != "321" {
+		t.Fatalf("stored SSN should be 321, but got %v", stored.SSN)
+	}
+
+	if person.Name != "abc" {
+		t.Fatalf("association should not be transformed, but got %v", person.Name)
+	}
+}
+
+func withInsertTransform_Error(t *testing.T) {
+	storage := mockf.NewConfig()
+	errEncrypt := errors.New("encrypt failed")
+	f := New(testTransformStruct{}).WithDB(storage).WithInsertTransform("SSN", func(interface{}) (interface{}, error) {
+		return nil, errEncrypt
+	})
+
+	_, err := f.Build(mockCTX).Insert()
+	if !errors.Is(err, errEncrypt) {
+		t.Fatalf("error should be %v, but got %v", errEncrypt, err)
+	}
+
+	if n := len(storage.Inserted("test_transform_structs")); n != 0 {
+		t.Fatalf("inserted should be 0, but got %v", n)
+	}
+}
+
+func withInsertTransform_TypeDiff(t *testing.T) {
+	f := New(testTransformStruct{}).WithDB(mockf.NewConfig()).WithInsertTransform("SSN", func(interface{}) (interface{}, error) {
+		return 123, nil
+	})
+
+	_, err := f.BuildList(mockCTX, 2).Insert()
+	if !errors.Is(err, errTypeDiff) {
+		t.Fatalf("error should be %v, but got %v", errTypeDiff, err)
+	}
+}
+
+func withInsertTransform_NotFound(t *testing.T) {
+	f := New(testTransformStruct{}).WithInsertTransform("Phone", reverseTransform)
+
+	_, err := f.Build(mockCTX).Get()
+	if !errors.Is(err, errFieldNotFound) {
+		t.Fatalf("error should be %v, but got %v", errFieldNotFound, err)
+	}
+}
+
+func TestWithIsStrict(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when all fields populated, no error":            strict_AllPopulated,
+		"when fields can't be populated, return error":   strict_NotPopulated,
+		"when fields are ignored, not listed":            strict_IgnoredFields,
+		"when not strict, fields are silently left zero": strict_Disabled,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testExpectStruct struct {
+	ID       int
+	Email    string
+	Age      int64
+	Nickname *string
+}
+
+// mockTB is a mock implementation of testing.TB recording the failure instead of stopping the test
+type mockTB struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (m *mockTB) Helper() {}
+
+func (m *mockTB) Fatalf(format string, args ...interface{}) {
+	m.failed = true
+	m.msg = fmt.Sprintf(format, args...)
+}
+
+func TestExpectRow(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when row matches, return it":                 expectRow_Match,
+		"when value type is convertible, convert it":  expectRow_Convert,
+		"when pointer field, match value and nil":     expectRow_Pointer,
+		"when no row matches, fail with closest rows": expectRow_NoMatch,
+		"when storage is empty, fail with empty":      expectRow_Empty,
+		"when field not found, fail":                  expectRow_FieldNotFound,
+		"when value type is different, fail":          expectRow_TypeDiff,
+		"when database doesn't support finding, fail": expectRow_NotSupported,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func expectRow_Match(t *testing.T) {
+	f := New(testExpectStruct{}).WithDB(mockf.NewConfig())
+	vals, err := f.BuildList(mockCTX, 3).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	got := f.ExpectRow(mockCTX, t, WithField("Email", vals[1].Email), WithField("Age", vals[1].Age))
+	if err := testutils.CompareVal(got, vals[1]); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func expectRow_Convert(t *testing.T) {
+	f := New(testExpectStruct{}).WithDB(mockf.NewConfig())
+	v, err := f.Build(mockCTX).Overwrite(testExpectStruct{Age: 20}).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// the untyped constant is int, while the field is int64
+	got := f.ExpectRow(mockCTX, t, WithField("Age", 20))
+	if got.ID != v.ID {
+		t.Fatalf("ID should be %d, but got %d", v.ID, got.ID)
+	}
+}
+
+func expectRow_Pointer(t *testing.T) {
+	f := New(testExpectStruct{}).WithDB(mockf.NewConfig())
+	nickname := "bob"
+	v1, err := f.Build(mockCTX).Overwrite(testExpectStruct{Nickname: &nickname}).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	v2, err := f.Build(mockCTX).SetZero("Nickname").Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if got := f.ExpectRow(mockCTX, t, WithField("Nickname", "bob")); got.ID != v1.ID {
+		t.Fatalf("ID should be %d, but got %d", v1.ID, got.ID)
+	}
+
+	if got := f.ExpectRow(mockCTX, t, WithField("Nickname", nil)); got.ID != v2.ID {
+		t.Fatalf("ID should be %d, but got %d", v2.ID, got.ID)
+	}
+}
+
+func expectRow_NoMatch(t *testing.T) {
+	f := New(testExpectStruct{}).WithDB(mockf.NewConfig()).WithStorageName("users")
+	if _, err := f.BuildList(mockCTX, 5).Overwrites(
+		testExpectStruct{Email: "a@example.com", Age: 1},
+		testExpectStruct{Email: "b@example.com", Age: 2},
+		testExpectStruct{Email: "c@example.com", Age: 20},
+		testExpectStruct{Email: "d@example.com", Age: 4},
+		testExpectStruct{Email: "e@example.com", Age: 5},
+	).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	mt := &mockTB{}
+	f.ExpectRow(mockCTX, mt, WithField("Email", "x@example.com"), WithField("Age", 20))
+	if !mt.failed {
+		t.Fatalf("test should fail")
+	}
+
+	want := `gofacto: row not found
+no row in users matches Email="x@example.com", Age=20
+closest rows of 5 scanned:
+  ID=3:
+    Email: got "c@example.com", want "x@example.com"
+  ID=1:
+    Email: got "a@example.com", want "x@example.com"
+    Age: got 1, want 20
+  ID=2:
+    Email: got "b@example.com", want "x@example.com"
+    Age: got 2, want 20`
+	if mt.msg != want {
+		t.Fatalf("message should be\n%s\nbut got\n%s", want, mt.msg)
+	}
+}
+
+func expectRow_Empty(t *testing.T) {
+	f := New(testExpectStruct{}).WithDB(mockf.NewConfig()).WithStorageName("users")
+
+	mt := &mockTB{}
+	f.ExpectRow(mockCTX, mt, WithField("Email", "x@example.com"))
+	if !mt.failed {
+		t.Fatalf("test should fail")
+	}
+
+	if !strings.HasSuffix(mt.msg, "users is empty") {
+		t.Fatalf("message should tell the storage is empty, but got %s", mt.msg)
+	}
+}
+
+func expectRow_FieldNotFound(t *testing.T) {
+	f := New(testExpectStruct{}).WithDB(mockf.NewConfig())
+
+	mt := &mockTB{}
+	f.ExpectRow(mockCTX, mt, WithField("Unknown", 1))
+	if !mt.failed || !strings.Contains(mt.msg, errFieldNotFound.Error()) {
+		t.Fatalf("test should fail with %v, but got %q", errFieldNotFound, mt.msg)
+	}
+}
+
+func expectRow_TypeDiff(t *testing.T) {
+	f := New(testExpectStruct{}).WithDB(mockf.NewConfig())
+
+	for _, m := range []RowMatcher{WithField("Email", 1), WithField("Age", "20"), WithField("Age", nil)} {
+		mt := &mockTB{}
+		f.ExpectRow(mockCTX, mt, m)
+		if !mt.failed || !strings.Contains(mt.msg, errValueNotTheSameType.Error()) {
+			t.Fatalf("test should fail with %v, but got %q", errValueNotTheSameType, mt.msg)
+		}
+	}
+}
+
+func expectRow_NotSupported(t *testing.T) {
+	f := New(testExpectStruct{}).WithDB(&mockDB{})
+
+	mt := &mockTB{}
+	f.ExpectRow(mockCTX, mt, WithField("Email", "x@example.com"))
+	if !mt.failed || !strings.Contains(mt.msg, errFindNotSupported.Error()) {
+		t.Fatalf("test should fail with %v, but got %q", errFindNotSupported, mt.msg)
+	}
+}
+
+func TestWithColumns(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when insert, omit fields not selected":           withColumns_Insert,
+		"when with associations, select only factory":     withColumns_Assoc,
+		"when field not found, return error":              withColumns_NotFound,
+		"when no generated ID, omit zero ID and not fill": withColumns_NoGeneratedID,
+		"when no generated ID with set ID, insert ID":     withColumns_NoGeneratedIDSet,
+		"when no generated ID with selected ID, insert":   withColumns_NoGeneratedIDSelected,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func withColumns_Insert(t *testing.T) {
+	storage := &omitDB{Config: mockf.NewConfig()}
+	f := New(testExpectStruct{}).WithDB(storage).WithColumns("Email", "Age")
+
+	val, err := f.Build(mockCTX).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.ID == 0 || val.Nickname == nil {
+		t.Fatalf("ID should be filled, and omitted fields should be kept, but got %v", val)
+	}
+
+	if _, err := f.BuildList(mockCTX, 2).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := [][]string{{"ID", "Nickname"}, {"ID", "Nickname"}}
+	if !reflect.DeepEqual(storage.omitFields, want) {
+		t.Fatalf("omitted fields should be %v, but got %v", want, storage.omitFields)
+	}
+}
+
+func withColumns_Assoc(t *testing.T) {
+	storage := &omitDB{Config: mockf.NewConfig()}
+	f := New(testStructWithID2{}).WithDB(storage).WithColumns("ForeignKey").WithNoGeneratedID(true)
+
+	assVal := testStructWithID3{}
+	val, err := f.Build(mockCTX).WithOne(&assVal).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if assVal.ID == 0 || val.ForeignKey != assVal.ID {
+		t.Fatalf("ForeignKey should be the generated ID of the association %v, but got %v", assVal.ID, val.ForeignKey)
+	}
+
+	if val.ID != 0 {
+		t.Fatalf("ID should not be filled, but got %v", val.ID)
+	}
+
+	want := [][]string{nil, {"ID", "ForeignValue", "Name"}}
+	if !reflect.DeepEqual(storage.omitFields, want) {
+		t.Fatalf("omitted fields should be %v, but got %v", want, storage.omitFields)
+	}
+}
+
+func withColumns_NotFound(t *testing.T) {
+	f := New(testExpectStruct{}).WithColumns("Email", "Unknown")
+
+	if _, err := f.Build(mockCTX).Get(); !errors.Is(err, errFieldNotFound) {
+		t.Fatalf("error should be %v, but got %v", errFieldNotFound, err)
+	}
+}
+
+func withColumns_NoGeneratedID(t *testing.T) {
+	storage := &omitDB{Config: mockf.NewConfig()}
+	f := New(testExpectStruct{}).WithDB(storage).WithNoGeneratedID(true)
+
+	vals, err := f.BuildList(mockCTX, 2).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, v := range vals {
+		if v.ID != 0 {
+			t.Fatalf("ID should not be filled, but got %v", v.ID)
+		}
+	}
+
+	want := [][]string{{"ID"}}
+	if !reflect.DeepEqual(storage.omitFields, want) {
+		t.Fatalf("omitted fields should be %v, but got %v", want, storage.omitFields)
+	}
+
+	// the values without the ID can't be deleted by the ID
+	if err := f.Cleanup(mockCTX); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if deleted := storage.Deleted("test_expect_structs"); len(deleted) != 0 {
+		t.Fatalf("values without ID should not be deleted, but got %v", deleted)
+	}
+}
+
+func withColumns_NoGeneratedIDSet(t *testing.T) {
+	storage := &omitDB{Config: mockf.NewConfig()}
+	f := New(testExpectStruct{}).
+		WithDB(storage).
+		WithBlueprint(func(i int) testExpectStruct { return testExpectStruct{ID: 100 + i} }).
+		WithNoGeneratedID(true)
+
+	val, err := f.Build(mockCTX).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.ID != 101 {
+		t.Fatalf("ID should be kept as %v, but got %v", 101, val.ID)
+	}
+
+	want := [][]string{nil}
+	if !reflect.DeepEqual(storage.omitFields, want) {
+		t.Fatalf("omitted fields should be %v, but got %v", want, storage.omitFields)
+	}
+
+	if err := f.Cleanup(mockCTX); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if deleted := storage.Deleted("test_expect_structs"); len(deleted) != 1 {
+		t.Fatalf("value with ID should be deleted, but got %v", deleted)
+	}
+}
+
+func withColumns_NoGeneratedIDSelected(t *testing.T) {
+	storage := &omitDB{Config: mockf.NewConfig()}
+	f := New(testExpectStruct{}).WithDB(storage).WithColumns("ID", "Email").WithNoGeneratedID(true)
+
+	if _, err := f.Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := [][]string{{"Age", "Nickname"}}
+	if !reflect.DeepEqual(storage.omitFields, want) {
+		t.Fatalf("omitted fields should be %v, but got %v", want, storage.omitFields)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when no association, plan factory table":      plan_NoAssoc,
+		"when associations, plan tables and edges":     plan_Assoc,
+		"when no generated ID, ID is not generated":    plan_NoGeneratedID,
+		"when skip insert, mark factory table skipped": plan_SkipInsert,
+		"when factory has error, return error":         plan_Error,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func plan_NoAssoc(t *testing.T) {
+	cfg := mockf.NewConfig()
+	f := New(testExpectStruct{}).WithDB(cfg).WithColumns("Email", "Age")
+
+	p, err := f.Plan(mockCTX)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := &InsertPlan{
+		Tables: []TablePlan{
+			{Name: "test_expect_structs", Type: "testExpectStruct", Rows: 1, Fields: []string{"Email", "Age"}, GeneratedFields: []string{"ID", "Nickname"}},
+		},
+		Edges: []EdgePlan{},
+	}
+	if err := testutils.CompareVal(p, want); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if cfg.NumCalls() != 0 {
+		t.Fatalf("database should not be called, but got %d calls", cfg.NumCalls())
+	}
+}
+
+func plan_Assoc(t *testing.T) {
+	cfg := mockf.NewConfig()
+	f := New(testStructWithID2{}).WithDB(cfg)
+
+	assVal := testStructWithID3{}
+	b := f.BuildList(mockCTX, 2).WithOne(&assVal)
+	p, err := b.Plan()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	got, err := p.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := `{
+  "tables": [
+    {
+      "name": "test_struct_with_id3s",
+      "type": "testStructWithID3",
+      "rows": 1,
+      "fields": [
+        "Name"
+      ],
+      "generatedFields": [
+        "ID"
+      ]
+    },
+    {
+      "name": "test_struct_with_id2s",
+      "type": "testStructWithID2",
+      "rows": 2,
+      "fields": [
+        "ForeignKey",
+        "ForeignValue",
+        "Name"
+      ],
+      "generatedFields": [
+        "ID"
+      ]
+    }
+  ],
+  "edges": [
+    {
+      "from": "test_struct_with_id2s",
+      "field": "ForeignKey",
+      "to": "test_struct_with_id3s",
+      "refField": "ID"
+    }
+  ]
+}`
+	if string(got) != want {
+		t.Fatalf("plan should be\n%s\nbut got\n%s", want, got)
+	}
+
+	if cfg.NumCalls() != 0 {
+		t.Fatalf("database should not be called, but got %d calls", cfg.NumCalls())
+	}
+
+	// the plan doesn't change what Insert does
+	vals, err := b.Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, v := range vals {
+		if v.ForeignKey != assVal.ID {
+			t.Fatalf("ForeignKey should be %v, but got %v", assVal.ID, v.ForeignKey)
+		}
+	}
+
+	if len(cfg.Inserted("test_struct_with_id2s")) != 2 || len(cfg.Inserted("test_struct_with_id3s")) != 1 {
+		t.Fatalf("values should be inserted once")
+	}
+}
+
+func plan_NoGeneratedID(t *testing.T) {
+	f := New(testExpectStruct{}).WithNoGeneratedID(true)
+
+	p, err := f.BuildList(mockCTX, 3).Plan()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := []TablePlan{
+		{Name: "test_expect_structs", Type: "testExpectStruct", Rows: 3, Fields: []string{"Email", "Age", "Nickname"}, GeneratedFields: []string{}},
+	}
+	if err := testutils.CompareVal(p.Tables, want); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func plan_SkipInsert(t *testing.T) {
+	f := New(testStructWithID2{})
+
+	p, err := f.Build(mockCTX).WithOne(&testStructWithID3{}).SkipInsert().Plan()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if p.Tables[0].Skipped || !p.Tables[1].Skipped {
+		t.Fatalf("only factory table should be skipped, but got %v", p.Tables)
+	}
+}
+
+func plan_Error(t *testing.T) {
+	f := New(testExpectStruct{}).WithColumns("Unknown")
+
+	if _, err := f.Plan(mockCTX); !errors.Is(err, errFieldNotFound) {
+		t.Fatalf("error should be %v, but got %v", errFieldNotFound, err)
+	}
+}
+
+type testStrictNested struct {
+	Name  string
+	Attrs map[string]string
+}
+
+type testStrictStruct struct {
+	ID      int
+	Name    string
+	Custom  customType
+	Any     interface{}
+	private string
+	Nested  testStrictNested
+	PtrNest *testStrictNested
+	Ignored customType `gofacto:"omit"`
+}
+
+func strict_AllPopulated(t *testing.T) {
+	val, err := New(testStructWithID3{}).WithIsStrict(true).Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Name == "" {
+		t.Fatalf("Name should be populated")
+	}
+}
+
+func strict_NotPopulated(t *testing.T) {
+	_, err := New(testStrictStruct{}).WithIsStrict(true).Build(mockCTX).Get()
+	if !errors.Is(err, errFieldsNotPopulated) {
+		t.Fatalf("error should be %v, got %v", errFieldsNotPopulated, err)
+	}
+
+	want := "Custom, Any, private, Nested.Attrs, PtrNest.Attrs"
+	if !strings.HasSuffix(err.Error(), want) {
+		t.Fatalf("error should list %s, got %v", want, err)
+	}
+}
+
+func strict_IgnoredFields(t *testing.T) {
+	type testStrictIgnored struct {
+		ID     int
+		Name   string
+		Custom customType `gofacto:"omit"`
+	}
+
+	if _, err := New(testStrictIgnored{}).WithIsStrict(true).Build(mockCTX).Get(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func strict_Disabled(t *testing.T) {
+	val, err := New(testStrictStruct{}).Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Custom != "" || val.Any != nil {
+		t.Fatalf("fields should be left zero")
+	}
+}
+
+func TestWithIDField(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when ID field is named Id, detect as ID field":         idField_Initialism,
+		"when ID field is named UUID, set string foreign key":   idField_UUIDAssoc,
+		"when set custom ID field, use the custom ID field":     idField_Custom,
+		"when struct has no ID field, ID field should be empty": idField_NotFound,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+type testStructWithIdInitialism struct {
+	Id   int
+	Name string
+}
+
+type testStructWithUUID struct {
+	UUID string
+	Name string
+}
+
+type testStructWithUUIDFK struct {
+	ID       int
+	UserUUID string `gofacto:"foreignKey,struct:testStructWithUUID"`
+}
+
+type testStructWithCustomID struct {
+	Code int
+	Name string
+}
+
+func idField_Initialism(t *testing.T) {
+	f := New(testStructWithIdInitialism{}).WithDB(&mockDB{})
+
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if val.Id != 0 {
+		t.Fatalf("Id should not be filled, got %v", val.Id)
+	}
+
+	val, err = f.Build(mockCTX).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if val.Id == 0 {
+		t.Fatalf("Id should be set when inserting")
+	}
+}
+
+func idField_UUIDAssoc(t *testing.T) {
+	f := New(testStructWithUUIDFK{}).WithDB(&mockDB{})
+
+	user := testStructWithUUID{}
+	val, err := f.Build(mockCTX).WithOne(&user).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if user.UUID == "" {
+		t.Fatalf("UUID should be set when inserting")
+	}
+	if val.UserUUID != user.UUID {
+		t.Fatalf("UserUUID should be %v, got %v", user.UUID, val.UserUUID)
+	}
+}
+
+func idField_Custom(t *testing.T) {
+	f := New(testStructWithCustomID{}).WithDB(&mockDB{}).WithIDField("Code")
+
+	val, err := f.Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if val.Code != 0 {
+		t.Fatalf("Code should not be filled, got %v", val.Code)
+	}
+
+	val, err = f.Build(mockCTX).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if val.Code == 0 {
+		t.Fatalf("Code should be set when inserting")
+	}
+}
+
+func idField_NotFound(t *testing.T) {
+	f := New(testStruct{})
+	if f.idField != "" {
+		t.Fatalf("idField should be empty, got %v", f.idField)
+	}
+}
+
+func TestPlanInsertOrder(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when values have dependencies, order by dependencies": planInsertOrder_Dependencies,
+		"when values have no dependencies, keep the order":     planInsertOrder_NoDependencies,
+		"when values have cycle, return error":                 planInsertOrder_Cycle,
+		"when value is not pointer, return error":              planInsertOrder_NotPtr,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func planInsertOrder_Dependencies(t *testing.T) {
+	assoc1, assoc2 := testAssocStruct{}, testAssocStruct{}
+	id, id2, id3 := testStructWithID{}, testStructWithID2{}, testStructWithID3{}
+
+	got, err := PlanInsertOrder([]interface{}{&assoc1, &id2, &assoc2, &id3, &id})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	want := []interface{}{&id3, &id2, &id, &assoc1, &assoc2}
+	if len(got) != len(want) {
+		t.Fatalf("length should be %v, got %v", len(want), len(got))
+	}
+	pos := map[interface{}]int{}
+	for i, v := range got {
+		pos[v] = i
+	}
+	if pos[&id3] > pos[&id2] {
+		t.Fatalf("testStructWithID3 should come before testStructWithID2")
+	}
+	if pos[&id2] > pos[&assoc1] || pos[&id] > pos[&assoc1] {
+		t.Fatalf("dependencies should come before testAssocStruct")
+	}
+	if pos[&assoc1] > pos[&assoc2] {
+		t.Fatalf("values of the same type should keep the order")
+	}
+}
+
+func planInsertOrder_NoDependencies(t *testing.T) {
+	a, b := testStructWithID{}, testStructWithCustomFK{}
+
+	got, err := PlanInsertOrder([]interface{}{&a, &b})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if got[0] != &a || got[1] != &b {
+		t.Fatalf("order should be kept")
+	}
+}
+
+func planInsertOrder_Cycle(t *testing.T) {
+	_, err := PlanInsertOrder([]interface{}{&testStructWithCycle{}, &testStructWithCycle2{}})
+	if !errors.Is(err, errCycleDependency) {
+		t.Fatalf("error should be %v", errCycleDependency)
+	}
+}
+
+func planInsertOrder_NotPtr(t *testing.T) {
+	_, err := PlanInsertOrder([]interface{}{testStructWithID{}})
+	if !errors.Is(err, errIsNotPtr) {
+		t.Fatalf("error should be %v", errIsNotPtr)
+	}
+}
+
+func TestWithBlueprintE(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when blueprint succeeds, value should be set by blueprint": withBlueprintE_Success,
+		"when blueprint fails on build, return error":               withBlueprintE_BuildErr,
+		"when blueprint fails on build list, return error":          withBlueprintE_BuildListErr,
+		"when blueprint fails, insert should return error":          withBlueprintE_InsertErr,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func withBlueprintE_Success(t *testing.T) {
+	f := New(testStructWithID3{}).WithBlueprintE(func(i int) (testStructWithID3, error) {
+		return testStructWithID3{Name: fmt.Sprintf("name%d", i)}, nil
+	})
+
+	vals, err := f.BuildList(mockCTX, 2).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for i, v := range vals {
+		if want := fmt.Sprintf("name%d", i+1); v.Name != want {
+			t.Fatalf("Name should be %v, got %v", want, v.Name)
+		}
+	}
+}
+
+func withBlueprintE_BuildErr(t *testing.T) {
+	wantErr := errors.New("blueprint error")
+	f := New(testStructWithID3{}).WithBlueprintE(func(i int) (testStructWithID3, error) {
+		return testStructWithID3{}, wantErr
+	})
+
+	val, err := f.Build(mockCTX).Get()
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("error should be %v, got %v", wantErr, err)
+	}
+
+	if err := testutils.CompareVal(val, testStructWithID3{}); err != nil {
+		t.Fatal(err.Error())
+	}
+}
+
+func withBlueprintE_BuildListErr(t *testing.T) {
+	wantErr := errors.New("blueprint error")
+	f := New(testStructWithID3{}).WithBlueprintE(func(i int) (testStructWithID3, error) {
+		if i == 2 {
+			return testStructWithID3{}, wantErr
+		}
+
+		return testStructWithID3{Name: "name"}, nil
+	})
+
+	vals, err := f.BuildList(mockCTX, 3).Get()
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("error should be %v, got %v", wantErr, err)
+	}
+
+	if vals != nil {
+		t.Fatalf("vals should be nil")
+	}
+}
+
+func withBlueprintE_InsertErr(t *testing.T) {
+	wantErr := errors.New("blueprint error")
+	mdb := &mockDB{}
+	f := New(testStructWithID3{}).WithDB(mdb).WithBlueprintE(func(i int) (testStructWithID3, error) {
+		return testStructWithID3{}, wantErr
+	})
+
+	_, err := f.Build(mockCTX).Insert()
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("error should be %v, got %v", wantErr, err)
+	}
+
+	if len(f.inserted) != 0 {
+		t.Fatalf("value should not be inserted")
+	}
+}
+
+func TestWithTraitE(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when trait succeeds, value should be mutated":           withTraitE_Success,
+		"when trait fails on build, return error":                withTraitE_BuildErr,
+		"when trait fails on build list, return error":           withTraitE_BuildListErr,
+		"when set trait with same name, the latest one replaces": withTraitE_Replace,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func withTraitE_Success(t *testing.T) {
+	f := New(testStructWithID3{}).WithTraitE("named", func(v *testStructWithID3) error {
+		v.Name = "named"
+		return nil
+	})
+
+	vals, err := f.BuildList(mockCTX, 2).SetTrait("named").Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, v := range vals {
+		if v.Name != "named" {
+			t.Fatalf("Name should be named, got %v", v.Name)
+		}
+	}
+}
+
+func withTraitE_BuildErr(t *testing.T) {
+	wantErr := errors.New("trait error")
+	f := New(testStructWithID3{}).WithTraitE("invalid", func(v *testStructWithID3) error {
+		return wantErr
+	})
+
+	_, err := f.Build(mockCTX).SetTrait("invalid").Get()
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("error should be %v, got %v", wantErr, err)
+	}
+}
+
+func withTraitE_BuildListErr(t *testing.T) {
+	wantErr := errors.New("trait error")
+	f := New(testStructWithID3{}).
+		WithTrait("valid", func(v *testStructWithID3) {}).
+		WithTraitE("invalid", func(v *testStructWithID3) error {
+			return wantErr
+		})
+
+	vals, err := f.BuildList(mockCTX, 2).SetTraits("valid", "invalid").Insert()
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("error should be %v, got %v", wantErr, err)
+	}
+
+	if vals != nil {
+		t.Fatalf("vals should be nil")
+	}
+}
+
+func withTraitE_Replace(t *testing.T) {
+	f := New(testStructWithID3{}).
+		WithTraitE("trait", func(v *testStructWithID3) error {
+			return errors.New("trait error")
+		}).
+		WithTrait("trait", func(v *testStructWithID3) {
+			v.Name = "replaced"
+		})
+
+	val, err := f.Build(mockCTX).SetTrait("trait").Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.Name != "replaced" {
+		t.Fatalf("Name should be replaced, got %v", val.Name)
+	}
+}
+
+func TestTimeTag(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when tag has past, time should be within the window before now":  timeTag_Past,
+		"when tag has future, time should be within the window after now": timeTag_Future,
+		"when tag has tz, time should be in the time zone":                timeTag_TZ,
+		"when tag is on non-time field, return error":                     timeTag_NotTime,
+		"when tag has both past and future, return error":                 timeTag_PastAndFuture,
+		"when tag has invalid tz, return error":                           timeTag_InvalidTZ,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func timeTag_Past(t *testing.T) {
+	type testStructWithPastTime struct {
+		ExpiredAt *time.Time `gofacto:"past,window:1h"`
+	}
+
+	before := time.Now()
+	vals, err := New(testStructWithPastTime{}).BuildList(mockCTX, 10).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, v := range vals {
+		if !v.ExpiredAt.Before(before.Add(time.Second)) || v.ExpiredAt.Before(before.Add(-time.Hour)) {
+			t.Fatalf("ExpiredAt should be within 1h before now, got %v", v.ExpiredAt)
+		}
+	}
+}
+
+func timeTag_Future(t *testing.T) {
+	type testStructWithFutureTime struct {
+		ScheduledAt time.Time `gofacto:"future,window:24h"`
+	}
+
+	before := time.Now()
+	vals, err := New(testStructWithFutureTime{}).BuildList(mockCTX, 10).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, v := range vals {
+		if !v.ScheduledAt.After(before) || v.ScheduledAt.After(before.Add(24*time.Hour+time.Second)) {
+			t.Fatalf("ScheduledAt should be within 24h after now, got %v", v.ScheduledAt)
+		}
+	}
+}
+
+func timeTag_TZ(t *testing.T) {
+	type testStructWithTZ struct {
+		CreatedAt time.Time `gofacto:"tz:UTC"`
+	}
+
+	val, err := New(testStructWithTZ{}).Build(mockCTX).Get()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if val.CreatedAt.Location() != time.UTC {
+		t.Fatalf("CreatedAt should be in UTC, got %v", val.CreatedAt.Location())
+	}
+}
+
+func timeTag_NotTime(t *testing.T) {
+	type testStructWithInvalidTimeTag struct {
+		Name string `gofacto:"past"`
+	}
+
+	f := New(testStructWithInvalidTimeTag{})
+	if !errors.Is(f.configErr, errTagFormat) {
+		t.Fatalf("error should be %v, got %v", errTagFormat, f.configErr)
+	}
+}
+
+func timeTag_PastAndFuture(t *testing.T) {
+	type testStructWithPastAndFuture struct {
+		CreatedAt time.Time `gofacto:"past,future"`
+	}
+
+	f := New(testStructWithPastAndFuture{})
+	if !errors.Is(f.configErr, errTagFormat) {
+		t.Fatalf("error should be %v, got %v", errTagFormat, f.configErr)
+	}
+}
+
+func timeTag_InvalidTZ(t *testing.T) {
+	type testStructWithInvalidTZ struct {
+		CreatedAt time.Time `gofacto:"tz:Not/AZone"`
+	}
+
+	f := New(testStructWithInvalidTZ{})
+	if !errors.Is(f.configErr, errTagFormat) {
+		t.Fatalf("error should be %v, got %v", errTagFormat, f.configErr)
+	}
+}
+
+func TestRecorderAndReplay(t *testing.T) {
+	for _, fn := range map[string]func(*testing.T){
+		"when replay, values should be inserted in order with remapped foreign keys": replay_CorrectCase,
+		"when replay with unregistered type, return error":                           replay_UnregisteredType,
+	} {
+		t.Run(testutils.GetFunName(fn), func(t *testing.T) {
+			fn(t)
+		})
+	}
+}
+
+func replay_CorrectCase(t *testing.T) {
+	rec := NewRecorder(&mockDB{})
+	f := New(testStructWithID2{}).WithDB(rec)
+
+	assVal := testStructWithID3{}
+	vals, err := f.BuildList(mockCTX, 2).WithOne(&assVal).Insert()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := rec.Save(&buf); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// shift the IDs of the target database, so the foreign keys must be remapped
+	target := mockf.NewConfig()
+	if _, err := New(testStructWithID{}).WithDB(target).BuildList(mockCTX, 5).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := Replay(mockCTX, target, &buf, testStructWithID2{}, testStructWithID3{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	assocs := target.Inserted("test_struct_with_id3s")
+	if len(assocs) != 1 {
+		t.Fatalf("association should be replayed, got %v", len(assocs))
+	}
+	newAssVal := assocs[0].(*testStructWithID3)
+	if newAssVal.ID != 6 || newAssVal.Name != assVal.Name {
+		t.Fatalf("association should be replayed with the new ID, got %v", newAssVal)
+	}
+
+	replayed := target.Inserted("test_struct_with_id2s")
+	if len(replayed) != len(vals) {
+		t.Fatalf("values should be replayed, got %v", len(replayed))
+	}
+	for i, v := range replayed {
+		v := v.(*testStructWithID2)
+		if v.ForeignKey != newAssVal.ID {
+			t.Fatalf("ForeignKey should be remapped to %v, got %v", newAssVal.ID, v.ForeignKey)
+		}
+		if v.ID == vals[i].ID && v.ID != 7+i {
+			t.Fatalf("ID should be populated by the target database, got %v", v.ID)
+		}
+	}
+}
+
+func replay_UnregisteredType(t *testing.T) {
+	rec := NewRecorder(&mockDB{})
+	if _, err := New(testStructWithID3{}).WithDB(rec).Build(mockCTX).Insert(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := rec.Save(&buf); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	err := Replay(mockCTX, mockf.NewConfig(), &buf, testStructWithID{})
+	if !errors.Is(err, errInvalidType) {
+		t.Fatalf("error should be %v, got %v", errInvalidType, err)
 	}
 }
 
@@ -3175,8 +9201,8 @@ func checkFactory[T any](got, want *Factory[T]) error {
 		return fmt.Errorf("isSetZeroValue should be %v", want.isSetZeroValue)
 	}
 
-	if !errors.Is(got.err, want.err) {
-		return fmt.Errorf("err should be %v", want.err)
+	if !errors.Is(got.configErr, want.configErr) {
+		return fmt.Errorf("configErr should be %v", want.configErr)
 	}
 
 	if len(got.traits) != len(want.traits) {
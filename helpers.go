@@ -4,7 +4,8 @@ import (
 	"fmt"
 	"reflect"
 	"slices"
-	"time"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -15,87 +16,149 @@ const (
 // Parameter v must be a pointer to a struct
 func (f *Factory[T]) setNonZeroValues(v interface{}, ignoreFields []string) {
 	val := reflect.ValueOf(v).Elem()
-	typeOfVal := val.Type()
+	idField := f.idFieldName(val.Type())
 
-	for k := 0; k < val.NumField(); k++ {
-		curVal := val.Field(k)
-		curField := typeOfVal.Field(k)
+	for _, p := range getTypePlan(val.Type()).fields {
+		curVal := val.Field(p.index)
 
-		// skip ignored fields
-		if slices.Contains(ignoreFields, curField.Name) {
-			continue
-		}
-
-		// skip non-zero fields, unexported fields, and ID field
-		if !curVal.IsZero() || !curVal.CanSet() || curField.Name == "ID" || curField.PkgPath != "" {
+		// skip ignored fields, non-zero fields, and ID field
+		if slices.Contains(ignoreFields, p.name) || !curVal.IsZero() || p.name == idField {
 			continue
 		}
 
 		// handle db custom types
-		if f.db != nil {
-			if customValue, ok := f.db.GenCustomType(curField.Type); ok {
-				curVal.Set(reflect.ValueOf(customValue))
-				continue
-			}
-		}
-
-		// handle time.Time
-		if curField.Type == reflect.TypeOf(time.Time{}) {
-			curVal.Set(reflect.ValueOf(time.Now()))
+		if customValue, ok := f.genCustomType(p.typ); ok {
+			curVal.Set(reflect.ValueOf(customValue))
 			continue
 		}
 
-		// handle *time.Time
-		if curField.Type.Kind() == reflect.Ptr && curField.Type.Elem() == reflect.TypeOf(time.Time{}) {
-			timeVal := time.Now()
-			curVal.Set(reflect.ValueOf(&timeVal))
+		// the foreign keys are kept, so the associations are still satisfiable
+		isEdgeKind := p.kind == fieldKindBasic || p.kind == fieldKindTime || p.kind == fieldKindPtrTime
+		if f.edgeCase != 0 && isEdgeKind && !p.isForeignKey && setEdgeValue(curVal, f.edgeCase) {
 			continue
 		}
 
-		// handle struct
-		if curField.Type.Kind() == reflect.Struct {
+		switch p.kind {
+		case fieldKindTime:
+			curVal.Set(reflect.ValueOf(p.timeOpt.genTime(f.now(), f.int63n)))
+		case fieldKindPtrTime:
+			timeVal := p.timeOpt.genTime(f.now(), f.int63n)
+			curVal.Set(reflect.ValueOf(&timeVal))
+		case fieldKindTimeSlice:
+			f.setTimeSlice(curVal, p.timeOpt)
+		case fieldKindDecimal:
+			setDecimalValue(curVal, p.decimalOpt.genDecimal(f.index))
+		case fieldKindStruct:
 			f.setNonZeroValues(curVal.Addr().Interface(), ignoreFields)
-			continue
-		}
-
-		// handle pointer to struct
-		if curField.Type.Kind() == reflect.Ptr && curField.Type.Elem().Kind() == reflect.Struct {
-			newInstance := reflect.New(curField.Type.Elem()).Elem()
+		case fieldKindPtrStruct:
+			newInstance := reflect.New(p.typ.Elem()).Elem()
 			f.setNonZeroValues(newInstance.Addr().Interface(), ignoreFields)
 			curVal.Set(newInstance.Addr())
-			continue
-		}
-
-		// handle slice
-		if curField.Type.Kind() == reflect.Slice {
+		case fieldKindSlice:
 			f.setNonZeroSlice(curVal.Addr().Interface(), ignoreFields)
-			continue
-		}
-
-		// handle pointer to slice
-		if curField.Type.Kind() == reflect.Ptr && curField.Type.Elem().Kind() == reflect.Slice {
-			newInstance := reflect.New(curField.Type.Elem()).Elem()
+		case fieldKindPtrSlice:
+			newInstance := reflect.New(p.typ.Elem()).Elem()
 			f.setNonZeroSlice(newInstance.Addr().Interface(), ignoreFields)
 			curVal.Set(newInstance.Addr())
-			continue
+		case fieldKindBasic:
+			if f.stringFormat != "" && isStringType(p.typ) {
+				setStringValue(curVal, f.formatString(val.Type().Name(), p.name))
+				continue
+			}
+
+			setBasicValue(curVal, f.index)
 		}
+	}
+}
 
-		// skip client-defined types
-		if curField.Type.PkgPath() != "" {
+// findUnpopulated returns the paths of the fields left zero after setting non-zero values, e.g. "Address.Geo".
+// The nested structs are inspected field by field, and the ignored fields and the ID field are skipped
+func (f *Factory[T]) findUnpopulated(val reflect.Value, prefix string, ignoreFields []string) []string {
+	typ := val.Type()
+	idField := f.idFieldName(typ)
+
+	var paths []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if slices.Contains(ignoreFields, field.Name) || field.Name == idField {
 			continue
 		}
 
-		// skip pointer to custom type
-		if curField.Type.Kind() == reflect.Ptr &&
-			curField.Type.Elem().PkgPath() != "" {
-			continue
+		path := prefix + field.Name
+		curVal := val.Field(i)
+		isExported := field.PkgPath == ""
+		kind := fieldKindOf(field)
+		switch {
+		case isExported && kind == fieldKindStruct:
+			paths = append(paths, f.findUnpopulated(curVal, path+".", ignoreFields)...)
+		case isExported && kind == fieldKindPtrStruct && !curVal.IsNil():
+			paths = append(paths, f.findUnpopulated(curVal.Elem(), path+".", ignoreFields)...)
+		case curVal.IsZero():
+			paths = append(paths, path)
 		}
+	}
+
+	return paths
+}
 
-		// For other types, set non-zero values if the field is zero
-		if v := genNonZeroValue(curField.Type, f.index); v != nil {
-			curVal.Set(reflect.ValueOf(v))
+// genCustomType generates a non-zero value for the db custom type.
+// The types which aren't custom types are cached, so they're not resolved again on later builds.
+// The value of the custom type is generated on every call, so the values are not shared across builds
+func (f *Factory[T]) genCustomType(t reflect.Type) (interface{}, bool) {
+	generator, ok := f.db.(customTypeGenerator)
+	if !ok {
+		return nil, false
+	}
+
+	if _, ok := f.nonCustomTypes[t]; ok {
+		return nil, false
+	}
+
+	v, ok := generator.GenCustomType(t)
+	if !ok {
+		if f.nonCustomTypes == nil {
+			f.nonCustomTypes = map[reflect.Type]struct{}{}
 		}
+		f.nonCustomTypes[t] = struct{}{}
 	}
+
+	return v, ok
+}
+
+// idFieldName returns the name of the ID field of the given struct type.
+// The factory type uses the configured ID field, other types use the detected one
+func (f *Factory[T]) idFieldName(typ reflect.Type) string {
+	if typ == f.dataType {
+		return f.idField
+	}
+
+	return getTypePlan(typ).idField
+}
+
+// setSeqID sets the sequential ID to the ID field when there's no db connection.
+// Parameter v must be a pointer to a struct
+func (f *Factory[T]) setSeqID(v interface{}) {
+	if !f.isSetSeqID || f.db != nil {
+		return
+	}
+
+	idField := reflect.ValueOf(v).Elem().FieldByName(f.idField)
+	if f.idField == "" || !idField.IsValid() || !idField.CanSet() {
+		return
+	}
+
+	switch {
+	case isIntType(idField.Kind()):
+		idField.SetInt(int64(f.seqID))
+	case isUintType(idField.Kind()):
+		idField.SetUint(uint64(f.seqID))
+	case idField.Kind() == reflect.String:
+		idField.SetString(strconv.Itoa(f.seqID))
+	default:
+		return
+	}
+
+	f.seqID++
 }
 
 // setNonZeroSlice sets non-zero values to the given slice.
@@ -103,6 +166,12 @@ func (f *Factory[T]) setNonZeroValues(v interface{}, ignoreFields []string) {
 func (f *Factory[T]) setNonZeroSlice(v interface{}, ignoreFields []string) {
 	val := reflect.ValueOf(v).Elem()
 
+	// handle slice of times
+	if isTimeSliceType(val.Type()) {
+		f.setTimeSlice(val, nil)
+		return
+	}
+
 	// handle slice
 	if val.Type().Elem().Kind() == reflect.Slice {
 		e := reflect.New(val.Type().Elem()).Elem()
@@ -142,6 +211,85 @@ func (f *Factory[T]) setNonZeroSlice(v interface{}, ignoreFields []string) {
 	}
 }
 
+// setTimeSlice sets the distinct times generated based on the option(opt) to the slice of time.Time or *time.Time
+func (f *Factory[T]) setTimeSlice(val reflect.Value, opt *timeOption) {
+	times := opt.genTimes(timeSliceLen, f.now(), f.int63n)
+	isPtr := val.Type().Elem().Kind() == reflect.Ptr
+
+	s := reflect.MakeSlice(val.Type(), len(times), len(times))
+	for i := range times {
+		if isPtr {
+			s.Index(i).Set(reflect.ValueOf(&times[i]))
+			continue
+		}
+
+		s.Index(i).Set(reflect.ValueOf(times[i]))
+	}
+
+	val.Set(s)
+}
+
+// checkFieldsExist checks if the fields exist in the struct type
+func checkFieldsExist(typ reflect.Type, fields []string) error {
+	for _, field := range fields {
+		if _, ok := typ.FieldByName(field); !ok {
+			return fmt.Errorf("%w: %s", errFieldNotFound, field)
+		}
+	}
+
+	return nil
+}
+
+// checkFrozenOverwrite checks if the overwrite value tries to overwrite the frozen fields.
+// Only non-zero fields are overwritten, so zero frozen fields are allowed
+func checkFrozenOverwrite[T any](ow T, frozen []string) error {
+	owVal := reflect.ValueOf(ow)
+	for _, field := range frozen {
+		if !owVal.FieldByName(field).IsZero() {
+			return fmt.Errorf("%w: %s", errFieldFrozen, field)
+		}
+	}
+
+	return nil
+}
+
+// applyTrait applies the trait function of the name to the value.
+// If the trait function mutates the frozen fields, the fields are restored and an error is returned
+func applyTrait[T any](v *T, name string, tr setTraiterE[T], frozen []string) error {
+	if len(frozen) == 0 {
+		if err := tr(v); err != nil {
+			return fmt.Errorf("trait %s: %w", name, err)
+		}
+
+		return nil
+	}
+
+	val := reflect.ValueOf(v).Elem()
+	snapshot := make([]reflect.Value, len(frozen))
+	for i, field := range frozen {
+		snapshot[i] = reflect.New(val.FieldByName(field).Type()).Elem()
+		snapshot[i].Set(val.FieldByName(field))
+	}
+
+	trErr := tr(v)
+
+	var err error
+	for i, field := range frozen {
+		if !reflect.DeepEqual(val.FieldByName(field).Interface(), snapshot[i].Interface()) {
+			val.FieldByName(field).Set(snapshot[i])
+			if err == nil {
+				err = fmt.Errorf("%w: %s", errFieldFrozen, field)
+			}
+		}
+	}
+
+	if trErr != nil {
+		return fmt.Errorf("trait %s: %w", name, trErr)
+	}
+
+	return err
+}
+
 // copyValues copys non-zero values from src to dest
 func copyValues[T any](dest *T, src T) error {
 	destValue := reflect.ValueOf(dest).Elem()
@@ -171,6 +319,78 @@ func copyValues[T any](dest *T, src T) error {
 	return nil
 }
 
+// setBasicValue sets the non-zero value to the field of builtin type.
+// It sets the value directly for the common types to avoid allocating the intermediate value
+func setBasicValue(v reflect.Value, i int) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(i))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(i))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(i))
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.String:
+		v.SetString("test" + strconv.Itoa(i))
+	case reflect.Pointer:
+		ptr := reflect.New(v.Type().Elem())
+		setBasicValue(ptr.Elem(), i)
+		v.Set(ptr)
+	default:
+		if nv := genNonZeroValue(v.Type(), i); nv != nil {
+			v.Set(reflect.ValueOf(nv))
+		}
+	}
+}
+
+// formatString generates the string of the given struct and field by the string format
+func (f *Factory[T]) formatString(structName, fieldName string) string {
+	return strings.NewReplacer(
+		"{struct}", structName,
+		"{field}", fieldName,
+		"{i}", strconv.Itoa(f.index),
+	).Replace(f.stringFormat)
+}
+
+// isStringType checks if the type is string or pointer to string
+func isStringType(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.String
+}
+
+// setStringValue sets the string to the field of string or pointer to string
+func setStringValue(v reflect.Value, s string) {
+	if v.Kind() == reflect.Pointer {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	v.SetString(s)
+}
+
+// setConvertedField sets the value to the field(name) of the struct(val),
+// and the value is converted to the type of the field, e.g. string to the client-defined string type
+func setConvertedField(val reflect.Value, name string, v interface{}) error {
+	fieldVal := val.FieldByName(name)
+	if !fieldVal.CanSet() {
+		return fmt.Errorf("%s: %w", name, errFieldCantSet)
+	}
+
+	src := reflect.ValueOf(v)
+	// the integer is convertible to string as a rune, which is never intended
+	isToString := fieldVal.Kind() == reflect.String && src.Kind() != reflect.String
+	if isToString || !src.Type().ConvertibleTo(fieldVal.Type()) {
+		return fmt.Errorf("%s: %w: %v and %v", name, errTypeDiff, fieldVal.Type(), src.Type())
+	}
+
+	fieldVal.Set(src.Convert(fieldVal.Type()))
+	return nil
+}
+
 // genNonZeroValue generates a non-zero value for the given type
 func genNonZeroValue(t reflect.Type, i int) interface{} {
 	switch t.Kind() {
@@ -0,0 +1,80 @@
+// Package httpf integrates the gofacto factories with net/http/httptest.
+//
+// It streamlines the common pattern of testing HTTP handlers:
+// create an entity, then call the API as that entity.
+package httpf
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+)
+
+// Inserter inserts a value into the database, e.g. the builder returned by factory.Build
+type Inserter[T any] interface {
+	Insert() (T, error)
+}
+
+// Extractor authenticates the request as the inserted value,
+// e.g. setting the authorization header, the cookie, or the context value
+type Extractor[T any] func(r *http.Request, v T) *http.Request
+
+// NewRequest inserts the value built by the inserter,
+// and returns the httptest request authenticated as the value by the extractor.
+//
+// Example:
+//
+//	req, user, err := httpf.NewRequest(userFactory.Build(ctx), http.MethodGet, "/me", nil,
+//		httpf.BearerToken(func(u User) string { return u.Token }))
+//	rec := httptest.NewRecorder()
+//	handler.ServeHTTP(rec, req)
+func NewRequest[T any](in Inserter[T], method, target string, body io.Reader, extract Extractor[T]) (*http.Request, T, error) {
+	v, err := in.Insert()
+	if err != nil {
+		var empty T
+		return nil, empty, err
+	}
+
+	r := httptest.NewRequest(method, target, body)
+	if extract != nil {
+		r = extract(r, v)
+	}
+
+	return r, v, nil
+}
+
+// BearerToken returns the extractor setting the authorization header with the bearer token of the value
+func BearerToken[T any](token func(v T) string) Extractor[T] {
+	return func(r *http.Request, v T) *http.Request {
+		r.Header.Set("Authorization", "Bearer "+token(v))
+		return r
+	}
+}
+
+// Header returns the extractor setting the header with the value, e.g. X-User-ID
+func Header[T any](name string, value func(v T) string) Extractor[T] {
+	return func(r *http.Request, v T) *http.Request {
+		r.Header.Set(name, value(v))
+		return r
+	}
+}
+
+// ContextValue returns the extractor storing the value in the request context with the key,
+// which is useful when the handler reads the authenticated entity set by the middleware
+func ContextValue[T any](key interface{}) Extractor[T] {
+	return func(r *http.Request, v T) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), key, v))
+	}
+}
+
+// Chain combines the extractors, they are applied in order
+func Chain[T any](extractors ...Extractor[T]) Extractor[T] {
+	return func(r *http.Request, v T) *http.Request {
+		for _, extract := range extractors {
+			r = extract(r, v)
+		}
+
+		return r
+	}
+}
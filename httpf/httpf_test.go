@@ -0,0 +1,67 @@
+package httpf
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type user struct {
+	ID    int
+	Token string
+}
+
+type ctxKey struct{}
+
+// mockInserter returns the given value and error when inserting
+type mockInserter struct {
+	v   user
+	err error
+}
+
+func (m mockInserter) Insert() (user, error) {
+	return m.v, m.err
+}
+
+func TestNewRequest(t *testing.T) {
+	u := user{ID: 1, Token: "token"}
+	extract := Chain(
+		BearerToken(func(u user) string { return u.Token }),
+		Header("X-User-ID", func(u user) string { return "1" }),
+		ContextValue[user](ctxKey{}),
+	)
+
+	req, got, err := NewRequest[user](mockInserter{v: u}, http.MethodGet, "/me", nil, extract)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if got != u {
+		t.Fatalf("value should be %v, got %v", u, got)
+	}
+	if req.Method != http.MethodGet || req.URL.Path != "/me" {
+		t.Fatalf("request should be GET /me, got %s %s", req.Method, req.URL.Path)
+	}
+	if h := req.Header.Get("Authorization"); h != "Bearer token" {
+		t.Fatalf("Authorization should be Bearer token, got %v", h)
+	}
+	if h := req.Header.Get("X-User-ID"); h != "1" {
+		t.Fatalf("X-User-ID should be 1, got %v", h)
+	}
+	if v, ok := req.Context().Value(ctxKey{}).(user); !ok || v != u {
+		t.Fatalf("context value should be %v, got %v", u, v)
+	}
+}
+
+func TestNewRequest_InsertErr(t *testing.T) {
+	wantErr := errors.New("insert error")
+
+	req, _, err := NewRequest[user](mockInserter{err: wantErr}, http.MethodGet, "/me", nil, nil)
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("error should be %v, got %v", wantErr, err)
+	}
+
+	if req != nil {
+		t.Fatalf("request should be nil")
+	}
+}
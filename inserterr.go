@@ -0,0 +1,163 @@
+package gofacto
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/eyo-chen/gofacto/internal/db"
+)
+
+// ErrorPreview is how the values of the failed row are previewed in InsertError
+type ErrorPreview int
+
+const (
+	// ErrorPreviewRedacted previews the numbers, the booleans, and the times as they are,
+	// and redacts the other values, e.g. the strings are redacted to their lengths.
+	// The IDs and the foreign keys are traceable without leaking the content
+	ErrorPreviewRedacted ErrorPreview = iota
+
+	// ErrorPreviewFull previews all the values as they are
+	ErrorPreviewFull
+
+	// ErrorPreviewNone doesn't preview the values
+	ErrorPreviewNone
+)
+
+// InsertError is the error of inserting into the database with the context of the failed row,
+// so the failure in the association graph can be traced to the exact record
+type InsertError struct {
+	// StorageName is the storage name of the failed insert
+	StorageName string
+
+	// Columns is the list of the inserted columns, they are the field names if the database doesn't report them
+	Columns []string
+
+	// Index is the index of the failed row in the inserted values, -1 means unknown
+	Index int
+
+	// Preview is the preview of the values of the failed row in the order of the columns, nil means unknown or disabled
+	Preview []string
+
+	Err error
+}
+
+func (e *InsertError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("insert into " + e.StorageName)
+	if len(e.Columns) > 0 {
+		fmt.Fprintf(&sb, " (%s)", strings.Join(e.Columns, ", "))
+	}
+	if e.Index >= 0 {
+		fmt.Fprintf(&sb, " row %d", e.Index)
+	}
+	if len(e.Preview) > 0 {
+		fmt.Fprintf(&sb, " [%s]", strings.Join(e.Preview, ", "))
+	}
+	fmt.Fprintf(&sb, ": %v", e.Err)
+
+	return sb.String()
+}
+
+func (e *InsertError) Unwrap() error {
+	return e.Err
+}
+
+// WithErrorPreview sets how the values of the failed row are previewed in InsertError,
+// and it's ErrorPreviewRedacted by default
+func (f *Factory[T]) WithErrorPreview(p ErrorPreview) *Factory[T] {
+	f.errorPreview = p
+	return f
+}
+
+// insertError wraps the error of inserting the values(vals) into the storage with the context of the failed row.
+// The columns and the failed row reported by the database take precedence over the fields of the values
+func (f *Factory[T]) insertError(storageName, idField string, vals []interface{}, err error) error {
+	ie := &InsertError{StorageName: storageName, Index: -1, Err: err}
+	if len(vals) == 1 {
+		ie.Index = 0
+	}
+
+	var row []interface{}
+	var dbErr *db.InsertError
+	if errors.As(err, &dbErr) {
+		ie.Columns, row = dbErr.Columns, dbErr.Row
+		if dbErr.Index >= 0 {
+			ie.Index = dbErr.Index
+		}
+	}
+
+	if ie.Columns == nil && len(vals) > 0 {
+		columns, fieldVals := fieldsOfRow(vals[max(ie.Index, 0)], idField)
+		ie.Columns = columns
+		if ie.Index >= 0 && ie.Index < len(vals) {
+			row = fieldVals
+		}
+	}
+
+	if f.errorPreview != ErrorPreviewNone && row != nil {
+		ie.Preview = make([]string, len(row))
+		for i, v := range row {
+			ie.Preview[i] = previewValue(v, f.errorPreview)
+		}
+	}
+
+	return ie
+}
+
+// fieldsOfRow returns the names and the values of the exported fields of the value except the ID field.
+// v is the pointer to the struct
+func fieldsOfRow(v interface{}, idField string) ([]string, []interface{}) {
+	val := reflect.ValueOf(v).Elem()
+
+	var names []string
+	var vals []interface{}
+	for _, p := range getTypePlan(val.Type()).fields {
+		if p.name == idField {
+			continue
+		}
+
+		names = append(names, p.name)
+		vals = append(vals, val.Field(p.index).Interface())
+	}
+
+	return names, vals
+}
+
+// previewValue returns the preview of the value
+func previewValue(v interface{}, p ErrorPreview) string {
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return "NULL"
+		}
+		val = val.Elem()
+	}
+
+	if !val.IsValid() {
+		return "NULL"
+	}
+
+	if t, ok := val.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+
+	kind := val.Kind()
+	isSafe := kind == reflect.Bool || isIntType(kind) || isUintType(kind) || kind == reflect.Float32 || kind == reflect.Float64
+	switch {
+	case isSafe:
+		return fmt.Sprint(val.Interface())
+	case p == ErrorPreviewFull && kind == reflect.String:
+		return fmt.Sprintf("%q", val.String())
+	case p == ErrorPreviewFull:
+		return fmt.Sprintf("%v", val.Interface())
+	case kind == reflect.String:
+		return fmt.Sprintf("<redacted %d chars>", val.Len())
+	case kind == reflect.Slice && val.Type().Elem().Kind() == reflect.Uint8:
+		return fmt.Sprintf("<redacted %d bytes>", val.Len())
+	default:
+		return "<redacted>"
+	}
+}
@@ -0,0 +1,185 @@
+package gofacto
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"slices"
+)
+
+// InsertPlan is the description of what Insert would do, without inserting.
+// It's marshaled to JSON, so the CI jobs can diff the plans across commits to detect the unintended fixture changes
+type InsertPlan struct {
+	// Tables is the list of the storages inserted into, in the insertion order
+	Tables []TablePlan `json:"tables"`
+
+	// Edges is the list of the foreign keys wired between the storages
+	Edges []EdgePlan `json:"edges"`
+}
+
+// TablePlan is the description of the rows inserted into a storage
+type TablePlan struct {
+	// Name is the storage name, e.g. the table name
+	Name string `json:"name"`
+
+	// Type is the name of the struct type of the rows
+	Type string `json:"type"`
+
+	// Rows is the number of the inserted rows
+	Rows int `json:"rows"`
+
+	// Fields is the list of the inserted fields in the struct field order
+	Fields []string `json:"fields"`
+
+	// GeneratedFields is the list of the fields generated by the database,
+	// which are the ID field populated after inserting, and the omitted fields set by the column defaults
+	GeneratedFields []string `json:"generatedFields"`
+
+	// Skipped is whether the rows are not inserted, e.g. by SkipInsert
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// EdgePlan is the description of the foreign key referencing the other storage
+type EdgePlan struct {
+	// From is the storage name of the rows with the foreign key
+	From string `json:"from"`
+
+	// Field is the name of the foreign key field
+	Field string `json:"field"`
+
+	// To is the storage name of the rows referenced by the foreign key
+	To string `json:"to"`
+
+	// RefField is the name of the field referenced by the foreign key, e.g. the ID field
+	RefField string `json:"refField"`
+
+	// IsMany is whether the foreign key is the slice referencing all the rows
+	IsMany bool `json:"isMany,omitempty"`
+}
+
+// JSON returns the indented JSON of the plan, which is stable for diffing
+func (p *InsertPlan) JSON() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// Plan builds a value, and returns the plan of inserting it without inserting, see builder.Plan
+func (f *Factory[T]) Plan(ctx context.Context) (*InsertPlan, error) {
+	return f.Build(ctx).Plan()
+}
+
+// Plan returns the plan of inserting the value and its associations without inserting.
+// The plan describes the storages, the row counts, the inserted and generated fields, and the foreign keys between the storages.
+//
+// Example:
+//
+//	plan, err := factory.Build(ctx).WithOne(&user).Plan()
+//	b, err := plan.JSON()
+func (b *builder[T]) Plan() (*InsertPlan, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return b.f.plan([]interface{}{b.v}, b.skipInsert)
+}
+
+// Plan returns the plan of inserting the list of values and their associations without inserting, see builder.Plan
+func (b *builderList[T]) Plan() (*InsertPlan, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	vals := make([]interface{}, len(b.list))
+	for i, v := range b.list {
+		vals[i] = v
+	}
+
+	return b.f.plan(vals, b.skipInsert)
+}
+
+// plan returns the plan of inserting the factory values(pointers to T) and the associations.
+// The factory values are not inserted if skipInsert is true
+func (f *Factory[T]) plan(vals []interface{}, skipInsert bool) (*InsertPlan, error) {
+	if len(f.associations) == 0 {
+		omitFields := f.factoryOmitFields(vals)
+		table := f.tablePlan(f.storageName, f.idField, vals, omitFields, f.noGeneratedID)
+		table.Skipped = skipInsert
+		return &InsertPlan{Tables: []TablePlan{table}, Edges: []EdgePlan{}}, nil
+	}
+
+	// the factory values are added to the associations as Insert does, and removed after planning
+	assocs := f.associations
+	f.associations = append(slices.Clip(assocs), vals)
+	defer func() { f.associations = assocs }()
+
+	nodes, err := f.prepareAssocNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if skipInsert {
+		f.skipFactoryNode(nodes)
+	}
+
+	p := &InsertPlan{Tables: make([]TablePlan, 0, len(nodes)), Edges: []EdgePlan{}}
+	for _, node := range nodes {
+		var table TablePlan
+		if node.name == reflect.TypeOf(f.empty).Name() {
+			table = f.tablePlan(node.tableName, node.idField, node.vals, f.factoryOmitFields(node.vals), f.noGeneratedID)
+		} else {
+			table = f.tablePlan(node.tableName, node.idField, node.vals, f.omitFields(node.vals), false)
+		}
+		table.Skipped = node.skipInsert
+		p.Tables = append(p.Tables, table)
+
+		for _, dep := range node.dependencies {
+			// the foreign key is left as it is without the dependency values
+			if len(dep.vals) == 0 {
+				continue
+			}
+
+			p.Edges = append(p.Edges, EdgePlan{
+				From:     node.tableName,
+				Field:    dep.fieldName,
+				To:       dep.tableName,
+				RefField: f.refFieldName(dep, dep.vals[0]),
+				IsMany:   dep.isMany,
+			})
+		}
+	}
+
+	return p, nil
+}
+
+// tablePlan returns the plan of inserting the values(pointers to the structs of the same type) into the storage.
+// If noGeneratedID is true, the ID field is inserted as the other fields unless it's omitted
+func (f *Factory[T]) tablePlan(storageName, idField string, vals []interface{}, omitFields []string, noGeneratedID bool) TablePlan {
+	typ := reflect.TypeOf(vals[0]).Elem()
+	table := TablePlan{
+		Name:            storageName,
+		Type:            typ.Name(),
+		Rows:            len(vals),
+		Fields:          []string{},
+		GeneratedFields: []string{},
+	}
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		switch {
+		case field.Name == idField && !noGeneratedID:
+			table.GeneratedFields = append(table.GeneratedFields, field.Name)
+		case slices.Contains(omitFields, field.Name):
+			// the ID field omitted without being generated is neither inserted nor generated
+			if field.Name != idField {
+				table.GeneratedFields = append(table.GeneratedFields, field.Name)
+			}
+		default:
+			table.Fields = append(table.Fields, field.Name)
+		}
+	}
+
+	return table
+}
@@ -1,13 +1,111 @@
 package db
 
+import "context"
+
 // InsertParams is a struct that holds the parameters for the Insert method
 type InsertParams struct {
 	StorageName string
+	IDField     string
 	Value       interface{}
+
+	// OmitFields is the list of the fields not inserted, so the database sets them by the column defaults
+	OmitFields []string
+
+	// NoGeneratedID is whether the database doesn't generate the ID, e.g. the writable views without the ID column.
+	// The ID isn't returned and set to the ID field, and the ID field is inserted as the other fields unless it's omitted
+	NoGeneratedID bool
 }
 
 // InsertListParams is a struct that holds the parameters for the InsertList method
 type InsertListParams struct {
 	StorageName string
+	IDField     string
 	Values      []interface{}
+
+	// OmitFields is the list of the fields not inserted, so the database sets them by the column defaults
+	OmitFields []string
+
+	// NoGeneratedID is whether the database doesn't generate the ID, e.g. the writable views without the ID column.
+	// The ID isn't returned and set to the ID field, and the ID field is inserted as the other fields unless it's omitted
+	NoGeneratedID bool
+}
+
+// UpdateParams is a struct that holds the parameters for the Update method
+type UpdateParams struct {
+	StorageName string
+	IDField     string
+	Value       interface{}
+
+	// OmitFields is the list of the fields not updated, e.g. the fields not inserted
+	OmitFields []string
+}
+
+// DeleteListParams is a struct that holds the parameters for the DeleteList method
+type DeleteListParams struct {
+	StorageName  string
+	IDField      string
+	Values       []interface{}
+	IsSoftDelete bool
+}
+
+// CheckSchemaParams is a struct that holds the parameters for the CheckSchema method
+type CheckSchemaParams struct {
+	StorageName string
+	IDField     string
+	Value       interface{}
+
+	// OmitFields is the list of fields which aren't inserted, so their columns aren't required
+	OmitFields []string
+}
+
+// FindParams is a struct that holds the parameters for the Find method
+type FindParams struct {
+	StorageName string
+	IDField     string
+
+	// Value is the pointer to the struct, the rows are returned as the pointers to the values of its type
+	Value interface{}
+
+	// Conditions is the list of the conditions, the returned rows match all of them
+	Conditions []Condition
+
+	// Limit is the maximum number of the returned rows, 0 means no limit
+	Limit int
+}
+
+// Condition is the condition that the field of the row equals the value
+type Condition struct {
+	// Field is the name of the struct field
+	Field string
+
+	// Value is the value of the field type, the nil pointer means the field is NULL
+	Value interface{}
+}
+
+// InsertError is the error of inserting the values, returned by the adapters to give the context of the failed row
+type InsertError struct {
+	// Columns is the list of the inserted columns, nil means unknown
+	Columns []string
+
+	// Index is the index of the failed value in the inserted values, -1 means unknown, e.g. the multi-row statement
+	Index int
+
+	// Row is the list of the values of the failed row in the order of the columns, nil means unknown
+	Row []interface{}
+
+	Err error
+}
+
+func (e *InsertError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InsertError) Unwrap() error {
+	return e.Err
+}
+
+// Tx is a shared transaction started by the BeginTx method of the database adapter
+type Tx interface {
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
 }
@@ -3,14 +3,56 @@ package sqllib
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/eyo-chen/gofacto/internal/db"
 	"github.com/eyo-chen/gofacto/internal/utils"
 )
 
+var (
+	// errInvalidStorageName is the error representing that the storage name is not a valid identifier
+	errInvalidStorageName = errors.New("invalid storage name")
+
+	// errFieldNotFound is the error representing that the field in the field order is not found
+	errFieldNotFound = errors.New("field not found")
+
+	// errTableNotFound is the error representing that the table doesn't exist
+	errTableNotFound = errors.New("table not found")
+
+	// errColumnNotFound is the error representing that the columns of the fields don't exist in the table
+	errColumnNotFound = errors.New("column not found")
+
+	// errIDCountMismatch is the error representing that the number of the returned IDs doesn't match the inserted rows
+	errIDCountMismatch = errors.New("number of returned IDs doesn't match the inserted rows")
+
+	// errInvalidColumnName is the error representing that the column name is not a valid identifier
+	errInvalidColumnName = errors.New("invalid column name")
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+const (
+	// defaultSoftDeleteColumn is the column marked with the deleted time when soft deleting by default
+	defaultSoftDeleteColumn = "deleted_at"
+
+	// defaultIDColumn is the ID column used when the struct doesn't have the ID field
+	defaultIDColumn = "id"
+
+	// maxBatchParams is the maximum number of the parameters of a batch insert statement, it's the limit of PostgreSQL.
+	// The rows exceeding the limit are split into multiple statements
+	maxBatchParams = 65535
+)
+
 // Config is for raw SQL database operations
 type Config struct {
 	// db is the database connection
@@ -21,18 +63,73 @@ type Config struct {
 
 	// packageName is the package name
 	packageName string
+
+	// tx is the shared transaction, it's only set when the config is created by BeginTx
+	tx *sql.Tx
+
+	// isAlphabetical is whether to order the columns by the column name alphabetically,
+	// otherwise, the columns are ordered by the struct field order
+	isAlphabetical bool
+
+	// fieldOrders is a map from the struct type to the fields ordered first
+	fieldOrders map[reflect.Type][]string
+
+	// softDeleteColumn is the column marked with the deleted time when soft deleting, empty means deleted_at
+	softDeleteColumn string
+}
+
+// txConfig is the config bound to a shared transaction
+type txConfig struct {
+	*Config
+}
+
+// execer is the common interface of *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 }
 
 // sqlDialect defines the behavior for different SQL dialects
 type sqlDialect interface {
-	// GenPlaceholder generates a placeholder
-	GenPlaceholder(placeholderIdx int) string
+	// GenPlaceholder generates a placeholder for the given field
+	GenPlaceholder(field reflect.StructField, placeholderIdx int) string
 
 	// GenInsertStmt generates an insert raw SQL statement
-	GenInsertStmt(tableName, fieldNames, placeholder string) string
+	GenInsertStmt(tableName, idColumn, fieldNames, placeholder string) string
+
+	// InsertToDB inserts the values to the database, and returns the generated ID
+	InsertToDB(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, vals []interface{}) (interface{}, error)
+
+	// ConvertValue converts the field value to the form accepted by the driver
+	ConvertValue(field reflect.StructField, v interface{}) (interface{}, bool)
 
-	// InsertToDB inserts the values to the database
-	InsertToDB(ctx context.Context, tx *sql.Tx, stmt *sql.Stmt, vals []interface{}) (int64, error)
+	// GenCustomType generates a non-zero value for dialect specific types
+	GenCustomType(t reflect.Type) (interface{}, bool)
+
+	// QuoteIdentifier quotes the identifier, e.g. the table name.
+	// The identifier is guaranteed to be valid, and it might be qualified by the schema
+	QuoteIdentifier(name string) string
+
+	// GenIdentifyStmt generates the statement querying the database name and the host in one row
+	GenIdentifyStmt() string
+
+	// GenColumnsStmt generates the statement querying the column names of the table, and its arguments.
+	// The table name is guaranteed to be valid, and it might be qualified by the schema
+	GenColumnsStmt(tableName string) (string, []interface{})
+}
+
+// batchDialect is the dialect which is able to insert multiple rows in one statement
+type batchDialect interface {
+	// GenBatchInsertStmt generates an insert statement of multiple rows returning the generated IDs in the order of the rows.
+	// rowPlaceholders is the list of the placeholders of each row
+	GenBatchInsertStmt(tableName, idColumn, fieldNames string, rowPlaceholders []string) string
+}
+
+// batchStmt is a batch insert statement with its values
+type batchStmt struct {
+	rawStmt string
+	vals    []interface{}
+	numRows int
 }
 
 // NewConfig initializes a sqllib config for raw SQL database operations
@@ -44,32 +141,72 @@ func NewConfig(db *sql.DB, dialect sqlDialect, packageName string) *Config {
 	}
 }
 
-func (c *Config) Insert(ctx context.Context, params db.InsertParams) (interface{}, error) {
-	rawStmt, vals := c.prepareStmtAndVals(params.StorageName, params.Value)
+// WithAlphabeticalColumns sets whether to order the columns by the column name alphabetically.
+// By default, the columns are ordered by the struct field order
+func (c *Config) WithAlphabeticalColumns(isAlphabetical bool) *Config {
+	c.isAlphabetical = isAlphabetical
+	return c
+}
 
-	stmt, err := c.db.Prepare(rawStmt)
+// WithFieldOrder sets the explicit order of the fields of the struct(v).
+// The given fields come first in the given order, and the rest follow the default order.
+// It keeps the generated SQL statements stable when adding the struct fields
+func (c *Config) WithFieldOrder(v interface{}, fields ...string) *Config {
+	typ := reflect.TypeOf(v)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if c.fieldOrders == nil {
+		c.fieldOrders = map[reflect.Type][]string{}
+	}
+	c.fieldOrders[typ] = fields
+
+	return c
+}
+
+// WithSoftDeleteColumn sets the column marked with the deleted time when soft deleting,
+// e.g. the factories configured with WithIsSoftDelete(true). By default, it's deleted_at
+func (c *Config) WithSoftDeleteColumn(column string) *Config {
+	c.softDeleteColumn = column
+	return c
+}
+
+func (c *Config) Insert(ctx context.Context, params db.InsertParams) (interface{}, error) {
+	tableName, err := c.tableName(params.StorageName)
 	if err != nil {
 		return nil, err
 	}
-	defer stmt.Close()
 
-	tx, err := c.db.Begin()
+	if params.NoGeneratedID {
+		if err := c.insertWithoutID(ctx, tableName, params.OmitFields, params.Value); err != nil {
+			return nil, err
+		}
+
+		return params.Value, nil
+	}
+
+	rawStmt, vals, err := c.prepareStmtAndVals(tableName, params.IDField, params.OmitFields, params.Value)
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) && err == nil {
-			err = rollbackErr
-		}
-	}()
 
-	id, err := c.dialect.InsertToDB(ctx, tx, stmt, vals[0])
+	stmt, err := c.db.Prepare(rawStmt)
 	if err != nil {
 		return nil, err
 	}
+	defer stmt.Close()
+
+	err = c.runInTx(ctx, func(tx *sql.Tx) error {
+		id, err := c.dialect.InsertToDB(ctx, tx, stmt, vals[0])
+		if err != nil {
+			return c.insertError(params.IDField, params.OmitFields, params.Value, 0, vals[0], err)
+		}
 
-	setIDField(params.Value, id)
-	if err := tx.Commit(); err != nil {
+		setIDField(params.Value, params.IDField, id)
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -77,7 +214,27 @@ func (c *Config) Insert(ctx context.Context, params db.InsertParams) (interface{
 }
 
 func (c *Config) InsertList(ctx context.Context, params db.InsertListParams) ([]interface{}, error) {
-	rawStmt, fieldValues := c.prepareStmtAndVals(params.StorageName, params.Values...)
+	tableName, err := c.tableName(params.StorageName)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.NoGeneratedID {
+		if err := c.insertWithoutID(ctx, tableName, params.OmitFields, params.Values...); err != nil {
+			return nil, err
+		}
+
+		return params.Values, nil
+	}
+
+	if bd, ok := c.dialect.(batchDialect); ok && len(params.Values) > 1 {
+		return c.insertBatch(ctx, bd, tableName, params)
+	}
+
+	rawStmt, fieldValues, err := c.prepareStmtAndVals(tableName, params.IDField, params.OmitFields, params.Values...)
+	if err != nil {
+		return nil, err
+	}
 
 	stmt, err := c.db.Prepare(rawStmt)
 	if err != nil {
@@ -85,96 +242,737 @@ func (c *Config) InsertList(ctx context.Context, params db.InsertListParams) ([]
 	}
 	defer stmt.Close()
 
-	tx, err := c.db.Begin()
+	result := make([]interface{}, len(fieldValues))
+	err = c.runInTx(ctx, func(tx *sql.Tx) error {
+		for i, vals := range fieldValues {
+			id, err := c.dialect.InsertToDB(ctx, tx, stmt, vals)
+			if err != nil {
+				return c.insertError(params.IDField, params.OmitFields, params.Values[i], i, vals, err)
+			}
+
+			v := params.Values[i]
+			setIDField(v, params.IDField, id)
+
+			result[i] = v
+		}
+
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer func() {
-		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) && err == nil {
-			err = rollbackErr
+
+	return result, nil
+}
+
+// insertWithoutID inserts the values with the plain insert statement, which doesn't return the generated ID,
+// e.g. into the writable views without the ID column.
+// values are the pointer to the struct
+func (c *Config) insertWithoutID(ctx context.Context, tableName string, omitFields []string, values ...interface{}) error {
+	rawStmt, fieldValues, err := c.prepareStmtWithoutID(tableName, omitFields, values...)
+	if err != nil {
+		return err
+	}
+
+	return c.runInTx(ctx, func(tx *sql.Tx) error {
+		for i, vals := range fieldValues {
+			if _, err := tx.ExecContext(ctx, rawStmt, vals...); err != nil {
+				return c.insertError("", omitFields, values[i], i, vals, err)
+			}
 		}
-	}()
 
-	result := make([]interface{}, len(fieldValues))
-	for i, vals := range fieldValues {
-		id, err := c.dialect.InsertToDB(ctx, tx, stmt, vals)
-		if err != nil {
+		return nil
+	})
+}
+
+// insertBatch inserts the values with the batch insert statements, and sets the returned IDs in order.
+// It reduces the round trips of inserting the large list against the remote database
+func (c *Config) insertBatch(ctx context.Context, bd batchDialect, tableName string, params db.InsertListParams) ([]interface{}, error) {
+	stmts, err := c.prepareBatchStmts(bd, tableName, params.IDField, params.OmitFields, params.Values...)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.runInTx(ctx, func(tx *sql.Tx) error {
+		inserted := 0
+		for _, stmt := range stmts {
+			ids, err := queryIDs(ctx, tx, stmt)
+			if err != nil {
+				// the failed row of the multi-row statement is unknown
+				return c.insertError(params.IDField, params.OmitFields, params.Values[0], -1, nil, err)
+			}
+
+			for _, id := range ids {
+				setIDField(params.Values[inserted], params.IDField, id)
+				inserted++
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return params.Values, nil
+}
+
+// queryIDs executes the batch insert statement, and returns the generated IDs in order
+func queryIDs(ctx context.Context, tx *sql.Tx, stmt batchStmt) ([]interface{}, error) {
+	rows, err := tx.QueryContext(ctx, stmt.rawStmt, stmt.vals...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]interface{}, 0, stmt.numRows)
+	for rows.Next() {
+		var id interface{}
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) != stmt.numRows {
+		return nil, fmt.Errorf("%w: %d IDs for %d rows", errIDCountMismatch, len(ids), stmt.numRows)
+	}
+
+	return ids, nil
+}
 
-		v := params.Values[i]
-		setIDField(v, id)
+func (c *Config) Update(ctx context.Context, params db.UpdateParams) error {
+	tableName, err := c.tableName(params.StorageName)
+	if err != nil {
+		return err
+	}
 
-		result[i] = v
+	rawStmt, vals, err := c.prepareUpdateStmt(tableName, params)
+	if err != nil || rawStmt == "" {
+		return err
 	}
 
-	if err := tx.Commit(); err != nil {
+	_, err = c.execer().ExecContext(ctx, rawStmt, vals...)
+	return err
+}
+
+// prepareUpdateStmt prepares the SQL update statement of the value by the ID field and the values to be set.
+// The statement is empty if the value has no ID field
+func (c *Config) prepareUpdateStmt(tableName string, params db.UpdateParams) (string, []interface{}, error) {
+	val := reflect.ValueOf(params.Value).Elem()
+	idField, ok := val.Type().FieldByName(params.IDField)
+	if !ok {
+		return "", nil, nil
+	}
+
+	order, err := c.fieldOrder(val.Type())
+	if err != nil {
+		return "", nil, err
+	}
+
+	sets := []string{}
+	vals := []interface{}{}
+	placeholderIndex := 1
+	for _, i := range order {
+		field := val.Type().Field(i)
+		if field.Name == params.IDField || !isInserted(field, params.OmitFields) {
+			continue
+		}
+
+		v := val.Field(i).Interface()
+		if cv, ok := c.ConvertValue(field, v); ok {
+			v = cv
+		}
+		vals = append(vals, v)
+
+		sets = append(sets, fmt.Sprintf("%s = %s", c.columnName(field), c.dialect.GenPlaceholder(field, placeholderIndex)))
+		placeholderIndex++
+	}
+	vals = append(vals, val.FieldByName(params.IDField).Interface())
+
+	rawStmt := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s",
+		tableName, strings.Join(sets, ", "), c.columnName(idField), c.dialect.GenPlaceholder(idField, placeholderIndex))
+
+	return rawStmt, vals, nil
+}
+
+func (c *Config) DeleteList(ctx context.Context, params db.DeleteListParams) error {
+	if len(params.Values) == 0 {
+		return nil
+	}
+
+	tableName, err := c.tableName(params.StorageName)
+	if err != nil {
+		return err
+	}
+
+	rawStmt, ids, err := c.prepareDeleteStmt(tableName, params)
+	if err != nil || rawStmt == "" {
+		return err
+	}
+
+	_, err = c.execer().ExecContext(ctx, rawStmt, ids...)
+	return err
+}
+
+// prepareDeleteStmt prepares the SQL statement deleting the values by the ID field and the IDs.
+// If IsSoftDelete is true, the statement sets the soft delete column to the current time instead.
+// The statement is empty if the values have no ID field
+func (c *Config) prepareDeleteStmt(tableName string, params db.DeleteListParams) (string, []interface{}, error) {
+	idField, ok := reflect.TypeOf(params.Values[0]).Elem().FieldByName(params.IDField)
+	if !ok {
+		return "", nil, nil
+	}
+
+	ids := make([]interface{}, len(params.Values))
+	placeholders := make([]string, len(params.Values))
+	for i, v := range params.Values {
+		ids[i] = reflect.ValueOf(v).Elem().FieldByName(params.IDField).Interface()
+		placeholders[i] = c.dialect.GenPlaceholder(idField, i+1)
+	}
+
+	if !params.IsSoftDelete {
+		return fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)",
+			tableName, c.columnName(idField), strings.Join(placeholders, ", ")), ids, nil
+	}
+
+	column := c.softDeleteColumn
+	if column == "" {
+		column = defaultSoftDeleteColumn
+	}
+
+	// the column is concatenated into the SQL statement, so only the plain identifier is allowed
+	if !utils.IsValidIdentifier(column) {
+		return "", nil, fmt.Errorf("%w: %q", errInvalidColumnName, column)
+	}
+
+	return fmt.Sprintf("UPDATE %s SET %s = CURRENT_TIMESTAMP WHERE %s IN (%s)",
+		tableName, column, c.columnName(idField), strings.Join(placeholders, ", ")), ids, nil
+}
+
+// Find returns the rows of the table matching all the conditions, the rows are scanned into the values of the struct type.
+// The columns which can't be scanned by the driver, e.g. JSON, are decoded as JSON, and left zero if they can't be decoded
+func (c *Config) Find(ctx context.Context, params db.FindParams) ([]interface{}, error) {
+	tableName, err := c.tableName(params.StorageName)
+	if err != nil {
 		return nil, err
 	}
 
-	return result, nil
+	typ := reflect.TypeOf(params.Value).Elem()
+	order, err := c.fieldOrder(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]int, 0, len(order))
+	columns := make([]string, 0, len(order))
+	for _, i := range order {
+		if field := typ.Field(i); field.IsExported() {
+			fields = append(fields, i)
+			columns = append(columns, c.columnName(field))
+		}
+	}
+
+	wheres := make([]string, 0, len(params.Conditions))
+	args := make([]interface{}, 0, len(params.Conditions))
+	for _, cond := range params.Conditions {
+		field, ok := typ.FieldByName(cond.Field)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errFieldNotFound, cond.Field)
+		}
+
+		if isNull(cond.Value) {
+			wheres = append(wheres, fmt.Sprintf("%s IS NULL", c.columnName(field)))
+			continue
+		}
+
+		v := cond.Value
+		if cv, ok := c.ConvertValue(field, v); ok {
+			v = cv
+		}
+		args = append(args, v)
+
+		wheres = append(wheres, fmt.Sprintf("%s = %s", c.columnName(field), c.dialect.GenPlaceholder(field, len(args))))
+	}
+
+	rawStmt := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), tableName)
+	if len(wheres) > 0 {
+		rawStmt += " WHERE " + strings.Join(wheres, " AND ")
+	}
+	if params.Limit > 0 {
+		rawStmt += fmt.Sprintf(" LIMIT %d", params.Limit)
+	}
+
+	rows, err := c.execer().QueryContext(ctx, rawStmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []interface{}
+	for rows.Next() {
+		v := reflect.New(typ)
+		dests := make([]interface{}, len(fields))
+		decodes := make([]func(), 0, len(fields))
+		for i, idx := range fields {
+			dest, decode := scanDest(v.Elem().Field(idx))
+			dests[i] = dest
+			if decode != nil {
+				decodes = append(decodes, decode)
+			}
+		}
+
+		if err := rows.Scan(dests...); err != nil {
+			return nil, err
+		}
+
+		for _, decode := range decodes {
+			decode()
+		}
+
+		result = append(result, v.Interface())
+	}
+
+	return result, rows.Err()
 }
 
 func (c *Config) GenCustomType(t reflect.Type) (interface{}, bool) {
-	return nil, false
+	return c.dialect.GenCustomType(t)
+}
+
+func (c *Config) ConvertValue(field reflect.StructField, v interface{}) (interface{}, bool) {
+	return c.dialect.ConvertValue(field, v)
+}
+
+// Identify returns the database name and the host of the connection
+func (c *Config) Identify(ctx context.Context) ([]string, error) {
+	var name, host sql.NullString
+	if err := c.db.QueryRowContext(ctx, c.dialect.GenIdentifyStmt()).Scan(&name, &host); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, n := range []sql.NullString{name, host} {
+		if n.Valid && n.String != "" {
+			names = append(names, n.String)
+		}
+	}
+
+	return names, nil
+}
+
+// CheckSchema checks if the table exists, and the fields of the value map to its columns
+func (c *Config) CheckSchema(ctx context.Context, params db.CheckSchemaParams) error {
+	if _, err := c.tableName(params.StorageName); err != nil {
+		return err
+	}
+
+	columns, err := c.queryColumns(ctx, params.StorageName)
+	if err != nil {
+		return err
+	}
+
+	if len(columns) == 0 {
+		return fmt.Errorf("%w: %s", errTableNotFound, params.StorageName)
+	}
+
+	if missing := c.missingColumns(reflect.TypeOf(params.Value).Elem(), params.OmitFields, columns); len(missing) > 0 {
+		return fmt.Errorf("%w: %s in %s", errColumnNotFound, strings.Join(missing, ", "), params.StorageName)
+	}
+
+	return nil
+}
+
+// queryColumns returns the column names of the table, it's empty if the table doesn't exist
+func (c *Config) queryColumns(ctx context.Context, tableName string) ([]string, error) {
+	rawStmt, args := c.dialect.GenColumnsStmt(tableName)
+	rows, err := c.db.QueryContext(ctx, rawStmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+// missingColumns returns the column names of the fields of the struct type which are not in the columns,
+// the fields which aren't inserted are skipped
+func (c *Config) missingColumns(typ reflect.Type, omitFields []string, columns []string) []string {
+	var missing []string
+	for i := 0; i < typ.NumField(); i++ {
+		if !isInserted(typ.Field(i), omitFields) {
+			continue
+		}
+
+		column := c.columnName(typ.Field(i))
+		if !slices.ContainsFunc(columns, func(col string) bool { return strings.EqualFold(col, column) }) {
+			missing = append(missing, column)
+		}
+	}
+
+	return missing
+}
+
+// BeginTx starts a shared transaction, all the operations of the returned config are executed within it
+func (c *Config) BeginTx(ctx context.Context) (db.Tx, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	txc := *c
+	txc.tx = tx
+	return &txConfig{Config: &txc}, nil
+}
+
+func (c *txConfig) Commit(ctx context.Context) error {
+	return c.tx.Commit()
+}
+
+func (c *txConfig) Rollback(ctx context.Context) error {
+	return c.tx.Rollback()
+}
+
+// runInTx runs fn within the shared transaction if there is one,
+// otherwise, it runs fn within a new transaction which is committed when fn succeeds
+func (c *Config) runInTx(ctx context.Context, fn func(tx *sql.Tx) error) (err error) {
+	if c.tx != nil {
+		return fn(c.tx)
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) && err == nil {
+			err = rollbackErr
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execer returns the shared transaction if there is one, otherwise, the database connection
+func (c *Config) execer() execer {
+	if c.tx != nil {
+		return c.tx
+	}
+
+	return c.db
 }
 
 // prepareStmtAndVals prepares the SQL insert statement and the values to be inserted
 // values are the pointer to the struct
-func (c *Config) prepareStmtAndVals(tableName string, values ...interface{}) (string, [][]interface{}) {
-	fieldNames := []string{}
-	placeholders := []string{}
-	fieldValues := [][]interface{}{}
+func (c *Config) prepareStmtAndVals(tableName, idField string, omitFields []string, values ...interface{}) (string, [][]interface{}, error) {
+	idColumn, fields, fieldValues, err := c.prepareColumnsAndVals(idField, omitFields, values...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	fieldNames := make([]string, len(fields))
+	placeholders := make([]string, len(fields))
+	for i, field := range fields {
+		fieldNames[i] = c.columnName(field)
+		placeholders[i] = c.dialect.GenPlaceholder(field, i+1)
+	}
 
+	// construct the SQL insert statement
+	fns := strings.Join(fieldNames, ", ")
+	phs := strings.Join(placeholders, ", ")
+	rawStmt := c.dialect.GenInsertStmt(tableName, idColumn, fns, phs)
+
+	return rawStmt, fieldValues, nil
+}
+
+// prepareStmtWithoutID prepares the SQL insert statement not returning the generated ID, and the values to be inserted.
+// The ID field is inserted as the other fields unless it's omitted.
+// values are the pointer to the struct
+func (c *Config) prepareStmtWithoutID(tableName string, omitFields []string, values ...interface{}) (string, [][]interface{}, error) {
+	_, fields, fieldValues, err := c.prepareColumnsAndVals("", omitFields, values...)
+	if err != nil {
+		return "", nil, err
+	}
+
+	fieldNames := make([]string, len(fields))
+	placeholders := make([]string, len(fields))
+	for i, field := range fields {
+		fieldNames[i] = c.columnName(field)
+		placeholders[i] = c.dialect.GenPlaceholder(field, i+1)
+	}
+
+	rawStmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", tableName, strings.Join(fieldNames, ", "), strings.Join(placeholders, ", "))
+
+	return rawStmt, fieldValues, nil
+}
+
+// insertError wraps the error of inserting the row of the index with the inserted columns and the values of the row.
+// v is the pointer to the struct
+func (c *Config) insertError(idField string, omitFields []string, v interface{}, index int, row []interface{}, err error) error {
+	_, fields, fErr := c.insertedFields(reflect.TypeOf(v).Elem(), idField, omitFields)
+	if fErr != nil {
+		return err
+	}
+
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = c.columnName(field)
+	}
+
+	return &db.InsertError{Columns: columns, Index: index, Row: row, Err: err}
+}
+
+// prepareBatchStmts prepares the batch insert statements of the values,
+// each statement has at most maxBatchParams parameters.
+// values are the pointer to the struct
+func (c *Config) prepareBatchStmts(bd batchDialect, tableName, idField string, omitFields []string, values ...interface{}) ([]batchStmt, error) {
+	idColumn, fields, fieldValues, err := c.prepareColumnsAndVals(idField, omitFields, values...)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldNames := make([]string, len(fields))
+	for i, field := range fields {
+		fieldNames[i] = c.columnName(field)
+	}
+	fns := strings.Join(fieldNames, ", ")
+
+	rowsPerStmt := len(fieldValues)
+	if len(fields) > 0 {
+		rowsPerStmt = min(rowsPerStmt, maxBatchParams/len(fields))
+	}
+
+	var stmts []batchStmt
+	for start := 0; start < len(fieldValues); start += rowsPerStmt {
+		rows := fieldValues[start:min(start+rowsPerStmt, len(fieldValues))]
+
+		rowPlaceholders := make([]string, len(rows))
+		vals := make([]interface{}, 0, len(rows)*len(fields))
+		for i, row := range rows {
+			placeholders := make([]string, len(fields))
+			for j, field := range fields {
+				placeholders[j] = c.dialect.GenPlaceholder(field, i*len(fields)+j+1)
+			}
+
+			rowPlaceholders[i] = strings.Join(placeholders, ", ")
+			vals = append(vals, row...)
+		}
+
+		stmts = append(stmts, batchStmt{
+			rawStmt: bd.GenBatchInsertStmt(tableName, idColumn, fns, rowPlaceholders),
+			vals:    vals,
+			numRows: len(rows),
+		})
+	}
+
+	return stmts, nil
+}
+
+// prepareColumnsAndVals returns the ID column, the inserted fields in the order of the columns,
+// and the converted values of the fields of each value.
+// The omitted fields aren't inserted, so the database sets them by the column defaults.
+// values are the pointer to the struct
+func (c *Config) prepareColumnsAndVals(idField string, omitFields []string, values ...interface{}) (string, []reflect.StructField, [][]interface{}, error) {
+	idColumn, fields, err := c.insertedFields(reflect.TypeOf(values[0]).Elem(), idField, omitFields)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	fieldValues := make([][]interface{}, len(values))
 	for index, val := range values {
 		val := reflect.ValueOf(val).Elem()
-		vals := []interface{}{}
+		vals := make([]interface{}, len(fields))
 
-		placeholderIndex := 1
-		for i := 0; i < val.NumField(); i++ {
-			n := val.Type().Field(i).Name
-			if n == "ID" {
-				continue
+		for i, field := range fields {
+			v := val.FieldByIndex(field.Index).Interface()
+			if cv, ok := c.ConvertValue(field, v); ok {
+				v = cv
 			}
+			vals[i] = v
+		}
 
-			vals = append(vals, val.Field(i).Interface())
+		fieldValues[index] = vals
+	}
 
-			if index == 0 {
-				fieldName := val.Type().Field(i).Tag.Get(c.packageName)
-				if fieldName == "" {
-					fieldName = utils.CamelToSnake(n)
-				}
+	return idColumn, fields, fieldValues, nil
+}
 
-				fieldNames = append(fieldNames, fieldName)
-				placeholders = append(placeholders, c.dialect.GenPlaceholder(placeholderIndex))
-			}
+// insertedFields returns the ID column, and the inserted fields of the struct type in the order of the columns.
+// The ID field and the fields which aren't inserted are skipped
+func (c *Config) insertedFields(typ reflect.Type, idField string, omitFields []string) (string, []reflect.StructField, error) {
+	order, err := c.fieldOrder(typ)
+	if err != nil {
+		return "", nil, err
+	}
 
-			placeholderIndex++
+	idColumn := defaultIDColumn
+	fields := []reflect.StructField{}
+	for _, i := range order {
+		field := typ.Field(i)
+		if field.Name == idField {
+			idColumn = c.columnName(field)
+			continue
 		}
 
-		fieldValues = append(fieldValues, vals)
+		if isInserted(field, omitFields) {
+			fields = append(fields, field)
+		}
 	}
 
-	// construct the SQL insert statement
-	fns := strings.Join(fieldNames, ", ")
-	phs := strings.Join(placeholders, ", ")
-	rawStmt := c.dialect.GenInsertStmt(tableName, fns, phs)
+	return idColumn, fields, nil
+}
 
-	return rawStmt, fieldValues
+// isInserted checks if the field is inserted, which is exported and not omitted
+func isInserted(field reflect.StructField, omitFields []string) bool {
+	return field.IsExported() && !slices.Contains(omitFields, field.Name)
 }
 
-// setIDField sets the id value on ID field of the given value
-func setIDField(v interface{}, id int64) {
+// fieldOrder returns the indexes of the fields of the struct type in the order of the columns.
+// The fields set by WithFieldOrder come first, and the rest are ordered by the column name alphabetically
+// if WithAlphabeticalColumns is set, otherwise, by the struct field order
+func (c *Config) fieldOrder(typ reflect.Type) ([]int, error) {
+	explicit := c.fieldOrders[typ]
+	order := make([]int, 0, typ.NumField())
+	for _, name := range explicit {
+		field, ok := typ.FieldByName(name)
+		if !ok || len(field.Index) != 1 {
+			return nil, fmt.Errorf("%w: %s", errFieldNotFound, name)
+		}
+
+		order = append(order, field.Index[0])
+	}
+
+	rest := make([]int, 0, typ.NumField()-len(order))
+	for i := 0; i < typ.NumField(); i++ {
+		if !slices.Contains(explicit, typ.Field(i).Name) {
+			rest = append(rest, i)
+		}
+	}
+
+	if c.isAlphabetical {
+		sort.SliceStable(rest, func(i, j int) bool {
+			return c.columnName(typ.Field(rest[i])) < c.columnName(typ.Field(rest[j]))
+		})
+	}
+
+	return append(order, rest...), nil
+}
+
+// tableName validates the storage name, and returns the quoted table name.
+// The storage name is concatenated into the SQL statement, so only the plain identifier is allowed
+func (c *Config) tableName(storageName string) (string, error) {
+	if !utils.IsValidIdentifier(storageName) {
+		return "", fmt.Errorf("%w: %q", errInvalidStorageName, storageName)
+	}
+
+	return c.dialect.QuoteIdentifier(storageName), nil
+}
+
+// columnName returns the column name of the given field.
+// The tag might contain options after the column name, e.g. `mysqlf:"location,geometry"`
+func (c *Config) columnName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get(c.packageName), ",")
+	if name == "" {
+		return utils.CamelToSnake(field.Name)
+	}
+
+	return name
+}
+
+// setIDField sets the id value on the ID field of the given value
+func setIDField(v interface{}, name string, id interface{}) {
 	val := reflect.ValueOf(v).Elem()
-	idField := val.FieldByName("ID")
-	if !idField.IsValid() || !idField.CanSet() {
+	idField := val.FieldByName(name)
+	if name == "" || !idField.IsValid() || !idField.CanSet() {
 		return
 	}
 
-	switch idField.Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		idField.SetInt(id)
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		idField.SetUint(uint64(id))
+	// e.g. UUID returned as []byte by the driver
+	if b, ok := id.([]byte); ok {
+		id = string(b)
+	}
+
+	idVal := reflect.ValueOf(id)
+	if !idVal.IsValid() || !idVal.Type().ConvertibleTo(idField.Type()) {
+		return
+	}
+
+	// string can't be converted from integer implicitly
+	if idField.Kind() == reflect.String && idVal.Kind() != reflect.String {
+		return
+	}
+
+	idField.Set(idVal.Convert(idField.Type()))
+}
+
+// isNull checks if the value is nil or the nil pointer
+func isNull(v interface{}) bool {
+	val := reflect.ValueOf(v)
+	return !val.IsValid() || (val.Kind() == reflect.Ptr && val.IsNil())
+}
+
+// scanDest returns the destination scanning the column into the field.
+// If the field can't be scanned by the driver, the column is scanned into the raw value,
+// and the returned function decodes it into the field after scanning
+func scanDest(field reflect.Value) (interface{}, func()) {
+	if isScannable(field.Type()) {
+		return field.Addr().Interface(), nil
+	}
+
+	var raw interface{}
+	return &raw, func() {
+		switch r := raw.(type) {
+		case nil:
+		case []byte:
+			_ = json.Unmarshal(r, field.Addr().Interface())
+		case string:
+			_ = json.Unmarshal([]byte(r), field.Addr().Interface())
+		default:
+			if rv := reflect.ValueOf(r); rv.Type().ConvertibleTo(field.Type()) {
+				field.Set(rv.Convert(field.Type()))
+			}
+		}
+	}
+}
+
+// isScannable checks if the column can be scanned into the type by the driver directly
+func isScannable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType || reflect.PointerTo(t).Implements(scannerType) {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Slice:
+		// []byte is scanned as BLOB or BYTEA
+		return t.Elem().Kind() == reflect.Uint8
+	default:
+		return false
 	}
 }
@@ -0,0 +1,488 @@
+package sqllib
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eyo-chen/gofacto/internal/db"
+)
+
+type testStruct struct {
+	ID     int
+	Name   string
+	Age    int
+	Email  string `testf:"mail"`
+	Active bool
+}
+
+// mockDialect generates the statements with the question mark placeholders
+type mockDialect struct{}
+
+func (d *mockDialect) GenPlaceholder(reflect.StructField, int) string { return "?" }
+
+func (d *mockDialect) GenInsertStmt(tableName, _, fieldNames, placeholder string) string {
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", tableName, fieldNames, placeholder)
+}
+
+func (d *mockDialect) InsertToDB(context.Context, *sql.Tx, *sql.Stmt, []interface{}) (interface{}, error) {
+	return nil, nil
+}
+
+func (d *mockDialect) ConvertValue(reflect.StructField, interface{}) (interface{}, bool) {
+	return nil, false
+}
+
+func (d *mockDialect) GenCustomType(reflect.Type) (interface{}, bool) { return nil, false }
+
+func (d *mockDialect) QuoteIdentifier(name string) string { return name }
+
+func (d *mockDialect) GenIdentifyStmt() string { return "" }
+
+func (d *mockDialect) GenColumnsStmt(string) (string, []interface{}) { return "", nil }
+
+func TestPrepareStmtAndVals(t *testing.T) {
+	tests := []struct {
+		desc       string
+		config     *Config
+		omitFields []string
+		wantStmt   string
+		wantVals   []interface{}
+	}{
+		{
+			desc:     "struct field order by default",
+			config:   NewConfig(nil, &mockDialect{}, "testf"),
+			wantStmt: "INSERT INTO tests (name, age, mail, active) VALUES (?, ?, ?, ?)",
+			wantVals: []interface{}{"name", 1, "a@b.c", true},
+		},
+		{
+			desc:     "alphabetical order",
+			config:   NewConfig(nil, &mockDialect{}, "testf").WithAlphabeticalColumns(true),
+			wantStmt: "INSERT INTO tests (active, age, mail, name) VALUES (?, ?, ?, ?)",
+			wantVals: []interface{}{true, 1, "a@b.c", "name"},
+		},
+		{
+			desc:     "explicit field order",
+			config:   NewConfig(nil, &mockDialect{}, "testf").WithFieldOrder(testStruct{}, "Email", "Name"),
+			wantStmt: "INSERT INTO tests (mail, name, age, active) VALUES (?, ?, ?, ?)",
+			wantVals: []interface{}{"a@b.c", "name", 1, true},
+		},
+		{
+			desc:     "explicit field order with alphabetical rest",
+			config:   NewConfig(nil, &mockDialect{}, "testf").WithFieldOrder(&testStruct{}, "Name").WithAlphabeticalColumns(true),
+			wantStmt: "INSERT INTO tests (name, active, age, mail) VALUES (?, ?, ?, ?)",
+			wantVals: []interface{}{"name", true, 1, "a@b.c"},
+		},
+		{
+			desc:       "omitted fields",
+			config:     NewConfig(nil, &mockDialect{}, "testf"),
+			omitFields: []string{"Age", "Active"},
+			wantStmt:   "INSERT INTO tests (name, mail) VALUES (?, ?)",
+			wantVals:   []interface{}{"name", "a@b.c"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			v := &testStruct{ID: 1, Name: "name", Age: 1, Email: "a@b.c", Active: true}
+			stmt, vals, err := test.config.prepareStmtAndVals("tests", "ID", test.omitFields, v)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if stmt != test.wantStmt {
+				t.Fatalf("statement should be %s, got %s", test.wantStmt, stmt)
+			}
+			if !reflect.DeepEqual(vals[0], test.wantVals) {
+				t.Fatalf("values should be %v, got %v", test.wantVals, vals[0])
+			}
+		})
+	}
+}
+
+func TestPrepareStmtAndVals_FieldNotFound(t *testing.T) {
+	c := NewConfig(nil, &mockDialect{}, "testf").WithFieldOrder(testStruct{}, "Phone")
+
+	_, _, err := c.prepareStmtAndVals("tests", "ID", nil, &testStruct{})
+	if !errors.Is(err, errFieldNotFound) {
+		t.Fatalf("error should be %v, got %v", errFieldNotFound, err)
+	}
+}
+
+func TestPrepareStmtWithoutID(t *testing.T) {
+	tests := []struct {
+		desc       string
+		omitFields []string
+		wantStmt   string
+		wantVals   []interface{}
+	}{
+		{
+			desc:     "ID field inserted",
+			wantStmt: "INSERT INTO tests (id, name, age, mail, active) VALUES (?, ?, ?, ?, ?)",
+			wantVals: []interface{}{1, "name", 1, "a@b.c", true},
+		},
+		{
+			desc:       "ID field omitted",
+			omitFields: []string{"ID", "Age"},
+			wantStmt:   "INSERT INTO tests (name, mail, active) VALUES (?, ?, ?)",
+			wantVals:   []interface{}{"name", "a@b.c", true},
+		},
+	}
+
+	c := NewConfig(nil, &mockDialect{}, "testf")
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			v := &testStruct{ID: 1, Name: "name", Age: 1, Email: "a@b.c", Active: true}
+			stmt, vals, err := c.prepareStmtWithoutID("tests", test.omitFields, v)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if stmt != test.wantStmt {
+				t.Fatalf("statement should be %s, got %s", test.wantStmt, stmt)
+			}
+			if !reflect.DeepEqual(vals[0], test.wantVals) {
+				t.Fatalf("values should be %v, got %v", test.wantVals, vals[0])
+			}
+		})
+	}
+}
+
+func TestPrepareUpdateStmt(t *testing.T) {
+	tests := []struct {
+		desc       string
+		omitFields []string
+		wantStmt   string
+		wantVals   []interface{}
+	}{
+		{
+			desc:     "all fields updated",
+			wantStmt: "UPDATE tests SET name = ?, age = ?, mail = ?, active = ? WHERE id = ?",
+			wantVals: []interface{}{"name", 1, "a@b.c", true, 1},
+		},
+		{
+			desc:       "omitted fields not updated",
+			omitFields: []string{"Age", "Active"},
+			wantStmt:   "UPDATE tests SET name = ?, mail = ? WHERE id = ?",
+			wantVals:   []interface{}{"name", "a@b.c", 1},
+		},
+	}
+
+	c := NewConfig(nil, &mockDialect{}, "testf")
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			v := &testStruct{ID: 1, Name: "name", Age: 1, Email: "a@b.c", Active: true}
+			stmt, vals, err := c.prepareUpdateStmt("tests", db.UpdateParams{IDField: "ID", Value: v, OmitFields: test.omitFields})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if stmt != test.wantStmt {
+				t.Fatalf("statement should be %s, got %s", test.wantStmt, stmt)
+			}
+			if !reflect.DeepEqual(vals, test.wantVals) {
+				t.Fatalf("values should be %v, got %v", test.wantVals, vals)
+			}
+		})
+	}
+}
+
+func TestPrepareStmt_UnexportedField(t *testing.T) {
+	type testUnexported struct {
+		ID    int
+		Name  string
+		cache string
+	}
+
+	c := NewConfig(nil, &mockDialect{}, "testf")
+	v := &testUnexported{ID: 1, Name: "name", cache: "cache"}
+
+	stmt, vals, err := c.prepareUpdateStmt("tests", db.UpdateParams{IDField: "ID", Value: v})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := "UPDATE tests SET name = ? WHERE id = ?"; stmt != want {
+		t.Fatalf("statement should be %s, got %s", want, stmt)
+	}
+	if want := []interface{}{"name", 1}; !reflect.DeepEqual(vals, want) {
+		t.Fatalf("values should be %v, got %v", want, vals)
+	}
+
+	stmt, insertVals, err := c.prepareStmtAndVals("tests", "ID", nil, v)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if want := "INSERT INTO tests (name) VALUES (?)"; stmt != want {
+		t.Fatalf("statement should be %s, got %s", want, stmt)
+	}
+	if want := []interface{}{"name"}; !reflect.DeepEqual(insertVals[0], want) {
+		t.Fatalf("values should be %v, got %v", want, insertVals[0])
+	}
+}
+
+func TestPrepareDeleteStmt(t *testing.T) {
+	tests := []struct {
+		desc             string
+		isSoftDelete     bool
+		softDeleteColumn string
+		wantStmt         string
+		wantErr          error
+	}{
+		{
+			desc:     "hard delete",
+			wantStmt: "DELETE FROM tests WHERE id IN (?, ?)",
+		},
+		{
+			desc:         "soft delete by default column",
+			isSoftDelete: true,
+			wantStmt:     "UPDATE tests SET deleted_at = CURRENT_TIMESTAMP WHERE id IN (?, ?)",
+		},
+		{
+			desc:             "soft delete by configured column",
+			isSoftDelete:     true,
+			softDeleteColumn: "removed_at",
+			wantStmt:         "UPDATE tests SET removed_at = CURRENT_TIMESTAMP WHERE id IN (?, ?)",
+		},
+		{
+			desc:             "invalid soft delete column",
+			isSoftDelete:     true,
+			softDeleteColumn: "removed_at = NULL; --",
+			wantErr:          errInvalidColumnName,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			c := NewConfig(nil, &mockDialect{}, "testf").WithSoftDeleteColumn(test.softDeleteColumn)
+			params := db.DeleteListParams{
+				IDField:      "ID",
+				Values:       []interface{}{&testStruct{ID: 1}, &testStruct{ID: 2}},
+				IsSoftDelete: test.isSoftDelete,
+			}
+
+			stmt, ids, err := c.prepareDeleteStmt("tests", params)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("error should be %v, got %v", test.wantErr, err)
+			}
+			if test.wantErr != nil {
+				return
+			}
+
+			if stmt != test.wantStmt {
+				t.Fatalf("statement should be %s, got %s", test.wantStmt, stmt)
+			}
+			if want := []interface{}{1, 2}; !reflect.DeepEqual(ids, want) {
+				t.Fatalf("IDs should be %v, got %v", want, ids)
+			}
+		})
+	}
+}
+
+func TestMissingColumns(t *testing.T) {
+	tests := []struct {
+		desc       string
+		omitFields []string
+		columns    []string
+		want       []string
+	}{
+		{desc: "all columns exist", columns: []string{"id", "name", "age", "mail", "active"}, want: nil},
+		{desc: "omitted column missing", omitFields: []string{"Email", "Active"}, columns: []string{"id", "name", "age"}, want: nil},
+		{desc: "columns in upper case", columns: []string{"ID", "NAME", "AGE", "MAIL", "ACTIVE"}, want: nil},
+		{desc: "tagged column missing", columns: []string{"id", "name", "age", "email", "active"}, want: []string{"mail"}},
+		{desc: "no columns", columns: nil, want: []string{"id", "name", "age", "mail", "active"}},
+	}
+
+	c := NewConfig(nil, &mockDialect{}, "testf")
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got := c.missingColumns(reflect.TypeOf(testStruct{}), test.omitFields, test.columns)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("missing columns should be %v, got %v", test.want, got)
+			}
+		})
+	}
+
+	t.Run("unexported field skipped", func(t *testing.T) {
+		type testUnexported struct {
+			ID    int
+			Name  string
+			cache string
+		}
+
+		got := c.missingColumns(reflect.TypeOf(testUnexported{}), nil, []string{"id", "name"})
+		if got != nil {
+			t.Fatalf("missing columns should be nil, got %v", got)
+		}
+	})
+}
+
+func TestInsertError(t *testing.T) {
+	c := NewConfig(nil, &mockDialect{}, "testf").WithAlphabeticalColumns(true)
+	errInsert := errors.New("duplicate key")
+	row := []interface{}{true, 1, "a@b.c", "name"}
+
+	err := c.insertError("ID", nil, &testStruct{}, 1, row, errInsert)
+	if !errors.Is(err, errInsert) {
+		t.Fatalf("error should be %v, got %v", errInsert, err)
+	}
+
+	var ie *db.InsertError
+	if !errors.As(err, &ie) {
+		t.Fatalf("error should be InsertError, got %T", err)
+	}
+
+	want := &db.InsertError{Columns: []string{"active", "age", "mail", "name"}, Index: 1, Row: row, Err: errInsert}
+	if !reflect.DeepEqual(ie, want) {
+		t.Fatalf("error should be %v, got %v", want, ie)
+	}
+}
+
+func TestInsertError_UnexportedField(t *testing.T) {
+	type testUnexported struct {
+		ID    int
+		cache string
+		Name  string
+	}
+
+	c := NewConfig(nil, &mockDialect{}, "testf")
+	v := &testUnexported{ID: 1, cache: "cache", Name: "name"}
+	_, vals, err := c.prepareStmtAndVals("tests", "ID", nil, v)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var ie *db.InsertError
+	if !errors.As(c.insertError("ID", nil, v, 0, vals[0], errors.New("duplicate key")), &ie) {
+		t.Fatalf("error should be InsertError")
+	}
+
+	if want := []string{"name"}; !reflect.DeepEqual(ie.Columns, want) {
+		t.Fatalf("columns should be %v, got %v", want, ie.Columns)
+	}
+	if len(ie.Columns) != len(ie.Row) {
+		t.Fatalf("columns %v should line up with the row %v", ie.Columns, ie.Row)
+	}
+}
+
+// mockBatchDialect generates the batch statements with the numbered placeholders
+type mockBatchDialect struct {
+	mockDialect
+}
+
+func (d *mockBatchDialect) GenPlaceholder(_ reflect.StructField, idx int) string {
+	return fmt.Sprintf("$%d", idx)
+}
+
+func (d *mockBatchDialect) GenBatchInsertStmt(tableName, idColumn, fieldNames string, rowPlaceholders []string) string {
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) RETURNING %s", tableName, fieldNames, strings.Join(rowPlaceholders, "), ("), idColumn)
+}
+
+func TestPrepareBatchStmts(t *testing.T) {
+	d := &mockBatchDialect{}
+	c := NewConfig(nil, d, "testf")
+
+	v1 := &testStruct{Name: "a", Age: 1, Email: "a@b.c", Active: true}
+	v2 := &testStruct{Name: "b", Age: 2, Email: "b@c.d"}
+	stmts, err := c.prepareBatchStmts(d, "tests", "ID", nil, v1, v2)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(stmts) != 1 {
+		t.Fatalf("number of statements should be 1, got %d", len(stmts))
+	}
+
+	wantStmt := "INSERT INTO tests (name, age, mail, active) VALUES ($1, $2, $3, $4), ($5, $6, $7, $8) RETURNING id"
+	if stmts[0].rawStmt != wantStmt {
+		t.Fatalf("statement should be %s, got %s", wantStmt, stmts[0].rawStmt)
+	}
+
+	wantVals := []interface{}{"a", 1, "a@b.c", true, "b", 2, "b@c.d", false}
+	if !reflect.DeepEqual(stmts[0].vals, wantVals) {
+		t.Fatalf("values should be %v, got %v", wantVals, stmts[0].vals)
+	}
+
+	if stmts[0].numRows != 2 {
+		t.Fatalf("number of rows should be 2, got %d", stmts[0].numRows)
+	}
+}
+
+func TestPrepareBatchStmts_Split(t *testing.T) {
+	d := &mockBatchDialect{}
+	c := NewConfig(nil, d, "testf")
+
+	// 4 columns, so at most 16383 rows per statement
+	rowsPerStmt := maxBatchParams / 4
+	values := make([]interface{}, rowsPerStmt+1)
+	for i := range values {
+		values[i] = &testStruct{}
+	}
+
+	stmts, err := c.prepareBatchStmts(d, "tests", "ID", nil, values...)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(stmts) != 2 {
+		t.Fatalf("number of statements should be 2, got %d", len(stmts))
+	}
+
+	if stmts[0].numRows != rowsPerStmt || len(stmts[0].vals) != rowsPerStmt*4 {
+		t.Fatalf("1st statement should have %d rows, got %d rows and %d values", rowsPerStmt, stmts[0].numRows, len(stmts[0].vals))
+	}
+
+	wantStmt := "INSERT INTO tests (name, age, mail, active) VALUES ($1, $2, $3, $4) RETURNING id"
+	if stmts[1].rawStmt != wantStmt || stmts[1].numRows != 1 {
+		t.Fatalf("2nd statement should be %s with 1 row, got %s with %d rows", wantStmt, stmts[1].rawStmt, stmts[1].numRows)
+	}
+}
+
+func TestScanDest(t *testing.T) {
+	type scanStruct struct {
+		Name  string
+		Tags  []string
+		Attrs map[string]int
+		Score int32
+	}
+
+	tests := []struct {
+		desc  string
+		field string
+		raw   interface{}
+		want  interface{}
+	}{
+		{desc: "JSON bytes", field: "Tags", raw: []byte(`["a","b"]`), want: []string{"a", "b"}},
+		{desc: "JSON string", field: "Attrs", raw: `{"a":1}`, want: map[string]int{"a": 1}},
+		{desc: "invalid JSON", field: "Tags", raw: []byte(`{a,b}`), want: []string(nil)},
+		{desc: "NULL", field: "Attrs", raw: nil, want: map[string]int(nil)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			v := reflect.ValueOf(&scanStruct{}).Elem()
+			dest, decode := scanDest(v.FieldByName(test.field))
+			if decode == nil {
+				t.Fatalf("%s should be decoded after scanning", test.field)
+			}
+
+			*dest.(*interface{}) = test.raw
+			decode()
+
+			if got := v.FieldByName(test.field).Interface(); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("%s should be %v, got %v", test.field, test.want, got)
+			}
+		})
+	}
+
+	// the basic types are scanned by the driver directly
+	v := reflect.ValueOf(&scanStruct{}).Elem()
+	for _, name := range []string{"Name", "Score"} {
+		if _, decode := scanDest(v.FieldByName(name)); decode != nil {
+			t.Fatalf("%s should be scanned directly", name)
+		}
+	}
+}
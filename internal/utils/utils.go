@@ -2,9 +2,21 @@ package utils
 
 import (
 	"bytes"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"unicode"
 )
 
+// maxRatScale is the scale of the rational number without the finite decimal representation, e.g. 1/3,
+// which is the maximum scale of DECIMAL in MySQL
+const maxRatScale = 30
+
+// identifierRegexp matches the SQL identifier, optionally qualified by the schema, e.g. users or public.users
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*(\.[A-Za-z_][A-Za-z0-9_$]*)?$`)
+
 // CamelToSnake converts a camel case string to a snake case string
 func CamelToSnake(input string) string {
 	var buf bytes.Buffer
@@ -22,3 +34,100 @@ func CamelToSnake(input string) string {
 
 	return buf.String()
 }
+
+// SnakeToCamel converts a snake case string to a camel case string with the upper case first letter
+func SnakeToCamel(input string) string {
+	var buf bytes.Buffer
+
+	for _, part := range strings.Split(input, "_") {
+		for i, r := range part {
+			if i == 0 {
+				r = unicode.ToUpper(r)
+			}
+			buf.WriteRune(r)
+		}
+	}
+
+	return buf.String()
+}
+
+// Pluralize returns the plural form of the word, which is the word with "s" appended
+func Pluralize(word string) string {
+	return word + "s"
+}
+
+// IsValidIdentifier reports whether the name is a plain SQL identifier,
+// so it's safe to be concatenated into the SQL statement
+func IsValidIdentifier(name string) bool {
+	return identifierRegexp.MatchString(name)
+}
+
+// DecimalString converts big.Rat, and the float with the decimal tag, e.g. `gofacto:"decimal:10,2"`, to the decimal string,
+// so the NUMERIC columns receive the exact value instead of the float losing precision.
+// The value is rounded to the scale of the tag, or the exact digits of big.Rat without the tag
+func DecimalString(field reflect.StructField, v interface{}) (string, bool) {
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return "", false
+		}
+
+		val = val.Elem()
+	}
+
+	scale, hasScale := decimalScale(field)
+	switch {
+	case val.Type() == reflect.TypeOf(big.Rat{}):
+		r := val.Interface().(big.Rat)
+		if !hasScale {
+			scale = ratScale(&r)
+		}
+
+		return r.FloatString(scale), true
+	case hasScale && (val.Kind() == reflect.Float32 || val.Kind() == reflect.Float64):
+		return strconv.FormatFloat(val.Float(), 'f', scale, val.Type().Bits()), true
+	default:
+		return "", false
+	}
+}
+
+// decimalScale returns the scale of the decimal tag of the field, e.g. 2 for `gofacto:"decimal:10,2"`
+func decimalScale(field reflect.StructField) (int, bool) {
+	for _, part := range strings.Split(field.Tag.Get("gofacto"), ";") {
+		opt, ok := strings.CutPrefix(part, "decimal:")
+		if !ok {
+			continue
+		}
+
+		_, scale, ok := strings.Cut(opt, ",")
+		if !ok {
+			return 0, false
+		}
+
+		n, err := strconv.Atoi(scale)
+		return n, err == nil
+	}
+
+	return 0, false
+}
+
+// ratScale returns the number of the digits after the decimal point to represent the rational number exactly,
+// which is the larger count of the factors 2 and 5 of the denominator
+func ratScale(r *big.Rat) int {
+	d := new(big.Int).Set(r.Denom())
+	m := new(big.Int)
+
+	var twos, fives int
+	for two := big.NewInt(2); m.Mod(d, two).Sign() == 0; twos++ {
+		d.Quo(d, two)
+	}
+	for five := big.NewInt(5); m.Mod(d, five).Sign() == 0; fives++ {
+		d.Quo(d, five)
+	}
+
+	if d.Cmp(big.NewInt(1)) != 0 {
+		return maxRatScale
+	}
+
+	return max(twos, fives)
+}
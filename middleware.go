@@ -0,0 +1,67 @@
+package gofacto
+
+import (
+	"context"
+)
+
+// StepKind is the kind of the step in the build-and-insert pipeline
+type StepKind int
+
+const (
+	// StepBuild is the step generating the values with the blueprint and non-zero values
+	StepBuild StepKind = iota
+
+	// StepInsert is the step inserting the values into the database
+	StepInsert
+)
+
+// Step is the information of the step in the build-and-insert pipeline
+type Step struct {
+	// Kind is the kind of the step
+	Kind StepKind
+
+	// StorageName is the storage name of the factory
+	StorageName string
+
+	// Values is the list of pointers to the values being built or inserted
+	Values []interface{}
+}
+
+// BuildStep runs a step of the build-and-insert pipeline
+type BuildStep func(ctx context.Context, s Step) error
+
+// Middleware wraps the BuildStep to add cross-cutting concerns, e.g. logging, validation, or metrics.
+//
+// The values are generated or inserted when calling next,
+// so the middleware can process the values before or after the step.
+type Middleware func(next BuildStep) BuildStep
+
+// Use registers the middlewares wrapping the build-and-insert pipeline.
+//
+// The middlewares are called in the order of registration, the first one is the outermost.
+//
+// Example:
+//
+//	func logging(next gofacto.BuildStep) gofacto.BuildStep {
+//		return func(ctx context.Context, s gofacto.Step) error {
+//			err := next(ctx, s)
+//			log.Printf("step %d on %s: %d values, err: %v", s.Kind, s.StorageName, len(s.Values), err)
+//			return err
+//		}
+//	}
+//
+//	factory := gofacto.New(Order{}).Use(logging)
+func (f *Factory[T]) Use(mws ...Middleware) *Factory[T] {
+	f.middlewares = append(f.middlewares, mws...)
+	return f
+}
+
+// runStep runs the core step wrapped by the middlewares
+func (f *Factory[T]) runStep(ctx context.Context, kind StepKind, vals []interface{}, core BuildStep) error {
+	step := core
+	for i := len(f.middlewares) - 1; i >= 0; i-- {
+		step = f.middlewares[i](step)
+	}
+
+	return step(ctx, Step{Kind: kind, StorageName: f.storageName, Values: vals})
+}
@@ -0,0 +1,61 @@
+package gofacto
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/eyo-chen/gofacto/internal/utils"
+)
+
+var (
+	// tableNamesByType maps the registered struct types to their table names
+	tableNamesByType sync.Map
+
+	// tableNamesByStruct maps the names of the registered struct types to their table names.
+	// It's used by the foreignKey tag, which only knows the struct name
+	tableNamesByStruct sync.Map
+)
+
+// RegisterTableName registers the table name of the struct type globally,
+// so every factory of the type, and every foreignKey tag referring to it,
+// uses the name instead of the default one(snake case of the struct name + "s").
+//
+// It's useful for the irregular plurals, e.g. Person -> people,
+// and should be called before the factories are created, e.g. in TestMain.
+// WithStorageName and the table key of the tag still take precedence.
+//
+//	gofacto.RegisterTableName(reflect.TypeOf(Person{}), "people")
+func RegisterTableName(typ reflect.Type, name string) {
+	if typ == nil {
+		return
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	tableNamesByType.Store(typ, name)
+	tableNamesByStruct.Store(typ.Name(), name)
+}
+
+// tableNameOfType returns the table name of the struct type
+func tableNameOfType(typ reflect.Type) string {
+	if name, ok := tableNamesByType.Load(typ); ok {
+		return name.(string)
+	}
+
+	return defaultTableName(typ.Name())
+}
+
+// tableNameOfStruct returns the table name of the struct name
+func tableNameOfStruct(structName string) string {
+	if name, ok := tableNamesByStruct.Load(structName); ok {
+		return name.(string)
+	}
+
+	return defaultTableName(structName)
+}
+
+// defaultTableName returns the default table name of the struct name, e.g. UserProfile -> user_profiles
+func defaultTableName(structName string) string {
+	return utils.Pluralize(utils.CamelToSnake(structName))
+}
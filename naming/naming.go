@@ -0,0 +1,33 @@
+// Package naming exports the naming helpers used by gofacto.
+//
+// It helps the custom adapters and storage name strategies produce the names
+// consistent with the conventions of gofacto, e.g. the default table name and the column names.
+package naming
+
+import "github.com/eyo-chen/gofacto/internal/utils"
+
+// CamelToSnake converts a camel case string to a snake case string, e.g. UserProfile -> user_profile.
+// It's how the SQL adapters name the columns of the fields without the tag
+func CamelToSnake(s string) string {
+	return utils.CamelToSnake(s)
+}
+
+// SnakeToCamel converts a snake case string to a camel case string with the upper case first letter,
+// e.g. user_profile -> UserProfile.
+// The initialisms aren't restored, e.g. user_id -> UserId
+func SnakeToCamel(s string) string {
+	return utils.SnakeToCamel(s)
+}
+
+// Pluralize returns the plural form of the word, e.g. user -> users.
+// It only appends "s", so register the irregular plurals by gofacto.RegisterTableName
+func Pluralize(word string) string {
+	return utils.Pluralize(word)
+}
+
+// TableName returns the default table name of the struct name, e.g. UserProfile -> user_profiles.
+// It's the storage name of the factory without WithStorageName, and the table of the foreignKey tag without the table key,
+// unless the struct is registered by gofacto.RegisterTableName
+func TableName(structName string) string {
+	return Pluralize(CamelToSnake(structName))
+}
@@ -0,0 +1,67 @@
+package naming
+
+import "testing"
+
+func TestCamelToSnake(t *testing.T) {
+	tests := []struct {
+		desc string
+		s    string
+		want string
+	}{
+		{desc: "single word", s: "User", want: "user"},
+		{desc: "multiple words", s: "UserProfile", want: "user_profile"},
+		{desc: "initialism", s: "UserID", want: "user_id"},
+		{desc: "lower case first letter", s: "userProfile", want: "user_profile"},
+		{desc: "empty", s: "", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := CamelToSnake(test.s); got != test.want {
+				t.Fatalf("CamelToSnake should be %s, got %s", test.want, got)
+			}
+		})
+	}
+}
+
+func TestSnakeToCamel(t *testing.T) {
+	tests := []struct {
+		desc string
+		s    string
+		want string
+	}{
+		{desc: "single word", s: "user", want: "User"},
+		{desc: "multiple words", s: "user_profile", want: "UserProfile"},
+		{desc: "initialism", s: "user_id", want: "UserId"},
+		{desc: "repeated underscores", s: "user__profile_", want: "UserProfile"},
+		{desc: "empty", s: "", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := SnakeToCamel(test.s); got != test.want {
+				t.Fatalf("SnakeToCamel should be %s, got %s", test.want, got)
+			}
+		})
+	}
+}
+
+func TestTableName(t *testing.T) {
+	tests := []struct {
+		desc       string
+		structName string
+		want       string
+	}{
+		{desc: "single word", structName: "User", want: "users"},
+		{desc: "multiple words", structName: "UserProfile", want: "user_profiles"},
+		{desc: "irregular plural", structName: "Person", want: "persons"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := TableName(test.structName); got != test.want {
+				t.Fatalf("TableName should be %s, got %s", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package gofacto
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// fieldKind is the kind of how the field is filled with non-zero value
+type fieldKind int
+
+const (
+	// fieldKindCustom is the client-defined type, only filled by the db custom type
+	fieldKindCustom fieldKind = iota
+	fieldKindTime
+	fieldKindPtrTime
+	fieldKindTimeSlice
+	fieldKindDecimal
+	fieldKindStruct
+	fieldKindPtrStruct
+	fieldKindSlice
+	fieldKindPtrSlice
+	fieldKindBasic
+)
+
+// defaultIDFieldNames is the list of field names detected as the ID field, in priority order
+var defaultIDFieldNames = []string{"ID", "Id", "UUID", "Uuid", "GUID", "Guid"}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+
+	// fieldPlanCache caches the field plans for each struct type
+	fieldPlanCache sync.Map
+)
+
+// typePlan is the precomputed plan of how to fill a struct type
+type typePlan struct {
+	// idField is the name of the detected ID field, empty if not found
+	idField string
+
+	// fields is the plans of the exported fields
+	fields []fieldPlan
+}
+
+// fieldPlan is the precomputed plan of how to fill a struct field.
+// The plan only depends on the struct type, so it's computed once for each type,
+// and it avoids inspecting the type of every field on every build.
+type fieldPlan struct {
+	index int
+	name  string
+	typ   reflect.Type
+	kind  fieldKind
+
+	// timeOpt is the option of how to generate the time field, nil means the current time
+	timeOpt *timeOption
+
+	// decimalOpt is the option of how to generate the decimal field, nil means the default precision and scale
+	decimalOpt *decimalOption
+
+	// isForeignKey means the field is declared as the foreign key by the tag
+	isForeignKey bool
+}
+
+// getTypePlan returns the cached plan of the given struct type, the plan is computed on the first call for each type
+func getTypePlan(typ reflect.Type) typePlan {
+	if plan, ok := fieldPlanCache.Load(typ); ok {
+		return plan.(typePlan)
+	}
+
+	plan := newTypePlan(typ)
+	fieldPlanCache.Store(typ, plan)
+	return plan
+}
+
+// newTypePlan computes the plan of the given struct type.
+// Unexported fields are excluded
+func newTypePlan(typ reflect.Type) typePlan {
+	plan := typePlan{
+		idField: detectIDField(typ),
+		fields:  make([]fieldPlan, 0, typ.NumField()),
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		fp := fieldPlan{
+			index: i,
+			name:  field.Name,
+			typ:   field.Type,
+			kind:  genFieldKind(field.Type),
+		}
+
+		// the tag of the factory type is validated when creating the factory
+		if t, ok, err := parseTag(field); err == nil && ok {
+			fp.timeOpt = t.timeOpt
+			fp.decimalOpt = t.decimalOpt
+			fp.isForeignKey = t.isForeignKey()
+			if t.decimalOpt != nil {
+				fp.kind = fieldKindDecimal
+			}
+		}
+
+		plan.fields = append(plan.fields, fp)
+	}
+
+	return plan
+}
+
+// detectIDField returns the name of the first exported field named as one of the default ID field names
+func detectIDField(typ reflect.Type) string {
+	for _, name := range defaultIDFieldNames {
+		if field, ok := typ.FieldByName(name); ok && field.PkgPath == "" && len(field.Index) == 1 {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// fieldKindOf returns the field kind of the struct field,
+// the field with the decimal tag is the decimal kind regardless of its type, e.g. decimal.Decimal of shopspring
+func fieldKindOf(field reflect.StructField) fieldKind {
+	if t, ok, err := parseTag(field); err == nil && ok && t.decimalOpt != nil {
+		return fieldKindDecimal
+	}
+
+	return genFieldKind(field.Type)
+}
+
+// genFieldKind returns the field kind of the given type
+func genFieldKind(t reflect.Type) fieldKind {
+	isPtr := t.Kind() == reflect.Ptr
+
+	switch {
+	case t == timeType:
+		return fieldKindTime
+	case isPtr && t.Elem() == timeType:
+		return fieldKindPtrTime
+	case isTimeSliceType(t):
+		return fieldKindTimeSlice
+	case indirectType(t) == ratType:
+		return fieldKindDecimal
+	case t.Kind() == reflect.Struct:
+		return fieldKindStruct
+	case isPtr && t.Elem().Kind() == reflect.Struct:
+		return fieldKindPtrStruct
+	case t.Kind() == reflect.Slice:
+		return fieldKindSlice
+	case isPtr && t.Elem().Kind() == reflect.Slice:
+		return fieldKindPtrSlice
+	case t.PkgPath() != "", isPtr && t.Elem().PkgPath() != "":
+		// skip client-defined types and pointer to client-defined types
+		return fieldKindCustom
+	default:
+		return fieldKindBasic
+	}
+}
+
+// isTimeSliceType checks if the type is a slice of time.Time or *time.Time
+func isTimeSliceType(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && indirectType(t.Elem()) == timeType
+}
@@ -0,0 +1,189 @@
+package gofacto
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eyo-chen/gofacto/internal/utils"
+)
+
+// DefaultEnvPrefix is the prefix of the environment variables read by the provider created by NewEnvProvider with the empty prefix,
+// e.g. GOFACTO_DEFAULT_CURRENCY for the field Currency
+const DefaultEnvPrefix = "GOFACTO_DEFAULT_"
+
+// ValueProvider provides the default values of the fields by the field names,
+// e.g. the values consistent with the deployment under test
+type ValueProvider interface {
+	// Lookup returns the default value of the field, and false if there is no default value
+	Lookup(field string) (interface{}, bool)
+}
+
+// WithValueProvider sets the providers of the default values of the fields.
+// The zero fields left by the blueprint are set to the provided values before setting the non-zero values.
+// The providers are looked up in order, and the first provided value is used.
+//
+// The string values are parsed to the types of the fields, e.g. "10" to int and RFC3339 to time.Time,
+// and other values are converted to the types of the fields
+func (f *Factory[T]) WithValueProvider(providers ...ValueProvider) *Factory[T] {
+	f.providers = providers
+	return f
+}
+
+// applyProvidedValues sets the provided values to the zero fields of the value
+func (f *Factory[T]) applyProvidedValues(v *T) error {
+	if len(f.providers) == 0 {
+		return nil
+	}
+
+	val := reflect.ValueOf(v).Elem()
+	for _, p := range getTypePlan(f.dataType).fields {
+		fieldVal := val.Field(p.index)
+		if slices.Contains(f.ignoreFields, p.name) || p.name == f.idField || !fieldVal.IsZero() {
+			continue
+		}
+
+		for _, provider := range f.providers {
+			pv, ok := provider.Lookup(p.name)
+			if !ok {
+				continue
+			}
+
+			if err := setProvidedValue(fieldVal, pv); err != nil {
+				return fmt.Errorf("provided value of %s: %w", p.name, err)
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// setProvidedValue sets the provided value to the field, the pointer field is set to the pointer to the value
+func setProvidedValue(field reflect.Value, v interface{}) error {
+	target := reflect.New(indirectType(field.Type())).Elem()
+
+	if s, ok := v.(string); ok && target.Kind() != reflect.String {
+		parsed, err := parseString(s, target.Type())
+		if err != nil {
+			return err
+		}
+		target.Set(parsed)
+	} else {
+		src := reflect.ValueOf(v)
+		// the integer is convertible to string as a rune, which is never intended
+		isToString := target.Kind() == reflect.String && src.Kind() != reflect.String
+		if !src.IsValid() || isToString || !src.Type().ConvertibleTo(target.Type()) {
+			return fmt.Errorf("%w: %v and %T", errTypeDiff, target.Type(), v)
+		}
+		target.Set(src.Convert(target.Type()))
+	}
+
+	if field.Kind() == reflect.Ptr {
+		field.Set(target.Addr())
+		return nil
+	}
+
+	field.Set(target)
+	return nil
+}
+
+// parseString parses the string to the value of the type
+func parseString(s string, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+
+	if t == timeType {
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return v, err
+		}
+
+		v.Set(reflect.ValueOf(tm))
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetFloat(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return v, err
+		}
+		v.SetBool(b)
+	default:
+		return v, fmt.Errorf("%w: %v and string", errTypeDiff, t)
+	}
+
+	return v, nil
+}
+
+// EnvProvider provides the default values from the environment variables.
+// The variable of the field is the prefix followed by the upper snake case of the field name,
+// e.g. GOFACTO_DEFAULT_CURRENCY for the field Currency
+type EnvProvider struct {
+	prefix string
+}
+
+// NewEnvProvider creates the provider reading the environment variables with the prefix,
+// the empty prefix means DefaultEnvPrefix
+func NewEnvProvider(prefix string) *EnvProvider {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+
+	return &EnvProvider{prefix: prefix}
+}
+
+// Lookup returns the value of the environment variable of the field
+func (p *EnvProvider) Lookup(field string) (interface{}, bool) {
+	return os.LookupEnv(p.prefix + strings.ToUpper(utils.CamelToSnake(field)))
+}
+
+// FileProvider provides the default values from the JSON config file,
+// the file is an object from the field names to the values, e.g. {"Currency": "USD", "Quantity": 1}
+type FileProvider struct {
+	vals map[string]interface{}
+}
+
+// NewFileProvider creates the provider reading the JSON config file of the path
+func NewFileProvider(path string) (*FileProvider, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vals := map[string]interface{}{}
+	if err := json.Unmarshal(b, &vals); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &FileProvider{vals: vals}, nil
+}
+
+// Lookup returns the value of the field in the config file
+func (p *FileProvider) Lookup(field string) (interface{}, bool) {
+	v, ok := p.vals[field]
+	return v, ok
+}
@@ -0,0 +1,112 @@
+package gofacto
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"reflect"
+)
+
+// cryptoRandLen is the number of random bytes of the values generated by crypto/rand
+const cryptoRandLen = 16
+
+// WithRand sets the random source of the generated values, e.g. the times of the time tag with past or future.
+// The values are reproducible with the seeded source, and the global source of math/rand is used by default.
+// The source is not safe for concurrent use, so it shouldn't be shared between factories used concurrently
+//
+// Example:
+//
+//	factory := gofacto.New(Order{}).WithRand(rand.New(rand.NewSource(42)))
+func (f *Factory[T]) WithRand(r *rand.Rand) *Factory[T] {
+	f.rand = r
+	return f
+}
+
+// WithCryptoRand sets the fields generated by crypto/rand, e.g. the tokens and the secrets,
+// which must not be predictable in the security tests.
+// The string fields are set to 32 hex characters, and the []byte fields are set to 16 random bytes.
+// The fields set by the blueprint are kept
+//
+// Example:
+//
+//	factory := gofacto.New(Session{}).WithCryptoRand("Token", "Secret")
+func (f *Factory[T]) WithCryptoRand(fields ...string) *Factory[T] {
+	if f.configErr != nil {
+		return f
+	}
+
+	for _, name := range fields {
+		field, ok := f.dataType.FieldByName(name)
+		if !ok {
+			f.configErr = fmt.Errorf("%w: %s", errFieldNotFound, name)
+			return f
+		}
+
+		if !isCryptoRandType(field.Type) {
+			f.configErr = fmt.Errorf("%w: %s of %v", errCryptoRandType, name, field.Type)
+			return f
+		}
+	}
+
+	f.cryptoFields = fields
+	return f
+}
+
+// int63n returns the random number in [0, n) from the random source of the factory
+func (f *Factory[T]) int63n(n int64) int64 {
+	if f.rand != nil {
+		return f.rand.Int63n(n)
+	}
+
+	return rand.Int63n(n)
+}
+
+// applyCryptoValues sets the zero fields generated by crypto/rand of the value
+func (f *Factory[T]) applyCryptoValues(v *T) error {
+	val := reflect.ValueOf(v).Elem()
+	for _, name := range f.cryptoFields {
+		fieldVal := val.FieldByName(name)
+		if !fieldVal.IsZero() {
+			continue
+		}
+
+		if err := setCryptoValue(fieldVal); err != nil {
+			return fmt.Errorf("crypto value of %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// isCryptoRandType checks if the type can be generated by crypto/rand, which are string, *string, and []byte
+func isCryptoRandType(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		return t.Elem().Kind() == reflect.Uint8
+	}
+
+	return indirectType(t).Kind() == reflect.String
+}
+
+// setCryptoValue sets the field to the random value generated by crypto/rand
+func setCryptoValue(field reflect.Value) error {
+	b := make([]byte, cryptoRandLen)
+	if _, err := crand.Read(b); err != nil {
+		return err
+	}
+
+	if field.Kind() == reflect.Slice {
+		field.SetBytes(b)
+		return nil
+	}
+
+	s := reflect.New(indirectType(field.Type())).Elem()
+	s.SetString(hex.EncodeToString(b))
+	if field.Kind() == reflect.Ptr {
+		field.Set(s.Addr())
+		return nil
+	}
+
+	field.Set(s)
+	return nil
+}
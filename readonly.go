@@ -0,0 +1,98 @@
+package gofacto
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// dbIdentifier is the database which is able to identify itself, e.g. the database name and the host
+type dbIdentifier interface {
+	// Identify returns the names identifying the database,
+	// the first one is the database name, followed by the others, e.g. the host
+	Identify(context.Context) ([]string, error)
+}
+
+// WithReadOnly sets whether the factory is read-only.
+// Inserting with the read-only factory returns an error, while Get still works.
+// It protects against accidentally inserting into a shared or production-like database in local runs
+func (f *Factory[T]) WithReadOnly(readOnly bool) *Factory[T] {
+	f.readOnly = readOnly
+	return f
+}
+
+// WithAllowedDBs sets the allow-list of the database names which the values can be inserted into.
+// Before inserting, the database is identified, and an error is returned if its database name is not allowed.
+// The database is identified once for each database connection, not on every insert.
+//
+// The names are case-insensitive, and the database must be able to identify itself,
+// which is supported by mysqlf, postgresf, gormf, mongof, and mockf
+//
+// Example:
+//
+//	factory := gofacto.New(Order{}).
+//		WithDB(mysqlf.NewConfig(db)).
+//		WithAllowedDBs("test_db")
+func (f *Factory[T]) WithAllowedDBs(names ...string) *Factory[T] {
+	f.allowedDBs = names
+	return f
+}
+
+// checkWritable checks if the values can be inserted into the database in use
+func (f *Factory[T]) checkWritable(ctx context.Context) error {
+	if f.readOnly {
+		return errReadOnly
+	}
+
+	if len(f.allowedDBs) == 0 {
+		return nil
+	}
+
+	name, err := f.identifyDB(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !slices.ContainsFunc(f.allowedDBs, func(allowed string) bool { return strings.EqualFold(allowed, name) }) {
+		return fmt.Errorf("%w: %s", errDBNotAllowed, name)
+	}
+
+	return nil
+}
+
+// identifyDB returns the database name of the database in use.
+// The name is cached for each database connection, so the database isn't queried on every insert
+func (f *Factory[T]) identifyDB(ctx context.Context) (string, error) {
+	// the database which can't be the map key, e.g. the struct with slices, is identified every time
+	isCacheable := reflect.TypeOf(f.db).Comparable()
+	if isCacheable {
+		if name, ok := f.identifiedDBs[f.db]; ok {
+			return name, nil
+		}
+	}
+
+	identifier, ok := f.db.(dbIdentifier)
+	if !ok {
+		return "", fmt.Errorf("%w: database can't be identified", errDBNotAllowed)
+	}
+
+	names, err := identifier.Identify(ctx)
+	if err != nil {
+		return "", fmt.Errorf("identify database: %w", err)
+	}
+
+	if len(names) == 0 || names[0] == "" {
+		return "", fmt.Errorf("%w: database name is unknown", errDBNotAllowed)
+	}
+
+	if isCacheable {
+		if f.identifiedDBs == nil {
+			f.identifiedDBs = map[database]string{}
+		}
+		f.identifiedDBs[f.db] = names[0]
+	}
+
+	return names[0], nil
+}
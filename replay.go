@@ -0,0 +1,289 @@
+package gofacto
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+
+	"github.com/eyo-chen/gofacto/internal/db"
+)
+
+// Recorder wraps the database to record the inserted values in order,
+// so the dataset can be saved to a file and replayed against another database by Replay.
+//
+// Example:
+//
+//	rec := gofacto.NewRecorder(mysqlf.NewConfig(db))
+//	userFactory := gofacto.New(User{}).WithDB(rec)
+//	// insert the dataset with the factories
+//	err := rec.SaveFile("testdata/dataset.jsonl")
+type Recorder struct {
+	db database
+
+	mu      sync.Mutex
+	records []record
+}
+
+// record is a single inserted value, saved as one line of JSON
+type record struct {
+	StorageName string          `json:"storageName"`
+	IDField     string          `json:"idField"`
+	Type        string          `json:"type"`
+	Value       json.RawMessage `json:"value"`
+}
+
+// NewRecorder creates a recorder wrapping the database
+func NewRecorder(d database) *Recorder {
+	return &Recorder{db: d}
+}
+
+func (r *Recorder) Insert(ctx context.Context, params db.InsertParams) (interface{}, error) {
+	v, err := r.db.Insert(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.record(params.StorageName, params.IDField, v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+func (r *Recorder) InsertList(ctx context.Context, params db.InsertListParams) ([]interface{}, error) {
+	vals, err := r.db.InsertList(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.record(params.StorageName, params.IDField, vals...); err != nil {
+		return nil, err
+	}
+
+	return vals, nil
+}
+
+func (r *Recorder) Update(ctx context.Context, params db.UpdateParams) error {
+	upd, ok := r.db.(updater)
+	if !ok {
+		return errUpdateNotSupported
+	}
+
+	return upd.Update(ctx, params)
+}
+
+func (r *Recorder) DeleteList(ctx context.Context, params db.DeleteListParams) error {
+	del, ok := r.db.(deleter)
+	if !ok {
+		return errDeleteNotSupported
+	}
+
+	return del.DeleteList(ctx, params)
+}
+
+func (r *Recorder) GenCustomType(t reflect.Type) (interface{}, bool) {
+	generator, ok := r.db.(customTypeGenerator)
+	if !ok {
+		return nil, false
+	}
+
+	return generator.GenCustomType(t)
+}
+
+func (r *Recorder) ConvertValue(field reflect.StructField, v interface{}) (interface{}, bool) {
+	converter, ok := r.db.(valueConverter)
+	if !ok {
+		return nil, false
+	}
+
+	return converter.ConvertValue(field, v)
+}
+
+// Save writes the recorded values to w as JSON lines, one value per line in insertion order
+func (r *Recorder) Save(w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enc := json.NewEncoder(w)
+	for _, rec := range r.records {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SaveFile writes the recorded values to the file, see Save
+func (r *Recorder) SaveFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := r.Save(file); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// record snapshots the inserted values.
+// The values are encoded immediately, so the later mutations are not recorded
+func (r *Recorder) record(storageName, idField string, vals ...interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, v := range vals {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		r.records = append(r.records, record{
+			StorageName: storageName,
+			IDField:     idField,
+			Type:        reflect.TypeOf(v).Elem().Name(),
+			Value:       b,
+		})
+	}
+
+	return nil
+}
+
+// Replay inserts the values saved by Recorder into the database in the recorded order.
+//
+// The types are the sample values of the recorded structs, e.g. User{}, which are used to decode the values.
+// The ID fields are populated by the database again,
+// and the foreign keys declared by the foreignKey tag are remapped to the new IDs.
+//
+// Example:
+//
+//	err := gofacto.ReplayFile(ctx, postgresf.NewConfig(db), "testdata/dataset.jsonl", User{}, Order{})
+func Replay(ctx context.Context, d database, r io.Reader, types ...interface{}) error {
+	typeMap := map[string]reflect.Type{}
+	for _, t := range types {
+		typ := reflect.TypeOf(t)
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct {
+			return fmt.Errorf("%v: %w", typ, errInvalidType)
+		}
+
+		typeMap[typ.Name()] = typ
+	}
+
+	// newVals is a map from the struct name to the map from the recorded ID to the newly inserted value
+	newVals := map[string]map[string]interface{}{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		typ, ok := typeMap[rec.Type]
+		if !ok {
+			return fmt.Errorf("line %d: %s: %w", line, rec.Type, errInvalidType)
+		}
+
+		v := reflect.New(typ)
+		if err := json.Unmarshal(rec.Value, v.Interface()); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if err := remapForeignKeys(v.Interface(), newVals); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		// the ID is populated by the database again
+		var oldID string
+		if idField := v.Elem().FieldByName(rec.IDField); rec.IDField != "" && idField.IsValid() {
+			oldID = fmt.Sprint(reflect.Indirect(idField).Interface())
+			idField.Set(reflect.Zero(idField.Type()))
+		}
+
+		res, err := d.Insert(ctx, db.InsertParams{StorageName: rec.StorageName, IDField: rec.IDField, Value: v.Interface()})
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if oldID != "" {
+			if newVals[rec.Type] == nil {
+				newVals[rec.Type] = map[string]interface{}{}
+			}
+			newVals[rec.Type][oldID] = res
+		}
+	}
+
+	return scanner.Err()
+}
+
+// ReplayFile inserts the values saved in the file into the database, see Replay
+func ReplayFile(ctx context.Context, d database, path string, types ...interface{}) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return Replay(ctx, d, file, types...)
+}
+
+// remapForeignKeys sets the foreign keys of v to the IDs of the newly inserted values.
+// The foreign keys referencing the values not in the dataset, or the non-ID fields, are kept
+func remapForeignKeys(v interface{}, newVals map[string]map[string]interface{}) error {
+	val := reflect.ValueOf(v).Elem()
+
+	return processStructFields(val.Type(), func(t tag, hasTag bool) error {
+		if !hasTag || t.omit || !t.isForeignKey() {
+			return nil
+		}
+
+		fkField := val.FieldByName(t.fieldName)
+		if t.isMany {
+			for i := 0; i < fkField.Len(); i++ {
+				if err := remapForeignKey(fkField.Index(i), t, newVals); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		return remapForeignKey(fkField, t, newVals)
+	})
+}
+
+// remapForeignKey sets the foreign key to the new ID of the value referenced by the old ID.
+// The foreign key referencing the field other than the ID field is kept
+func remapForeignKey(fkField reflect.Value, t tag, newVals map[string]map[string]interface{}) error {
+	fkField = reflect.Indirect(fkField)
+	if !fkField.IsValid() {
+		return nil
+	}
+
+	newVal, ok := newVals[t.structName][fmt.Sprint(fkField.Interface())]
+	if !ok {
+		return nil
+	}
+
+	idField := getTypePlan(reflect.TypeOf(newVal).Elem()).idField
+	if t.fkName != "" && t.fkName != idField {
+		return nil
+	}
+
+	return setKey(fkField, t.fieldName, newVal, idField)
+}
@@ -0,0 +1,89 @@
+package gofacto
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/eyo-chen/gofacto/internal/db"
+)
+
+// schemaChecker is the database which is able to check the schema of the storage
+type schemaChecker interface {
+	// CheckSchema checks if the storage exists, and the fields of the value map to its columns
+	CheckSchema(ctx context.Context, params db.CheckSchemaParams) error
+}
+
+// schemaKey is the key of the schema checked by WithSchemaCheck
+type schemaKey struct {
+	db          database
+	storageName string
+	dataType    reflect.Type
+	omitFields  string
+}
+
+// checkedSchemas caches the schemas which passed the check,
+// so the schema is checked once per database, storage, and type across the factories
+var checkedSchemas sync.Map
+
+// WithSchemaCheck checks if the storage exists, and the fields map to its columns in the database set by WithDB.
+// It fails at the setup time instead of on the first Insert deep inside a test,
+// and the error is returned by Build and BuildList.
+// The check is skipped if no database is set, and the passed checks are cached.
+// The columns of the fields omitted by WithColumns aren't required.
+// It's supported by mysqlf, postgresf, and gormf, and it should be called after WithDB, WithStorageName, and WithColumns
+//
+// Example:
+//
+//	factory := gofacto.New(Order{}).
+//		WithDB(mysqlf.NewConfig(db)).
+//		WithStorageName("orders").
+//		WithSchemaCheck(ctx)
+func (f *Factory[T]) WithSchemaCheck(ctx context.Context) *Factory[T] {
+	if f.configErr != nil || f.db == nil {
+		return f
+	}
+
+	if err := f.checkSchema(ctx); err != nil {
+		f.configErr = err
+	}
+
+	return f
+}
+
+// checkSchema checks the schema of the storage of the factory, the passed check is cached
+func (f *Factory[T]) checkSchema(ctx context.Context) error {
+	checker, ok := f.db.(schemaChecker)
+	if !ok {
+		return errSchemaCheckNotSupported
+	}
+
+	// the database which isn't comparable can't be the key of the cache
+	isCacheable := reflect.TypeOf(f.db).Comparable()
+	omitFields := f.factoryOmitFields([]interface{}{&f.empty})
+	key := schemaKey{storageName: f.storageName, dataType: f.dataType, omitFields: strings.Join(omitFields, ",")}
+	if isCacheable {
+		key.db = f.db
+		if _, ok := checkedSchemas.Load(key); ok {
+			return nil
+		}
+	}
+
+	err := checker.CheckSchema(ctx, db.CheckSchemaParams{
+		StorageName: f.storageName,
+		IDField:     f.idField,
+		Value:       &f.empty,
+		OmitFields:  omitFields,
+	})
+	if err != nil {
+		return fmt.Errorf("check schema of %s: %w", f.storageName, err)
+	}
+
+	if isCacheable {
+		checkedSchemas.Store(key, struct{}{})
+	}
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package gofacto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// sequenceBlockSize is the number of indexes reserved from the sequence at a time
+	sequenceBlockSize = 100
+
+	// fileLockRetryInterval is the interval of retrying to acquire the lock of the file sequence
+	fileLockRetryInterval = 10 * time.Millisecond
+
+	// fileLockStaleAfter is the duration after which the lock of the file sequence is considered stale,
+	// e.g. the process holding the lock crashed
+	fileLockStaleAfter = 10 * time.Second
+)
+
+// Sequence reserves the ranges of the index shared across processes,
+// e.g. the test packages running in parallel against one shared database
+type Sequence interface {
+	// Reserve reserves n consecutive indexes, and returns the first one
+	Reserve(ctx context.Context, n int) (int, error)
+}
+
+// WithSequence sets the sequence which the indexes are reserved from,
+// so the generated values, e.g. emails and usernames, are unique across the factories and processes sharing the sequence.
+//
+// The indexes are reserved in blocks, so they are unique but not consecutive across the blocks
+func (f *Factory[T]) WithSequence(seq Sequence) *Factory[T] {
+	f.seq = seq
+	f.seqEnd = 0
+	return f
+}
+
+// WithIndexOffset sets the offset of the index, so the index starts at n+1 instead of 1,
+// and the factories or the parallel workers using the different offsets generate the values in disjoint ranges,
+// e.g. worker 1 uses 1_000_000 and worker 2 uses 2_000_000.
+// It applies to the sequential IDs set by WithIsSetSeqID, Reset, and WithIsLocalIndex as well,
+// and the indexes reserved from the sequence are shifted by the offset
+//
+// Example:
+//
+//	factory := gofacto.New(User{}).WithIndexOffset(workerID * 1_000_000)
+func (f *Factory[T]) WithIndexOffset(n int) *Factory[T] {
+	if n < 0 {
+		f.configErr = fmt.Errorf("%w: %d", errInvalidIndexOffset, n)
+		return f
+	}
+
+	f.indexOffset = n
+	f.index, f.seqID = f.firstIndex(), f.firstIndex()
+	return f
+}
+
+// firstIndex returns the first index of the factory, which is shifted by the index offset
+func (f *Factory[T]) firstIndex() int {
+	return f.indexOffset + 1
+}
+
+// reserveIndex makes sure the current index is reserved from the sequence.
+// It reserves a new block when the current block is used up
+func (f *Factory[T]) reserveIndex(ctx context.Context) error {
+	if f.seq == nil || (f.index >= f.seqStart && f.index < f.seqEnd) {
+		return nil
+	}
+
+	start, err := f.seq.Reserve(ctx, sequenceBlockSize)
+	if err != nil {
+		return fmt.Errorf("reserve index: %w", err)
+	}
+
+	start += f.indexOffset
+	f.index, f.seqStart, f.seqEnd = start, start, start+sequenceBlockSize
+	return nil
+}
+
+// FileSequence is the sequence stored in a file, the processes sharing the file reserve the indexes exclusively.
+// The file is locked by creating the lock file next to it
+type FileSequence struct {
+	path string
+}
+
+// NewFileSequence creates the sequence stored in the file of the path, the file is created if it doesn't exist
+func NewFileSequence(path string) *FileSequence {
+	return &FileSequence{path: path}
+}
+
+// Reserve reserves n consecutive indexes from the file, and returns the first one.
+// It waits until the lock is acquired or the context is done
+func (s *FileSequence) Reserve(ctx context.Context, n int) (int, error) {
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	start := 1
+	b, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return 0, err
+	default:
+		if start, err = strconv.Atoi(strings.TrimSpace(string(b))); err != nil {
+			return 0, fmt.Errorf("%s: %w", s.path, errNotInt)
+		}
+	}
+
+	if err := os.WriteFile(s.path, []byte(strconv.Itoa(start+n)), 0o644); err != nil {
+		return 0, err
+	}
+
+	return start, nil
+}
+
+// lock acquires the lock of the file, and returns the function releasing it
+func (s *FileSequence) lock(ctx context.Context) (func(), error) {
+	lockPath := s.path + ".lock"
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > fileLockStaleAfter {
+			os.Remove(lockPath)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(fileLockRetryInterval):
+		}
+	}
+}
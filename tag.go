@@ -1,18 +1,32 @@
 package gofacto
 
 import (
+	"fmt"
 	"reflect"
+	"slices"
 	"strings"
-
-	"github.com/eyo-chen/gofacto/internal/utils"
+	"time"
 )
 
 const (
-	defaultFkName  = "ID"
 	tagKeyStruct   = "struct"
 	tagKeyTable    = "table"
 	tagKeyField    = "field"
 	tagKeyRefField = "refField"
+
+	tagKeyTZ     = "tz"
+	tagKeyWindow = "window"
+	tagOptPast   = "past"
+	tagOptFuture = "future"
+
+	// defaultTimeWindow is the window of the past or future time when it's not specified
+	defaultTimeWindow = 30 * 24 * time.Hour
+
+	// timeSliceLen is the number of the times generated for the slice of times
+	timeSliceLen = 3
+
+	// maxTimeAttempts is the maximum number of drawing the time until it's distinct from the previous ones
+	maxTimeAttempts = 10
 )
 
 // tag represents the metadata parsed from the custom tag
@@ -20,9 +34,76 @@ type tag struct {
 	fieldName    string
 	structName   string
 	tableName    string
-	fkName       string
+	fkName       string // empty means the ID field of the referenced struct
 	foreignField string
 	omit         bool
+	timeOpt      *timeOption
+	decimalOpt   *decimalOption
+
+	// isMany means the field is a slice of foreign keys, e.g. `gofacto:"foreignKeys,struct:Tag"`
+	isMany bool
+}
+
+// isForeignKey reports whether the tag declares a foreign key
+func (t tag) isForeignKey() bool {
+	return t.structName != ""
+}
+
+// timeOption is the option of how to generate the time field, e.g. `gofacto:"tz:UTC,past,window:24h"`
+type timeOption struct {
+	// loc is the time zone of the generated time, nil means the local time zone
+	loc *time.Location
+
+	// past and future generate the time within the window before or after now
+	past   bool
+	future bool
+	window time.Duration
+}
+
+// genTime generates the time based on the option relative to now, the offset is drawn by int63n
+func (o *timeOption) genTime(now time.Time, int63n func(n int64) int64) time.Time {
+	if o == nil {
+		return now
+	}
+
+	if o.loc != nil {
+		now = now.In(o.loc)
+	}
+
+	if !o.past && !o.future {
+		return now
+	}
+
+	offset := time.Duration(int63n(int64(o.window))) + 1
+	if o.past {
+		return now.Add(-offset)
+	}
+
+	return now.Add(offset)
+}
+
+// genTimes generates n distinct times based on the option in ascending order.
+// The times within the window are drawn until they are distinct,
+// and the current times are spaced by a second, so they are distinct in the precision of the databases
+func (o *timeOption) genTimes(n int, now time.Time, int63n func(n int64) int64) []time.Time {
+	isRandom := o != nil && (o.past || o.future)
+
+	times := make([]time.Time, n)
+	for i := range times {
+		t := o.genTime(now, int63n)
+		if !isRandom {
+			t = t.Add(time.Duration(i) * time.Second)
+		}
+
+		for attempt := 1; isRandom && attempt < maxTimeAttempts && slices.ContainsFunc(times[:i], t.Equal); attempt++ {
+			t = o.genTime(now, int63n)
+		}
+
+		times[i] = t
+	}
+
+	slices.SortFunc(times, time.Time.Compare)
+	return times
 }
 
 // extractTag extracts the tag metadata from the struct type
@@ -87,8 +168,32 @@ func parseTag(field reflect.StructField) (tag, bool, error) {
 		}
 
 		subParts := strings.Split(part, ",")
-		if subParts[0] != "foreignKey" {
-			return tag{}, false, errTagFormat
+		if strings.HasPrefix(subParts[0], tagKeyDecimal+":") {
+			opt, err := parseDecimalOption(field, subParts)
+			if err != nil {
+				return tag{}, false, err
+			}
+
+			t.decimalOpt = opt
+			continue
+		}
+
+		if subParts[0] != "foreignKey" && subParts[0] != "foreignKeys" {
+			opt, err := parseTimeOption(field, subParts)
+			if err != nil {
+				return tag{}, false, err
+			}
+
+			t.timeOpt = opt
+			continue
+		}
+
+		if subParts[0] == "foreignKeys" {
+			if field.Type.Kind() != reflect.Slice {
+				return tag{}, false, fmt.Errorf("%s: %w: foreignKeys must be a slice", field.Name, errTagFormat)
+			}
+
+			t.isMany = true
 		}
 
 		for _, subPart := range subParts[1:] {
@@ -109,12 +214,47 @@ func parseTag(field reflect.StructField) (tag, bool, error) {
 	}
 
 	if t.tableName == "" {
-		t.tableName = utils.CamelToSnake(t.structName) + "s"
+		t.tableName = tableNameOfStruct(t.structName)
 	}
 
-	if t.fkName == "" {
-		t.fkName = defaultFkName
+	return t, true, nil
+}
+
+// parseTimeOption parses the time option of the tag, e.g. `gofacto:"tz:UTC,past,window:24h"`.
+// The field must be a time.Time, *time.Time, or the slice of them
+func parseTimeOption(field reflect.StructField, subParts []string) (*timeOption, error) {
+	if indirectType(field.Type) != timeType && !isTimeSliceType(field.Type) {
+		return nil, fmt.Errorf("%s: %w", field.Name, errTagFormat)
 	}
 
-	return t, true, nil
+	opt := &timeOption{window: defaultTimeWindow}
+	for _, subPart := range subParts {
+		kv := strings.SplitN(subPart, ":", 2)
+		switch {
+		case kv[0] == tagOptPast && len(kv) == 1:
+			opt.past = true
+		case kv[0] == tagOptFuture && len(kv) == 1:
+			opt.future = true
+		case kv[0] == tagKeyTZ && len(kv) == 2:
+			loc, err := time.LoadLocation(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w: %v", field.Name, errTagFormat, err)
+			}
+			opt.loc = loc
+		case kv[0] == tagKeyWindow && len(kv) == 2:
+			d, err := time.ParseDuration(kv[1])
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("%s: %w: invalid window %s", field.Name, errTagFormat, kv[1])
+			}
+			opt.window = d
+		default:
+			return nil, fmt.Errorf("%s: %w", field.Name, errTagFormat)
+		}
+	}
+
+	if opt.past && opt.future {
+		return nil, fmt.Errorf("%s: %w: past and future are exclusive", field.Name, errTagFormat)
+	}
+
+	return opt, nil
 }
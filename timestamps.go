@@ -0,0 +1,122 @@
+package gofacto
+
+import (
+	"reflect"
+	"slices"
+	"time"
+)
+
+// TimestampMode is how the timestamp fields, CreatedAt and UpdatedAt, are set
+type TimestampMode int
+
+const (
+	// TimestampGenerated generates the timestamp fields like the other time fields, it's the default mode
+	TimestampGenerated TimestampMode = iota
+
+	// TimestampNow sets CreatedAt and UpdatedAt to the same current time of the clock
+	TimestampNow
+
+	// TimestampDBDefault leaves the timestamp fields zero, and omits them when inserting,
+	// so the database sets them by the column defaults
+	TimestampDBDefault
+)
+
+// timestampFields is the list of the field names recognized as the timestamp fields
+var timestampFields = []string{"CreatedAt", "UpdatedAt"}
+
+// WithTimestamps sets how the timestamp fields, CreatedAt and UpdatedAt of time.Time or *time.Time, are set.
+// It applies to the values of the factory and the associations.
+// The timestamp fields set by the blueprint or the overwrites are kept
+//
+// Example:
+//
+//	factory := gofacto.New(User{}).WithTimestamps(gofacto.TimestampNow)
+func (f *Factory[T]) WithTimestamps(mode TimestampMode) *Factory[T] {
+	f.timestampMode = mode
+	return f
+}
+
+// WithClock sets the clock of the current time, which is used by TimestampNow and the time tag,
+// so the generated times can be asserted against the fixed time
+//
+// Example:
+//
+//	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+//	factory := gofacto.New(User{}).WithClock(func() time.Time { return now })
+func (f *Factory[T]) WithClock(now func() time.Time) *Factory[T] {
+	f.clock = now
+	return f
+}
+
+// now returns the current time of the clock
+func (f *Factory[T]) now() time.Time {
+	if f.clock == nil {
+		return time.Now()
+	}
+
+	return f.clock()
+}
+
+// setTimestamps sets the zero timestamp fields of the value(v) to the current time in TimestampNow mode.
+// Parameter v must be a pointer to a struct
+func (f *Factory[T]) setTimestamps(v interface{}) {
+	if f.timestampMode != TimestampNow {
+		return
+	}
+
+	now := f.now()
+	val := reflect.ValueOf(v).Elem()
+	for _, name := range timestampFields {
+		field := val.FieldByName(name)
+		if !field.IsValid() || !field.CanSet() || !field.IsZero() {
+			continue
+		}
+
+		switch field.Type() {
+		case timeType:
+			field.Set(reflect.ValueOf(now))
+		case reflect.PointerTo(timeType):
+			t := now
+			field.Set(reflect.ValueOf(&t))
+		}
+	}
+}
+
+// fillIgnoreFields returns the fields not filled with the non-zero values,
+// which are the ignored fields and the timestamp fields in TimestampDBDefault mode
+func (f *Factory[T]) fillIgnoreFields(ignoreFields []string) []string {
+	if f.timestampMode != TimestampDBDefault {
+		return ignoreFields
+	}
+
+	return append(slices.Clip(ignoreFields), timestampFields...)
+}
+
+// omitFields returns the timestamp fields left zero in all the values, which are omitted when inserting in TimestampDBDefault mode.
+// Parameter vals must be the pointers to the structs of the same type
+func (f *Factory[T]) omitFields(vals []interface{}) []string {
+	if f.timestampMode != TimestampDBDefault || len(vals) == 0 {
+		return nil
+	}
+
+	var fields []string
+	for _, name := range timestampFields {
+		if _, ok := reflect.TypeOf(vals[0]).Elem().FieldByName(name); !ok {
+			continue
+		}
+
+		isZero := true
+		for _, v := range vals {
+			if !reflect.ValueOf(v).Elem().FieldByName(name).IsZero() {
+				isZero = false
+				break
+			}
+		}
+
+		if isZero {
+			fields = append(fields, name)
+		}
+	}
+
+	return fields
+}
@@ -0,0 +1,109 @@
+package gofacto
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// TransformFunc transforms the value of the field into the value stored in the database, e.g. encrypting or hashing.
+// The returned value must be convertible to the type of the field
+type TransformFunc func(v interface{}) (interface{}, error)
+
+// insertTransform is the transform function of the field applied when inserting
+type insertTransform struct {
+	field string
+	fn    TransformFunc
+}
+
+// WithInsertTransform sets the transform function of the field(name) applied when inserting only,
+// so the stored value differs from the value kept in the returned struct,
+// e.g. seeding the systems where the application encrypts the PII fields before persistence.
+// The transform of the same field set before is replaced
+//
+// Example:
+//
+//	factory := gofacto.New(User{}).
+//		WithInsertTransform("SSN", func(v interface{}) (interface{}, error) {
+//			return encrypt(v.(string))
+//		})
+//
+//	user, err := factory.Build(ctx).Insert()
+//	// user.SSN is the plain value, and the encrypted value is stored
+func (f *Factory[T]) WithInsertTransform(name string, fn TransformFunc) *Factory[T] {
+	if f.configErr != nil {
+		return f
+	}
+
+	if _, ok := f.dataType.FieldByName(name); !ok {
+		f.configErr = fmt.Errorf("%w: %s", errFieldNotFound, name)
+		return f
+	}
+
+	f.insertTransforms = slices.DeleteFunc(slices.Clip(f.insertTransforms), func(t insertTransform) bool {
+		return t.field == name
+	})
+	f.insertTransforms = append(f.insertTransforms, insertTransform{field: name, fn: fn})
+	return f
+}
+
+// transformValues returns the copies of the values with the insert transforms applied, which are inserted into the database.
+// The values are returned as they are if there's no insert transform.
+// Parameter vals must be the pointers to T
+func (f *Factory[T]) transformValues(vals []interface{}) ([]interface{}, error) {
+	if len(f.insertTransforms) == 0 {
+		return vals, nil
+	}
+
+	stored := make([]interface{}, len(vals))
+	for i, v := range vals {
+		cp := reflect.New(f.dataType)
+		cp.Elem().Set(reflect.ValueOf(v).Elem())
+
+		for _, t := range f.insertTransforms {
+			out, err := t.fn(cp.Elem().FieldByName(t.field).Interface())
+			if err != nil {
+				return nil, fmt.Errorf("insert transform of %s: %w", t.field, err)
+			}
+
+			if out == nil {
+				fieldVal := cp.Elem().FieldByName(t.field)
+				fieldVal.Set(reflect.Zero(fieldVal.Type()))
+				continue
+			}
+
+			if err := setConvertedField(cp.Elem(), t.field, out); err != nil {
+				return nil, fmt.Errorf("insert transform of %s: %w", t.field, err)
+			}
+		}
+
+		stored[i] = cp.Interface()
+	}
+
+	return stored, nil
+}
+
+// untransformValues copies the fields of the inserted values back to the values(vals), except the transformed fields,
+// so the fields populated by the database, e.g. the ID, are set and the transformed fields keep the values before transforming.
+// It returns vals, or the inserted values as they are if there's no insert transform
+func (f *Factory[T]) untransformValues(vals, inserted []interface{}) []interface{} {
+	if len(f.insertTransforms) == 0 {
+		return inserted
+	}
+
+	for i, v := range vals {
+		dst := reflect.ValueOf(v).Elem()
+		src := reflect.ValueOf(inserted[i]).Elem()
+		for j := 0; j < dst.NumField(); j++ {
+			name := dst.Type().Field(j).Name
+			isTransformed := slices.ContainsFunc(f.insertTransforms, func(t insertTransform) bool {
+				return t.field == name
+			})
+			if !isTransformed && dst.Field(j).CanSet() {
+				dst.Field(j).Set(src.Field(j))
+			}
+		}
+	}
+
+	return vals
+}
@@ -0,0 +1,128 @@
+package gofacto
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eyo-chen/gofacto/internal/db"
+)
+
+// txBeginner is the database which is able to start a shared transaction
+type txBeginner interface {
+	// BeginTx starts a shared transaction.
+	// The returned transaction is also a database, all the operations of it are executed within the transaction
+	BeginTx(context.Context) (db.Tx, error)
+}
+
+// txBinder is the factory which is able to be bound to the shared transaction
+type txBinder interface {
+	// bindDB replaces the database of the factory, and returns the function restoring it
+	bindDB(d database) (unbind func(isRollback bool))
+}
+
+// TxScope is the scope of a shared transaction created by WithinTx
+type TxScope struct {
+	// db is the database bound to the shared transaction
+	db database
+
+	// unbinds is the list of functions restoring the database of the bound factories
+	unbinds []func(isRollback bool)
+}
+
+// WithinTx starts a transaction on the database, and calls fn with the scope of it.
+// All the factories bound to the scope insert within the transaction,
+// which is committed when fn returns nil, and is rolled back when fn returns an error or panics.
+//
+// The factories are unbound when WithinTx returns.
+// If the transaction is rolled back, the values inserted within it are no longer tracked by Cleanup.
+//
+// The database must support transactions, which are supported by mysqlf, postgresf, gormf, and mongof.
+//
+// Example:
+//
+//	err := gofacto.WithinTx(ctx, mysqlf.NewConfig(db), func(scope *gofacto.TxScope) error {
+//		scope.Bind(userFactory, postFactory)
+//
+//		user, err := userFactory.Build(ctx).Insert()
+//		if err != nil {
+//			return err
+//		}
+//
+//		_, err = postFactory.Build(ctx).WithOne(&user).Insert()
+//		return err
+//	})
+func WithinTx(ctx context.Context, d database, fn func(scope *TxScope) error) (err error) {
+	beginner, ok := d.(txBeginner)
+	if !ok {
+		return errTxNotSupported
+	}
+
+	tx, err := beginner.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+
+	txDB, ok := tx.(database)
+	if !ok {
+		_ = tx.Rollback(ctx)
+		return errTxNotSupported
+	}
+
+	scope := &TxScope{db: txDB}
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback(ctx)
+			scope.unbind(true)
+			panic(r)
+		}
+	}()
+
+	if err := fn(scope); err != nil {
+		scope.unbind(true)
+		if rollbackErr := tx.Rollback(ctx); rollbackErr != nil {
+			return fmt.Errorf("%w; rollback failed: %v", err, rollbackErr)
+		}
+
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		scope.unbind(true)
+		return err
+	}
+
+	scope.unbind(false)
+	return nil
+}
+
+// Bind binds the factories to the scope, so they insert within the shared transaction
+func (s *TxScope) Bind(fs ...txBinder) {
+	for _, f := range fs {
+		s.unbinds = append(s.unbinds, f.bindDB(s.db))
+	}
+}
+
+// unbind restores the database of the bound factories in reverse order,
+// so a factory bound more than once gets back its original database
+func (s *TxScope) unbind(isRollback bool) {
+	for i := len(s.unbinds) - 1; i >= 0; i-- {
+		s.unbinds[i](isRollback)
+	}
+
+	s.unbinds = nil
+}
+
+// bindDB replaces the database of the factory, and returns the function restoring it.
+// The values inserted while being bound are forgotten if the transaction is rolled back,
+// and the cache of the non-custom types is dropped while being bound
+func (f *Factory[T]) bindDB(d database) func(isRollback bool) {
+	prevDB, prevTypes, numInserted := f.db, f.nonCustomTypes, len(f.inserted)
+	f.db, f.nonCustomTypes = d, nil
+
+	return func(isRollback bool) {
+		f.db, f.nonCustomTypes = prevDB, prevTypes
+		if isRollback && len(f.inserted) > numInserted {
+			f.inserted = f.inserted[:numInserted]
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package typeconv
 
 import (
+	"context"
 	"reflect"
+
+	"github.com/eyo-chen/gofacto"
 )
 
 // ToAnysWithOW generates the given number of values to a slice of pointers of given type with the given one overwrite.
@@ -39,6 +42,32 @@ func ToAnysWithOWs[T any](i int, ows ...*T) []interface{} {
 	return res
 }
 
+// ToAnysWithFactory generates the given number of values by the given factory to a slice of pointers of given type.
+// The values are built with the blueprint of the factory and the given traits, so the associations have realistic content rather than zero values.
+// It returns an error if building fails, e.g. the trait is not found.
+func ToAnysWithFactory[T any](i int, f *gofacto.Factory[T], traits ...string) ([]interface{}, error) {
+	if i == 0 {
+		return []interface{}{}, nil
+	}
+
+	b := f.BuildList(context.Background(), i)
+	for _, tr := range traits {
+		b = b.SetTrait(tr)
+	}
+
+	vals, err := b.GetP()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]interface{}, len(vals))
+	for k, v := range vals {
+		res[k] = v
+	}
+
+	return res, nil
+}
+
 // ToAnys converts the given slice of any type to a slice of values of the given type. Note that the given slice must be a slice of pointers.
 func ToT[T any](vals []interface{}) []T {
 	res := make([]T, len(vals))
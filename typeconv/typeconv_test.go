@@ -1,7 +1,10 @@
 package typeconv
 
 import (
+	"fmt"
 	"testing"
+
+	"github.com/eyo-chen/gofacto"
 )
 
 type Person struct {
@@ -236,3 +239,70 @@ func TestToPointerT(t *testing.T) {
 		})
 	}
 }
+
+func TestToAnysWithFactory(t *testing.T) {
+	blueprint := func(i int) Person {
+		return Person{Name: fmt.Sprintf("person%d", i)}
+	}
+	anonymous := func(p *Person) {
+		p.Name = "anonymous"
+	}
+
+	tests := []struct {
+		desc    string
+		i       int
+		traits  []string
+		want    []interface{}
+		wantErr bool
+	}{
+		{
+			desc: "i is 0",
+			i:    0,
+			want: []interface{}{},
+		},
+		{
+			desc: "i is 2 without traits",
+			i:    2,
+			want: []interface{}{&Person{Name: "person1"}, &Person{Name: "person2"}},
+		},
+		{
+			desc:   "i is 2 with trait",
+			i:      2,
+			traits: []string{"anonymous"},
+			want:   []interface{}{&Person{Name: "anonymous"}, &Person{Name: "anonymous"}},
+		},
+		{
+			desc:    "trait is not found",
+			i:       2,
+			traits:  []string{"unknown"},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			f := gofacto.New(Person{}).WithBlueprint(blueprint).WithTrait("anonymous", anonymous)
+
+			res, err := ToAnysWithFactory(test.i, f, test.traits...)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if len(res) != len(test.want) {
+				t.Fatalf("expected length of %d, but got %d", len(test.want), len(res))
+			}
+
+			for k, v := range res {
+				if *v.(*Person) != *test.want[k].(*Person) {
+					t.Errorf("expected %v, but got %v", *test.want[k].(*Person), *v.(*Person))
+				}
+			}
+		})
+	}
+}